@@ -1,6 +1,8 @@
 package main
 
 import (
+	"log"
+
 	_ "user-jwt/docs"
 	"user-jwt/internal/interface/routes"
 	"user-jwt/pkg/config"
@@ -14,6 +16,12 @@ import (
 func main() {
 	r := gin.Default()
 
+	// クライアントIPはレート制限・不正検知のキーになるため、信頼するプロキシ経由の場合のみ X-Forwarded-For を使う
+	serverCfg := config.LoadServerConfig()
+	if err := r.SetTrustedProxies(serverCfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Swaggerエンドポイント
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -21,6 +29,12 @@ func main() {
 	config.ConnectDB()
 	// Redis接続
 	config.ConnectRedis()
+	// トークン署名キーの読み込み
+	config.LoadJWTKey()
+	// パスワード用ペッパーの読み込み
+	config.LoadPeppers()
+	// 保存データ暗号化キーの読み込み
+	config.LoadEncryptionKey()
 
 	// ルートの設定
 	routes.SetupRoutes(r)
@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// 監査イベントの種類
+const (
+	AuditSignIn             = "sign_in"
+	AuditAccountLocked      = "account_locked"
+	AuditAccountUnlocked    = "account_unlocked"
+	AuditStuffingDetect     = "credential_stuffing_detected"
+	AuditStuffingThresholds = "credential_stuffing_thresholds_updated"
+	AuditMFAEnabled         = "mfa_enabled"
+	AuditMFADisabled        = "mfa_disabled"
+	AuditRecoveryCodeUsed   = "mfa_recovery_code_used"
+	AuditPasskeyRegistered  = "passkey_registered"
+	AuditPasskeyRemoved     = "passkey_removed"
+	AuditPasskeyCloned      = "passkey_counter_regression"
+	AuditEmailChangeRequest = "email_change_requested"
+	AuditEmailChanged       = "email_changed"
+	AuditEmailChangeRevert  = "email_change_reverted"
+	AuditEmailRevertBlocked = "email_change_revert_conflict" // 管理者による確認が必要
+	AuditDeletionScheduled  = "account_deletion_scheduled"
+	AuditAccountErased      = "account_erased"
+	AuditUserSuspended      = "user_suspended"
+	AuditUserReinstated     = "user_reinstated"
+	AuditUserDeleted        = "user_deleted"
+	AuditUserRestored       = "user_restored"
+	AuditIdentityAdded      = "identity_added"
+	AuditIdentityRemoved    = "identity_removed"
+	AuditInvitationCreated  = "signup_invitation_created"
+	AuditUserApproved       = "user_approved"
+	AuditUserRejected       = "user_rejected"
+	AuditSignUpRejected     = "signup_rejected"
+	AuditOrgCreated         = "organization_created"
+	AuditOrgInvited         = "organization_invitation_created"
+	AuditOrgInviteRevoked   = "organization_invitation_revoked"
+	AuditOrgMemberAdded     = "organization_member_added"
+	AuditOrgMemberRemoved   = "organization_member_removed"
+)
+
+// AuditEvent 監査ログのエンティティ
+type AuditEvent struct {
+	ID        uint
+	Type      string `gorm:"index"`
+	UserID    *uint  `gorm:"index"`
+	ActorID   *uint
+	Email     string
+	IP        string
+	UserAgent string
+	Detail    string    // JSON形式の補足情報
+	CreatedAt time.Time `gorm:"index"`
+}
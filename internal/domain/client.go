@@ -0,0 +1,8 @@
+package domain
+
+// ClientInfo リクエスト元クライアントの情報
+type ClientInfo struct {
+	IP                string
+	UserAgent         string
+	ChallengeResponse string // チャレンジモード時のクライアント応答
+}
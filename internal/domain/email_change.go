@@ -0,0 +1,10 @@
+package domain
+
+// EmailChange 確認待ちのメールアドレス変更
+type EmailChange struct {
+	UserID      uint   `json:"user_id"`
+	OldEmail    string `json:"old_email"`
+	NewEmail    string `json:"new_email"`
+	ConfirmHash string `json:"confirm_hash"` // 新アドレスに送る確認リンクのトークンのハッシュ
+	RevertHash  string `json:"revert_hash"`  // 旧アドレスに送る取り消しリンクのトークンのハッシュ
+}
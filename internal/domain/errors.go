@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials メールアドレスまたはパスワードが正しくない
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrAccountLocked サインイン失敗が続いたためアカウントが一時的にロックされている
+	ErrAccountLocked = errors.New("account is temporarily locked")
+	// ErrSignInBlocked 不審なサインインの急増によりブロックされた
+	ErrSignInBlocked = errors.New("sign-in temporarily blocked")
+	// ErrChallengeRequired サインインにチャレンジの応答が必要
+	ErrChallengeRequired = errors.New("challenge required")
+	// ErrEmailAlreadyExists メールアドレスが既に登録されている
+	ErrEmailAlreadyExists = errors.New("email already exists")
+	// ErrInvalidMFACode TOTPコードまたはリカバリーコードが正しくない
+	ErrInvalidMFACode = errors.New("invalid verification code")
+	// ErrMFANotEnrolled MFAが登録されていない
+	ErrMFANotEnrolled = errors.New("mfa is not enrolled")
+	// ErrMFAAlreadyEnabled MFAが既に有効
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+	// ErrInvalidCode メールで送ったコードまたはリンクが無効
+	ErrInvalidCode = errors.New("invalid or expired code")
+	// ErrWebAuthnFailed パスキーの検証に失敗した
+	ErrWebAuthnFailed = errors.New("passkey verification failed")
+	// ErrCredentialNotFound パスキーが存在しない
+	ErrCredentialNotFound = errors.New("credential not found")
+	// ErrFeatureDisabled 設定で無効化されている機能
+	ErrFeatureDisabled = errors.New("feature is disabled")
+	// ErrEmailNotVerified メールアドレスが未確認
+	ErrEmailNotVerified = errors.New("email address is not verified")
+	// ErrInvalidToken 確認用トークンが無効または期限切れ
+	ErrInvalidToken = errors.New("invalid or expired token")
+	// ErrSameEmail 変更後のメールアドレスが現在と同じ
+	ErrSameEmail = errors.New("new email is the same as the current one")
+	// ErrEmailRevertConflict 元のメールアドレスが他のアカウントで使われていて戻せない
+	ErrEmailRevertConflict = errors.New("previous email address is now used by another account")
+	// ErrExportNotFound データエクスポートが見つからない
+	ErrExportNotFound = errors.New("export not found")
+	// ErrInvalidCursor ページネーションのカーソルが不正
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrAccountSuspended アカウントが利用停止中
+	ErrAccountSuspended = errors.New("account is suspended")
+	// ErrVersionMismatch 更新対象が他の更新で変更されている
+	ErrVersionMismatch = errors.New("resource has been modified")
+	// ErrInvalidIdentifier ユーザー名または電話番号の形式が正しくない
+	ErrInvalidIdentifier = errors.New("invalid username or phone number")
+	// ErrUsernameNotAllowed 予約済み、またはそれと紛らわしいユーザー名
+	ErrUsernameNotAllowed = errors.New("username is reserved or too similar to a reserved name")
+	// ErrIdentifierTaken ユーザー名または電話番号が他のアカウントで使われている
+	ErrIdentifierTaken = errors.New("identifier is already in use")
+	// ErrInvalidInvitation 招待が無効・期限切れ・使用済み
+	ErrInvalidInvitation = errors.New("invalid or expired invitation")
+	// ErrEmailDomainNotAllowed サインアップを受け付けていないドメインのメールアドレス
+	ErrEmailDomainNotAllowed = errors.New("email domain is not allowed")
+	// ErrApprovalPending 管理者の承認待ち
+	ErrApprovalPending = errors.New("account is awaiting approval")
+	// ErrSignUpRejected 不正利用対策でサインアップを拒否した
+	ErrSignUpRejected = errors.New("sign-up rejected")
+	// ErrUnknownProvider 設定されていない外部プロバイダー
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrExternalSignInFailed 外部プロバイダーでのサインインを検証できなかった
+	ErrExternalSignInFailed = errors.New("external sign-in failed")
+	// ErrExternalEmailRequired 外部プロバイダーからメールアドレスを取得できなかった
+	ErrExternalEmailRequired = errors.New("identity provider did not return an email address")
+	// ErrExternalEmailUnverified 外部プロバイダーが確認していないメールアドレスではアカウントを作成できない
+	ErrExternalEmailUnverified = errors.New("identity provider has not verified the email address")
+	// ErrExternalAccountNotLinked 外部アカウントに連携したアカウントがなく、新規登録もできない
+	ErrExternalAccountNotLinked = errors.New("no account is linked to this external identity")
+	// ErrOrganizationNotFound 組織が存在しない、またはメンバーではない
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrgAdminRequired 組織の管理者のみが行える操作
+	ErrOrgAdminRequired = errors.New("organization admin role required")
+	// ErrAlreadyMember 既に組織のメンバー
+	ErrAlreadyMember = errors.New("user is already a member of the organization")
+	// ErrLastOrgAdmin 最後の管理者は組織から外せない
+	ErrLastOrgAdmin = errors.New("cannot remove the last admin of the organization")
+	// ErrOrgAdminHandoverRequired 唯一の管理者である組織があるためアカウントを削除できない
+	ErrOrgAdminHandoverRequired = errors.New("hand over the admin role of your organizations before deleting the account")
+	// ErrMemberNotFound 組織のメンバーではない
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrInvitationEmailMismatch 招待の宛先と異なるアカウントで受諾しようとした
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+	// ErrUserNotFound ユーザーが存在しない
+	ErrUserNotFound = errors.New("user not found")
+	// ErrConflict 一意であるべき値が既に使われている
+	ErrConflict = errors.New("conflict")
+)
+
+// ConflictError で重複した項目
+const (
+	ConflictFieldEmail      = "email"
+	ConflictFieldIdentifier = "identifier"
+	ConflictFieldMembership = "membership"
+)
+
+// ConflictError 一意制約に違反した項目を持つ ErrConflict
+// 項目に対応するエラー（ErrEmailAlreadyExists など）としても判定できる
+type ConflictError struct {
+	Field string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Field + " already exists"
+}
+
+func (e *ConflictError) Is(target error) bool {
+	switch target {
+	case ErrConflict:
+		return true
+	case ErrEmailAlreadyExists:
+		return e.Field == ConflictFieldEmail
+	case ErrIdentifierTaken:
+		return e.Field == ConflictFieldIdentifier
+	case ErrAlreadyMember:
+		return e.Field == ConflictFieldMembership
+	}
+	return false
+}
+
+// LockedError ロック解除までの残り時間を持つ ErrAccountLocked
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrAccountLocked.Error()
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// データエクスポートの状態
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// DataExport ユーザーの個人データのエクスポートジョブ
+type DataExport struct {
+	ID          string     `json:"id"`
+	UserID      uint       `json:"user_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"` // アーカイブが削除される日時
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// サインインに使える識別子の種類
+const (
+	IdentityEmail    = "email"
+	IdentityUsername = "username"
+	IdentityPhone    = "phone"
+)
+
+// IdentityOIDCPrefix 上流の OpenID Connect プロバイダーとの連携の種類の接頭辞（"oidc:google" など）
+// Key には検証済みの iss と sub の組を保存する（sub は発行者の中でしか一意でなく、マルチテナントのプロバイダーではテナントごとに iss が異なる）
+const IdentityOIDCPrefix = "oidc:"
+
+// OIDCIdentityType プロバイダー名から連携の識別子の種類を求める
+func OIDCIdentityType(provider string) string {
+	return IdentityOIDCPrefix + provider
+}
+
+// OIDCIdentityKey 発行者と sub から連携の Key を求める（iss には "#" を含められないため区切りに使う）
+func OIDCIdentityKey(issuer, subject string) string {
+	return issuer + "#" + subject
+}
+
+// SplitOIDCIdentityKey 連携の Key を発行者と sub に分ける
+func SplitOIDCIdentityKey(key string) (issuer, subject string) {
+	issuer, subject, _ = strings.Cut(key, "#")
+	return issuer, subject
+}
+
+// Identity ユーザーのサインイン用識別子
+// メールアドレスは users テーブルで管理し、このテーブルにはユーザー名・電話番号・外部プロバイダーとの連携を保存する
+// 同じ値の重複は確認済みのものだけを禁止する（他人が確認前の番号を押さえられないようにする）
+type Identity struct {
+	ID            uint
+	UserID        uint       `gorm:"not null;uniqueIndex:idx_identities_user_type"`
+	Type          string     `gorm:"not null;uniqueIndex:idx_identities_user_type;uniqueIndex:idx_identities_type_key,where:verified_at IS NOT NULL"`
+	Value         string     `gorm:"not null"`                                                                   // 登録された表記（表示用）
+	Key           string     `gorm:"not null;uniqueIndex:idx_identities_type_key,where:verified_at IS NOT NULL"` // 重複判定・検索用に正規化した値
+	VerifiedAt    *time.Time // 本人のものと確認した日時
+	CodeHash      string     // 確認コードのハッシュ（確認待ちの間のみ）
+	CodeExpiresAt *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Verified サインインに使えるよう確認済みか
+func (i *Identity) Verified() bool {
+	return i.VerifiedAt != nil
+}
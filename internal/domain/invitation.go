@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// Invitation 招待制のサインアップで使う1回限りの招待
+type Invitation struct {
+	Email     string    `json:"email,omitempty"` // 指定した場合はこのアドレスでのみ登録できる
+	CreatedBy uint      `json:"created_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// TOTPCredential ユーザーのTOTP設定
+type TOTPCredential struct {
+	ID          uint
+	UserID      uint   `gorm:"uniqueIndex"`
+	Secret      string // 暗号化して保存
+	ConfirmedAt *time.Time
+	LastStep    int64 // 最後に使われたタイムステップ（再利用防止）
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Enabled 初回コードで確認済みか
+func (c *TOTPCredential) Enabled() bool {
+	return c != nil && c.ConfirmedAt != nil
+}
+
+// RecoveryCode 使い捨てのリカバリーコード（ハッシュで保存）
+type RecoveryCode struct {
+	ID        uint
+	UserID    uint   `gorm:"index"`
+	CodeHash  string `gorm:"uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,9 @@
+package domain
+
+// OIDCLogin 上流の OpenID Connect プロバイダーへの認可リクエストの状態
+// state をキーに callback まで保存する
+type OIDCLogin struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"` // PKCE
+}
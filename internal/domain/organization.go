@@ -0,0 +1,66 @@
+package domain
+
+import "time"
+
+// 組織内のロール
+const (
+	OrgRoleAdmin  = "admin" // メンバーの招待・削除ができる
+	OrgRoleMember = "member"
+)
+
+// Organization 組織のエンティティ
+type Organization struct {
+	ID        uint   // 内部の結合用キー（外部には公開しない）
+	PublicID  string `gorm:"type:char(36);not null;uniqueIndex"` // URLやトークンに使う公開ID（UUIDv7）
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Membership ユーザーの組織への所属
+type Membership struct {
+	ID             uint
+	OrganizationID uint   `gorm:"not null;uniqueIndex:idx_memberships_org_user"`
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_memberships_org_user;index"`
+	Role           string `gorm:"not null;default:member"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// OrgMember 組織のメンバー一覧の要素
+type OrgMember struct {
+	UserPublicID string
+	Email        string
+	DisplayName  string
+	Role         string
+	JoinedAt     time.Time
+}
+
+// UserOrganization ユーザーが所属する組織とそのロール
+type UserOrganization struct {
+	Organization Organization
+	Role         string
+	JoinedAt     time.Time
+}
+
+// OrgInvitation 組織への招待
+// 招待リンクには署名付きトークン（公開IDを含む）を使い、取り消し・使用済みの判定はこの行で行う
+type OrgInvitation struct {
+	ID              uint
+	PublicID        string    `gorm:"type:char(36);not null;uniqueIndex"`
+	OrganizationID  uint      `gorm:"not null;index"`
+	Email           string    `gorm:"not null"`       // 招待した宛先（送信先に使う）
+	EmailNormalized string    `gorm:"not null;index"` // 受諾するアカウントとの照合用
+	Role            string    `gorm:"not null"`
+	InvitedBy       *uint     // 招待した管理者（アカウント消去時は NULL にする）
+	ExpiresAt       time.Time `gorm:"not null"`
+	AcceptedAt      *time.Time
+	AcceptedBy      *uint
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// Pending 受諾・取り消しされておらず期限内か
+func (i *OrgInvitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,14 @@
+package domain
+
+// パスワードレスサインインの送信方法
+const (
+	PasswordlessMethodLink = "link"
+	PasswordlessMethodCode = "code"
+)
+
+// PasswordlessChallenge メールで送ったマジックリンク・ワンタイムコードの状態
+type PasswordlessChallenge struct {
+	Email     string `json:"email"`
+	CodeHash  string `json:"code_hash,omitempty"`
+	TokenHash string `json:"token_hash,omitempty"`
+}
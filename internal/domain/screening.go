@@ -0,0 +1,30 @@
+package domain
+
+// サインアップを拒否した理由（監査ログと応答に含める）
+const (
+	ScreeningDisposableDomain = "disposable_domain" // 使い捨てメールアドレスのドメイン
+	ScreeningNoMXRecord       = "no_mx_record"      // メールを受け取れないドメイン
+	ScreeningIPVelocity       = "ip_velocity"       // 同じIPからのサインアップが多すぎる
+	ScreeningPoWRequired      = "pow_required"      // 負荷が高いためProof of Workが必要
+	ScreeningPoWInvalid       = "pow_invalid"       // Proof of Workの応答が正しくない
+)
+
+// ScreeningError 不正利用対策でサインアップを拒否した理由を持つ ErrSignUpRejected
+// Proof of Workが必要な場合は ErrChallengeRequired としても判定できる
+type ScreeningError struct {
+	Reason string
+}
+
+func (e *ScreeningError) Error() string {
+	return ErrSignUpRejected.Error() + ": " + e.Reason
+}
+
+func (e *ScreeningError) Is(target error) bool {
+	switch target {
+	case ErrSignUpRejected:
+		return true
+	case ErrChallengeRequired:
+		return e.Reason == ScreeningPoWRequired || e.Reason == ScreeningPoWInvalid
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package domain
+
+// StuffingRule 1つの集計軸に対する判定しきい値
+type StuffingRule struct {
+	MinAttempts    int     `json:"min_attempts" validate:"min=1"`          // 判定に必要な最小試行回数
+	ChallengeRatio float64 `json:"challenge_ratio" validate:"gte=0,lte=1"` // チャレンジを要求する失敗率（0で無効）
+	BlockRatio     float64 `json:"block_ratio" validate:"gte=0,lte=1"`     // ブロックする失敗率（0で無効）
+}
+
+// StuffingThresholds クレデンシャルスタッフィング検知のしきい値（実行時に変更可能）
+type StuffingThresholds struct {
+	WindowSeconds int          `json:"window_seconds" validate:"min=60,max=86400"`
+	IP            StuffingRule `json:"ip"`
+	Subnet        StuffingRule `json:"subnet"`
+	UserAgent     StuffingRule `json:"user_agent"`
+	Global        StuffingRule `json:"global"`
+}
+
+// StuffingVerdict 検知結果
+type StuffingVerdict int
+
+const (
+	StuffingAllow StuffingVerdict = iota
+	StuffingChallenge
+	StuffingBlock
+)
+
+func (v StuffingVerdict) String() string {
+	switch v {
+	case StuffingChallenge:
+		return "challenge"
+	case StuffingBlock:
+		return "block"
+	default:
+		return "allow"
+	}
+}
@@ -1,12 +1,136 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ユーザーのロール
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// 承認制サインアップでの承認状態
+const (
+	ApprovalApproved = "approved"
+	ApprovalPending  = "pending"  // 管理者の承認待ち（サインインできない）
+	ApprovalRejected = "rejected" // 却下済み（アカウントは論理削除される）
+)
+
+// プロフィールの公開範囲
+const (
+	VisibilityPublic  = "public"  // 他のユーザーに公開プロフィールを見せる
+	VisibilityPrivate = "private" // 本人と管理者のみ参照できる
+)
 
 // User エンティティ
 type User struct {
-	ID        uint
-	Email     string
-	Password  string
+	ID              uint   // 内部の結合用キー（外部には公開しない）
+	PublicID        string `gorm:"type:char(36);not null;uniqueIndex"` // URLやトークンに使う公開ID（UUIDv7）
+	Email           string // 登録された表記のメールアドレス（送信先に使う）
+	EmailNormalized string `gorm:"uniqueIndex:idx_users_email_normalized,where:deleted_at IS NULL"` // 重複判定・検索用に正規化したメールアドレス
+	Password        string
+	Role            string `gorm:"default:user;index"`
+	EmailVerifiedAt *time.Time
+	DisplayName     string
+	Locale          string
+	Timezone        string
+	AvatarURL       string
+	Visibility      string     `gorm:"not null;default:public"`
+	ApprovalStatus  string     `gorm:"not null;default:approved;index"`
+	Version         int        `gorm:"not null;default:1"` // 楽観的排他制御用（プロフィール更新ごとに加算）
+	DeletionDueAt   *time.Time `gorm:"index"`              // 削除予定の場合、完全に消去する日時
+	DisabledAt      *time.Time // 利用停止した日時
+	DisabledReason  string
+	DisabledUntil   *time.Time // 利用停止の期限（nilの場合は無期限）
+	CreatedAt       time.Time  `gorm:"index"`
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"` // 論理削除した日時（通常の検索からは除外される）
+}
+
+// PendingDeletion 削除の猶予期間中か
+func (u *User) PendingDeletion() bool {
+	return u.DeletionDueAt != nil
+}
+
+// Deleted 論理削除されているか
+func (u *User) Deleted() bool {
+	return u.DeletedAt.Valid
+}
+
+// Approved 承認済みでサインインできるか
+func (u *User) Approved() bool {
+	return u.ApprovalStatus == "" || u.ApprovalStatus == ApprovalApproved
+}
+
+// Suspended 指定時刻に利用停止中か
+func (u *User) Suspended(now time.Time) bool {
+	return suspended(u.DisabledAt, u.DisabledUntil, now)
+}
+
+// UserStatus 認証のたびに確認するユーザーの状態（キャッシュして使う）
+type UserStatus struct {
+	UserID        uint       `json:"user_id"` // 0の場合はユーザーが存在しない
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	DisabledUntil *time.Time `json:"disabled_until,omitempty"`
+}
+
+// Suspended 指定時刻に利用停止中か
+func (s *UserStatus) Suspended(now time.Time) bool {
+	return suspended(s.DisabledAt, s.DisabledUntil, now)
+}
+
+func suspended(disabledAt, disabledUntil *time.Time, now time.Time) bool {
+	return disabledAt != nil && (disabledUntil == nil || now.Before(*disabledUntil))
+}
+
+// ProfileUpdate プロフィールの部分更新（nilの項目は変更しない）
+type ProfileUpdate struct {
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
+	Visibility  *string
+}
+
+// Empty 変更する項目がないか
+func (p ProfileUpdate) Empty() bool {
+	return p.DisplayName == nil && p.Locale == nil && p.Timezone == nil && p.AvatarURL == nil && p.Visibility == nil
+}
+
+// EmailVerified メールアドレスが確認済みか
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Viewer ユーザー情報を参照する主体
+type Viewer struct {
+	UserID uint
+	Role   string
+}
+
+// IsAdmin 管理者か
+func (v Viewer) IsAdmin() bool {
+	return v.Role == RoleAdmin
+}
+
+// UserFilter 管理者向けユーザー一覧の絞り込み条件（ゼロ値・nilの項目は条件にしない）
+type UserFilter struct {
+	EmailPrefix   string // メールアドレスの前方一致（大文字小文字を区別しない）
+	Role          string
+	Approval      string // 承認状態
+	Verified      *bool  // メールアドレス確認済みか
+	Disabled      *bool  // 利用停止中・削除予定など利用できない状態か
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Deleted       bool        // trueの場合は論理削除されたユーザーのみを対象にする
+	Before        *UserCursor // カーソル（この位置より古いユーザーを返す）
+}
+
+// UserCursor ユーザー一覧のページ位置（作成日時と公開IDの組で並べる）
+type UserCursor struct {
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	PublicID  string
 }
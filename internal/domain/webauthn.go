@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// WebAuthnCredential ユーザーに登録されたパスキー
+type WebAuthnCredential struct {
+	ID                uint
+	UserID            uint   `gorm:"index"`
+	CredentialID      string `gorm:"uniqueIndex"` // base64url
+	PublicKey         []byte // COSE_Key
+	SignCount         uint32
+	AAGUID            string
+	AttestationFormat string
+	Transports        string // カンマ区切り
+	Name              string
+	LastUsedAt        *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// WebAuthnセレモニーの種類
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+	CeremonyMFA          = "mfa"
+)
+
+// WebAuthnSession セレモニー開始から完了までの一時的な状態
+type WebAuthnSession struct {
+	Ceremony  string `json:"ceremony"`
+	Challenge []byte `json:"challenge"`
+	UserID    uint   `json:"user_id,omitempty"`
+	RequireUV bool   `json:"require_uv"`
+}
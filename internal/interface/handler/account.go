@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	accountUsecase usecase.AccountUsecase
+}
+
+func NewAccountHandler(accountUsecase usecase.AccountUsecase) *AccountHandler {
+	return &AccountHandler{accountUsecase: accountUsecase}
+}
+
+type DeleteAccountResponse struct {
+	Message       string    `json:"message"`
+	DeletionDueAt time.Time `json:"deletion_due_at"`
+}
+
+// DeleteMe 認証中のユーザーのアカウント削除を予約
+// @Summary      Delete Current User
+// @Description  Schedule the account for erasure after a grace period and revoke all tokens. Signing in again before the due date cancels the deletion. Requires recent authentication. Sole admins of an organization with other members must hand over the admin role first
+// @Tags         user
+// @Produce      json
+// @Success      202  {object}  DeleteAccountResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /user/me [delete]
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	dueAt, err := h.accountUsecase.ScheduleDeletion(c.GetUint("userID"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrOrgAdminHandoverRequired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, DeleteAccountResponse{
+		Message:       "Account scheduled for deletion. Sign in before the due date to cancel",
+		DeletionDueAt: dueAt,
+	})
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	authUsecase usecase.AuthUsecase
+	detector    usecase.StuffingDetector
+	adminUsers  usecase.AdminUserUsecase
+}
+
+func NewAdminHandler(authUsecase usecase.AuthUsecase, detector usecase.StuffingDetector, adminUsers usecase.AdminUserUsecase) *AdminHandler {
+	return &AdminHandler{authUsecase: authUsecase, detector: detector, adminUsers: adminUsers}
+}
+
+// ユーザー一覧の検索条件
+type ListUsersRequest struct {
+	Limit         int        `form:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor        string     `form:"cursor"`
+	Email         string     `form:"email" validate:"omitempty,max=254"` // 前方一致
+	Role          string     `form:"role" validate:"omitempty,oneof=user admin"`
+	Approval      string     `form:"approval" validate:"omitempty,oneof=approved pending rejected"`
+	Verified      *bool      `form:"verified"`
+	Disabled      *bool      `form:"disabled"`
+	Deleted       bool       `form:"deleted"` // trueの場合は論理削除されたユーザーのみ
+	CreatedAfter  *time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// 管理者向けのユーザー情報
+type AdminUserResponse struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Role            string     `json:"role"`
+	ApprovalStatus  string     `json:"approval_status"`
+	DisplayName     string     `json:"display_name"`
+	Disabled        bool       `json:"disabled"`
+	DisabledReason  string     `json:"disabled_reason,omitempty"`
+	DisabledUntil   *time.Time `json:"disabled_until,omitempty"`
+	DeletionDueAt   *time.Time `json:"deletion_due_at,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type ListUsersResponse struct {
+	Users      []AdminUserResponse `json:"users"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	Locale            string `json:"locale"`
+	Timezone          string `json:"timezone"`
+	AvatarURL         string `json:"avatar_url"`
+	Visibility        string `json:"visibility"`
+	TOTPEnabled       bool   `json:"totp_enabled"`
+	Passkeys          int    `json:"passkeys"`
+	Locked            bool   `json:"locked"`
+	BlockedForSeconds int    `json:"blocked_for_seconds,omitempty"`
+}
+
+// ListUsers ユーザー一覧を取得
+// @Summary      List Users
+// @Description  List users newest first with cursor pagination and filters (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        limit           query  int     false  "Page size (1-100, default 20)"
+// @Param        cursor          query  string  false  "Cursor from the previous page"
+// @Param        email           query  string  false  "Email prefix"
+// @Param        role            query  string  false  "Role" Enums(user, admin)
+// @Param        approval        query  string  false  "Approval status" Enums(approved, pending, rejected)
+// @Param        verified        query  bool    false  "Email verified"
+// @Param        disabled        query  bool    false  "Disabled"
+// @Param        deleted         query  bool    false  "List soft-deleted users only"
+// @Param        created_after   query  string  false  "Created at or after (RFC 3339)"
+// @Param        created_before  query  string  false  "Created before (RFC 3339)"
+// @Success      200  {object}  ListUsersResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	var req ListUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	validationErrors := utils.ValidateStruct(&req)
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+		return
+	}
+
+	filter := domain.UserFilter{
+		EmailPrefix:   req.Email,
+		Role:          req.Role,
+		Approval:      req.Approval,
+		Verified:      req.Verified,
+		Disabled:      req.Disabled,
+		Deleted:       req.Deleted,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+	}
+	page, err := h.adminUsers.ListUsers(filter, req.Cursor, req.Limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	response := ListUsersResponse{
+		Users:      make([]AdminUserResponse, 0, len(page.Users)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Users {
+		response.Users = append(response.Users, newAdminUserResponse(&page.Users[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUser ユーザーの詳細を取得
+// @Summary      Get User Detail
+// @Description  Get the full detail of a user including security state (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  AdminUserDetailResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	detail, err := h.adminUsers.GetUserDetail(publicID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	user := detail.User
+	c.JSON(http.StatusOK, AdminUserDetailResponse{
+		AdminUserResponse: newAdminUserResponse(user),
+		Locale:            user.Locale,
+		Timezone:          user.Timezone,
+		AvatarURL:         user.AvatarURL,
+		Visibility:        user.Visibility,
+		TOTPEnabled:       detail.TOTPEnabled,
+		Passkeys:          detail.Passkeys,
+		Locked:            detail.Locked,
+		BlockedForSeconds: int(math.Ceil(detail.BlockedFor.Seconds())),
+	})
+}
+
+// 利用停止リクエスト（until を省略すると無期限）
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" validate:"required,max=500"`
+	Until  *time.Time `json:"until"`
+}
+
+// SuspendUser ユーザーを利用停止にする
+// @Summary      Suspend User
+// @Description  Block sign-in and reject existing tokens of a user until reinstated or until the given time (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string              true  "User public ID" Format(uuid)
+// @Param        body  body      SuspendUserRequest  true  "Suspension"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/suspend [post]
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SuspendUserRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Until must be in the future"})
+		return
+	}
+	if publicID == c.GetString("publicID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot suspend yourself"})
+		return
+	}
+
+	if err := h.adminUsers.Suspend(c.GetUint("userID"), publicID, req.Reason, req.Until); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User suspended"})
+}
+
+// ReinstateUser ユーザーの利用停止を解除
+// @Summary      Reinstate User
+// @Description  Lift a suspension (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/reinstate [post]
+func (h *AdminHandler) ReinstateUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminUsers.Reinstate(c.GetUint("userID"), publicID); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reinstate user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User reinstated"})
+}
+
+// DeleteUser ユーザーを論理削除
+// @Summary      Delete User
+// @Description  Soft-delete a user. Audit references are kept and the account can be restored (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if publicID == c.GetString("publicID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete yourself"})
+		return
+	}
+
+	if err := h.adminUsers.Delete(c.GetUint("userID"), publicID); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// RestoreUser 論理削除したユーザーを復元
+// @Summary      Restore User
+// @Description  Restore a soft-deleted user unless another account now uses the same email (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /admin/users/{id}/restore [post]
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminUsers.Restore(c.GetUint("userID"), publicID); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrEmailAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restored"})
+}
+
+func newAdminUserResponse(user *domain.User) AdminUserResponse {
+	var deletedAt *time.Time
+	if user.Deleted() {
+		deletedAt = &user.DeletedAt.Time
+	}
+	return AdminUserResponse{
+		ID:              user.PublicID,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Role:            user.Role,
+		ApprovalStatus:  user.ApprovalStatus,
+		DisplayName:     user.DisplayName,
+		Disabled:        user.PendingDeletion() || user.Suspended(time.Now()),
+		DisabledReason:  user.DisabledReason,
+		DisabledUntil:   user.DisabledUntil,
+		DeletionDueAt:   user.DeletionDueAt,
+		DeletedAt:       deletedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+// UnlockUser サインイン失敗によるロックを解除
+// @Summary      Unlock User
+// @Description  Clear sign-in failure counters and lockout for a user (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.authUsecase.UnlockAccount(c.GetUint("userID"), publicID); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// GetStuffingThresholds クレデンシャルスタッフィング検知のしきい値を取得
+// @Summary      Get Credential Stuffing Thresholds
+// @Description  Get the current credential stuffing detection thresholds (admin only)
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.StuffingThresholds
+// @Failure      403  {object}  map[string]string
+// @Router       /admin/security/stuffing-thresholds [get]
+func (h *AdminHandler) GetStuffingThresholds(c *gin.Context) {
+	c.JSON(http.StatusOK, h.detector.Thresholds())
+}
+
+// UpdateStuffingThresholds クレデンシャルスタッフィング検知のしきい値を更新
+// @Summary      Update Credential Stuffing Thresholds
+// @Description  Replace the credential stuffing detection thresholds at runtime (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      domain.StuffingThresholds  true  "Thresholds"
+// @Success      200   {object}  domain.StuffingThresholds
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Router       /admin/security/stuffing-thresholds [put]
+func (h *AdminHandler) UpdateStuffingThresholds(c *gin.Context) {
+	var req domain.StuffingThresholds
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	validationErrors := utils.ValidateStruct(&req)
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+		return
+	}
+
+	if err := h.detector.UpdateThresholds(c.GetUint("userID"), req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update thresholds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
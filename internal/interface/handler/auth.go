@@ -2,10 +2,15 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"user-jwt/internal/domain"
 	"user-jwt/internal/usecase"
 	"user-jwt/pkg/config"
 	"user-jwt/pkg/utils"
@@ -15,10 +20,11 @@ import (
 
 type AuthHandler struct {
 	authUsecase usecase.AuthUsecase
+	cfg         config.AuthConfig
 }
 
-func NewAuthHandler(authUsecase usecase.AuthUsecase) *AuthHandler {
-	return &AuthHandler{authUsecase: authUsecase}
+func NewAuthHandler(authUsecase usecase.AuthUsecase, cfg config.AuthConfig) *AuthHandler {
+	return &AuthHandler{authUsecase: authUsecase, cfg: cfg}
 }
 
 // SugnUPリクエスト・レスポンス用構造体定義
@@ -26,10 +32,11 @@ type SignUpRequest struct {
 	Email                string `json:"email" validate:"required,email"`
 	Password             string `json:"password" validate:"required,min=8"`
 	PasswordConfirmation string `json:"password_confirmation" validate:"required"`
+	Invitation           string `json:"invitation" validate:"omitempty,max=128"` // 招待制の場合に必要な招待トークン
 }
 
 type UserResponse struct {
-	ID    uint   `json:"id"`
+	ID    string `json:"id"`
 	Email string `json:"email"`
 }
 
@@ -37,25 +44,39 @@ type SignUpResponse struct {
 	User UserResponse `json:"user"`
 }
 
+// アカウントの存在を隠す設定時のサインアップ応答
+type SignUpAcceptedResponse struct {
+	Message string `json:"message"`
+}
+
 // SugnINリクエスト・レスポンス用構造体定義
+// identifier にはメールアドレス・ユーザー名・電話番号のいずれかを指定する（email は従来の互換用）
 type SignInRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Identifier string `json:"identifier" validate:"required_without=Email,max=254"`
+	Email      string `json:"email" validate:"omitempty,email"`
+	Password   string `json:"password" validate:"required"`
 }
 
 type SignInResponse struct {
-	Token string `json:"token"`
+	Token       string   `json:"token,omitempty"`
+	MFARequired bool     `json:"mfa_required,omitempty"` // trueの場合は /auth/mfa/verify または /auth/webauthn/mfa/* で認証を完了する
+	MFAToken    string   `json:"mfa_token,omitempty"`
+	MFAMethods  []string `json:"mfa_methods,omitempty"`
 }
 
 // @Summary      Sign Up
-// @Description  Register a new user
+// @Description  Register a new user. Depending on the sign-up mode an invitation, an allowed email domain or admin approval is required
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        body  body  SignUpRequest  true  "SignUp payload"
+// @Param        X-Challenge-Response  header  string  false  "Proof-of-work response (challenge:nonce) when required"
 // @Success      201   {object} SignUpResponse
+// @Success      202   {object} SignUpAcceptedResponse
 // @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]interface{}
 // @Failure      409   {object} map[string]string
+// @Failure      429   {object} map[string]string
 // @Router       /auth/sign-up [post]
 func (h *AuthHandler) SignUp(c *gin.Context) {
 	var req SignUpRequest
@@ -75,15 +96,33 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authUsecase.SignUp(req.Email, req.Password)
+	user, err := h.authUsecase.SignUp(req.Email, req.Password, req.Invitation, clientInfo(c))
+	if h.cfg.EnumerationProtection && (err == nil || errors.Is(err, domain.ErrEmailAlreadyExists)) {
+		// 新規・既存のどちらでも同じ応答を返す
+		c.JSON(http.StatusAccepted, SignUpAcceptedResponse{Message: "Check your email to continue"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, domain.ErrEmailAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrInvalidInvitation), errors.Is(err, domain.ErrEmailDomainNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrSignUpRejected):
+			respondScreeningError(c, err)
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up"})
+		}
+		return
+	}
+	if !user.Approved() {
+		c.JSON(http.StatusAccepted, SignUpAcceptedResponse{Message: "Your account is awaiting approval"})
 		return
 	}
 
 	response := SignUpResponse{
 		User: UserResponse{
-			ID:    user.ID,
+			ID:    user.PublicID,
 			Email: user.Email,
 		},
 	}
@@ -91,7 +130,7 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 }
 
 // @Summary      Sign In
-// @Description  Authenticate a user and return a JWT token
+// @Description  Authenticate a user by email, verified username or verified phone number and return a JWT token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -99,6 +138,8 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 // @Success      200   {object} SignInResponse
 // @Failure      400   {object} map[string]string
 // @Failure      401   {object} map[string]string
+// @Failure      403   {object} map[string]interface{}
+// @Failure      429   {object} map[string]string
 // @Router       /auth/sign-in [post]
 func (h *AuthHandler) SignIn(c *gin.Context) {
 	var req SignInRequest
@@ -107,22 +148,178 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 		return
 	}
 
-	validationErrors := utils.ValidateStruct(req)
+	validationErrors := utils.ValidateStruct(&req)
 	if validationErrors != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
 		return
 	}
 
-	token, err := h.authUsecase.SignIn(req.Email, req.Password)
+	identifier := req.Identifier
+	if identifier == "" {
+		identifier = req.Email
+	}
+	result, err := h.authUsecase.SignIn(identifier, req.Password, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		var locked *domain.LockedError
+		switch {
+		case errors.As(err, &locked):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-in attempts, try again later"})
+		case errors.Is(err, domain.ErrSignInBlocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-in attempts, try again later"})
+		case errors.Is(err, domain.ErrChallengeRequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Challenge required", "challenge_required": true})
+		case errors.Is(err, domain.ErrEmailNotVerified):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "email_verification_required": true})
+		case errors.Is(err, domain.ErrAccountSuspended), errors.Is(err, domain.ErrApprovalPending):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	response := SignInResponse{Token: token}
+	response := SignInResponse{
+		Token:       result.Token,
+		MFARequired: result.MFARequired(),
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
+	}
 	c.JSON(http.StatusOK, response)
 }
 
+// password と code の少なくとも一方を指定する（両方で多要素の再認証になる）
+type StepUpRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// StepUp 再認証して認証時刻と強度を更新したトークンを発行
+// @Summary      Step Up Authentication
+// @Description  Re-authenticate with a password and/or an MFA code and return an upgraded token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  StepUpRequest  true  "Step-up payload"
+// @Success      200   {object} SignInResponse
+// @Failure      400   {object} map[string]string
+// @Failure      401   {object} map[string]string
+// @Failure      429   {object} map[string]string
+// @Router       /auth/step-up [post]
+func (h *AuthHandler) StepUp(c *gin.Context) {
+	var req StepUpRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if req.Password == "" && req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password or code is required"})
+		return
+	}
+
+	token, err := h.authUsecase.StepUp(c.GetUint("userID"), req.Password, req.Code)
+	if err != nil {
+		var locked *domain.LockedError
+		switch {
+		case errors.As(err, &locked):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, try again later"})
+		case errors.Is(err, domain.ErrInvalidCredentials), errors.Is(err, domain.ErrInvalidMFACode):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrMFANotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-authenticate"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, SignInResponse{Token: token})
+}
+
+// VerifyEmail 確認リンクのトークンでメールアドレスを確認済みにする
+// @Summary      Verify Email
+// @Description  Confirm the email address using the token sent by email
+// @Tags         auth
+// @Produce      json
+// @Param        token  query  string  true  "Verification token"
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Router       /auth/verify-email [get]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	if err := h.authUsecase.VerifyEmail(token); err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResendVerification 確認メールを再送する（アカウントの有無に関わらず同じ応答を返す）
+// @Summary      Resend Verification Email
+// @Description  Send the email verification link again
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  ResendVerificationRequest  true  "Resend payload"
+// @Success      202   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Failure      429   {object} map[string]string
+// @Router       /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.authUsecase.ResendVerification(req.Email); err != nil {
+		log.Printf("failed to resend verification email: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists and is unverified, a verification email has been sent"})
+}
+
+// respondScreeningError 不正利用対策による拒否を理由コード付きで返す
+// Proof of Work が必要な場合は 403 と challenge_required、IP あたりの登録数の超過は 429 にする
+func respondScreeningError(c *gin.Context, err error) {
+	var rejected *domain.ScreeningError
+	if !errors.As(err, &rejected) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	switch {
+	case errors.Is(err, domain.ErrChallengeRequired):
+		// GET /auth/challenge で取得したチャレンジを解き、X-Challenge-Response ヘッダーで送り直す
+		c.JSON(http.StatusForbidden, gin.H{"error": "Challenge required", "reason": rejected.Reason, "challenge_required": true})
+	case rejected.Reason == domain.ScreeningIPVelocity:
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-ups, try again later", "reason": rejected.Reason})
+	default:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Sign-up rejected", "reason": rejected.Reason})
+	}
+}
+
+// clientInfo リクエスト元の情報を取り出す
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{
+		IP:                c.ClientIP(),
+		UserAgent:         c.Request.UserAgent(),
+		ChallengeResponse: c.GetHeader("X-Challenge-Response"),
+	}
+}
+
 func (h *AuthHandler) PostAuthSignOut(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeAuthUsecase 登録済みのアドレスへのサインアップだけを重複として扱う
+type fakeAuthUsecase struct {
+	usecase.AuthUsecase
+	registered string
+}
+
+func (u *fakeAuthUsecase) SignUp(email, password, invitation string, client domain.ClientInfo) (domain.User, error) {
+	if email == u.registered {
+		return domain.User{}, domain.ErrEmailAlreadyExists
+	}
+	return domain.User{ID: 2, PublicID: testPublicID, Email: email, ApprovalStatus: domain.ApprovalApproved}, nil
+}
+
+func newAuthTestRouter(cfg config.AuthConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthHandler(&fakeAuthUsecase{registered: "alice@example.com"}, cfg)
+	r := gin.New()
+	r.POST("/auth/sign-up", h.SignUp)
+	return r
+}
+
+func signUpBody(email string) string {
+	return `{"email":"` + email + `","password":"password123","password_confirmation":"password123"}`
+}
+
+// 列挙対策が有効な場合、既存のアドレスでも新規と同じ応答を返す
+func TestSignUpEnumerationProtection(t *testing.T) {
+	r := newAuthTestRouter(config.AuthConfig{EnumerationProtection: true})
+
+	existing := serve(r, http.MethodPost, "/auth/sign-up", signUpBody("alice@example.com"), nil)
+	created := serve(r, http.MethodPost, "/auth/sign-up", signUpBody("bob@example.com"), nil)
+	if existing.Code != http.StatusAccepted || created.Code != http.StatusAccepted {
+		t.Fatalf("status = %d / %d, want 202", existing.Code, created.Code)
+	}
+	if existing.Body.String() != created.Body.String() {
+		t.Fatalf("bodies differ: %s / %s", existing.Body, created.Body)
+	}
+}
+
+func TestSignUpWithoutEnumerationProtection(t *testing.T) {
+	r := newAuthTestRouter(config.AuthConfig{})
+
+	if w := serve(r, http.MethodPost, "/auth/sign-up", signUpBody("alice@example.com"), nil); w.Code != http.StatusConflict {
+		t.Fatalf("existing: status = %d, want 409", w.Code)
+	}
+	if w := serve(r, http.MethodPost, "/auth/sign-up", signUpBody("bob@example.com"), nil); w.Code != http.StatusCreated {
+		t.Fatalf("new: status = %d, want 201", w.Code)
+	}
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChallengeHandler struct {
+	powUsecase usecase.ProofOfWorkUsecase
+}
+
+func NewChallengeHandler(powUsecase usecase.ProofOfWorkUsecase) *ChallengeHandler {
+	return &ChallengeHandler{powUsecase: powUsecase}
+}
+
+// Proof of Workのチャレンジ
+// SHA-256(challenge + ":" + nonce) の先頭 difficulty ビットが0になる nonce を探し、
+// "challenge:nonce" を X-Challenge-Response ヘッダーで送る
+type ChallengeResponse struct {
+	Challenge        string `json:"challenge"`
+	Difficulty       int    `json:"difficulty"`
+	ExpiresInSeconds int    `json:"expires_in_seconds"`
+}
+
+// Issue Proof of Workのチャレンジを発行
+// @Summary      Get Proof-of-Work Challenge
+// @Description  Issue a single-use proof-of-work challenge required for sign-up and sign-in under load
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  ChallengeResponse
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/challenge [get]
+func (h *ChallengeHandler) Issue(c *gin.Context) {
+	challenge, err := h.powUsecase.Issue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue challenge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ChallengeResponse{
+		Challenge:        challenge.Challenge,
+		Difficulty:       challenge.Difficulty,
+		ExpiresInSeconds: int(challenge.ExpiresIn.Seconds()),
+	})
+}
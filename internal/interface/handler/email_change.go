@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailChangeHandler struct {
+	emailChangeUsecase usecase.EmailChangeUsecase
+}
+
+func NewEmailChangeHandler(emailChangeUsecase usecase.EmailChangeUsecase) *EmailChangeHandler {
+	return &EmailChangeHandler{emailChangeUsecase: emailChangeUsecase}
+}
+
+type EmailChangeRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Request メールアドレスの変更を要求
+// @Summary      Request Email Change
+// @Description  Send a confirmation link to the new address and an undo link to the current one. Requires recent authentication
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body  EmailChangeRequest  true  "New email address"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /user/me/email [post]
+func (h *EmailChangeHandler) Request(c *gin.Context) {
+	var req EmailChangeRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.emailChangeUsecase.Request(c.GetUint("userID"), req.Email); err != nil {
+		switch {
+		case errors.Is(err, domain.ErrSameEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request email change"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check the new email address to confirm the change"})
+}
+
+// Confirm 確認リンクでメールアドレスの変更を確定
+// @Summary      Confirm Email Change
+// @Description  Apply the email change using the token sent to the new address. Existing access tokens are revoked
+// @Tags         user
+// @Produce      json
+// @Param        token  query  string  true  "Confirmation token"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /user/email/confirm [get]
+func (h *EmailChangeHandler) Confirm(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	if err := h.emailChangeUsecase.Confirm(token); err != nil {
+		respondEmailChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address changed. Please sign in again"})
+}
+
+// Revert 取り消しリンクでメールアドレスの変更を取り消す
+// @Summary      Revert Email Change
+// @Description  Cancel a pending email change or switch back to the previous address using the token sent to it. Existing access tokens are revoked
+// @Tags         user
+// @Produce      json
+// @Param        token  query  string  true  "Revert token"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /user/email/revert [get]
+func (h *EmailChangeHandler) Revert(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	if err := h.emailChangeUsecase.Revert(token); err != nil {
+		respondEmailChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email change reverted. Please sign in again and consider changing your password"})
+}
+
+func respondEmailChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrEmailAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrEmailRevertConflict):
+		// トークンは失効済み。管理者が確認するまでアドレスは戻せない
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "message": "All sessions have been signed out and an administrator has been notified. Please contact support"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email address"})
+	}
+}
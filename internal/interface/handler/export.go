@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	exportUsecase usecase.DataExportUsecase
+}
+
+func NewDataExportHandler(exportUsecase usecase.DataExportUsecase) *DataExportHandler {
+	return &DataExportHandler{exportUsecase: exportUsecase}
+}
+
+type DataExportResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DownloadURL string     `json:"download_url,omitempty"` // 完了時のみ（短時間で失効するため都度取得する）
+}
+
+// Start 個人データのエクスポートを開始
+// @Summary      Start Data Export
+// @Description  Start an asynchronous export of all personal data. Poll the returned location until the status is completed
+// @Tags         user
+// @Produce      json
+// @Success      202  {object}  DataExportResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /user/me/export [post]
+func (h *DataExportHandler) Start(c *gin.Context) {
+	job, err := h.exportUsecase.Start(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+
+	c.Header("Location", "/user/me/export/"+job.ID)
+	c.JSON(http.StatusAccepted, newDataExportResponse(usecase.ExportStatus{Job: job}))
+}
+
+// Status エクスポートの進行状況を取得
+// @Summary      Get Data Export Status
+// @Description  Get the status of a data export. Completed exports include a short-lived signed download URL
+// @Tags         user
+// @Produce      json
+// @Param        id   path      string  true  "Export ID"
+// @Success      200  {object}  DataExportResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /user/me/export/{id} [get]
+func (h *DataExportHandler) Status(c *gin.Context) {
+	status, err := h.exportUsecase.Status(c.GetUint("userID"), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, domain.ErrExportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newDataExportResponse(status))
+}
+
+// Download 署名付きリンクでアーカイブをダウンロード
+// @Summary      Download Data Export
+// @Description  Download the export archive (ZIP of JSON files) using the signed link from the status endpoint
+// @Tags         user
+// @Produce      application/zip
+// @Param        token  query  string  true  "Signed download token"
+// @Success      200  {file}  binary
+// @Failure      400  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /user/exports/download [get]
+func (h *DataExportHandler) Download(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	archive, err := h.exportUsecase.Download(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrExportNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download export"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="personal-data.zip"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+func newDataExportResponse(status usecase.ExportStatus) DataExportResponse {
+	return DataExportResponse{
+		ID:          status.Job.ID,
+		Status:      status.Job.Status,
+		CreatedAt:   status.Job.CreatedAt,
+		CompletedAt: status.Job.CompletedAt,
+		ExpiresAt:   status.Job.ExpiresAt,
+		DownloadURL: status.DownloadURL,
+	}
+}
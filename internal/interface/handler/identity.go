@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IdentityHandler struct {
+	identityUsecase usecase.IdentityUsecase
+}
+
+func NewIdentityHandler(identityUsecase usecase.IdentityUsecase) *IdentityHandler {
+	return &IdentityHandler{identityUsecase: identityUsecase}
+}
+
+// サインインに使える識別子
+type IdentityResponse struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+type SetUsernameRequest struct {
+	Username string `json:"username" validate:"required,max=64"`
+}
+
+type StartPhoneRequest struct {
+	Phone string `json:"phone" validate:"required,max=32"` // E.164形式（空白・ハイフン・括弧は無視する）
+}
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" validate:"required,max=16"`
+}
+
+// List サインインに使える識別子の一覧
+// @Summary      List Identities
+// @Description  List the email address, username and phone number of the authenticated user with their verification state
+// @Tags         user
+// @Produce      json
+// @Success      200  {array}   IdentityResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /user/me/identities [get]
+func (h *IdentityHandler) List(c *gin.Context) {
+	identities, err := h.identityUsecase.List(c.GetUint("userID"))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list identities"})
+		return
+	}
+
+	response := make([]IdentityResponse, 0, len(identities))
+	for i := range identities {
+		response = append(response, newIdentityResponse(&identities[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetUsername ユーザー名を設定
+// @Summary      Set Username
+// @Description  Set or replace the username used for sign-in. Reserved names and names confusable with them or with existing usernames are rejected. Requires recent authentication
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      SetUsernameRequest  true  "Username"
+// @Success      200   {object}  IdentityResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Router       /user/me/username [put]
+func (h *IdentityHandler) SetUsername(c *gin.Context) {
+	var req SetUsernameRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	identity, err := h.identityUsecase.SetUsername(c.GetUint("userID"), req.Username)
+	if err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newIdentityResponse(&identity))
+}
+
+// StartPhone 電話番号を登録して確認コードを送る
+// @Summary      Add Phone Number
+// @Description  Register an E.164 phone number and send a verification code by SMS. The number can be used for sign-in once verified. Requires recent authentication
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      StartPhoneRequest  true  "Phone number"
+// @Success      202   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Router       /user/me/phone [post]
+func (h *IdentityHandler) StartPhone(c *gin.Context) {
+	var req StartPhoneRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.identityUsecase.StartPhone(c.GetUint("userID"), req.Phone); err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification code sent"})
+}
+
+// VerifyPhone 確認コードで電話番号を確認
+// @Summary      Verify Phone Number
+// @Description  Verify the registered phone number with the code sent by SMS
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        body  body      VerifyPhoneRequest  true  "Verification code"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      409   {object}  map[string]string
+// @Router       /user/me/phone/verify [post]
+func (h *IdentityHandler) VerifyPhone(c *gin.Context) {
+	var req VerifyPhoneRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.identityUsecase.VerifyPhone(c.GetUint("userID"), req.Code); err != nil {
+		respondIdentityError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Phone number verified"})
+}
+
+// RemoveUsername ユーザー名を削除
+// @Summary      Remove Username
+// @Description  Remove the username. Requires recent authentication
+// @Tags         user
+// @Produce      json
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Router       /user/me/username [delete]
+func (h *IdentityHandler) RemoveUsername(c *gin.Context) {
+	h.remove(c, domain.IdentityUsername)
+}
+
+// RemovePhone 電話番号を削除
+// @Summary      Remove Phone Number
+// @Description  Remove the phone number. Requires recent authentication
+// @Tags         user
+// @Produce      json
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Router       /user/me/phone [delete]
+func (h *IdentityHandler) RemovePhone(c *gin.Context) {
+	h.remove(c, domain.IdentityPhone)
+}
+
+func (h *IdentityHandler) remove(c *gin.Context, identityType string) {
+	if err := h.identityUsecase.Remove(c.GetUint("userID"), identityType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove identity"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondIdentityError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidIdentifier),
+		errors.Is(err, domain.ErrUsernameNotAllowed),
+		errors.Is(err, domain.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrIdentifierTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update identity"})
+	}
+}
+
+func newIdentityResponse(identity *domain.Identity) IdentityResponse {
+	return IdentityResponse{
+		Type:       identity.Type,
+		Value:      identity.Value,
+		Verified:   identity.Verified(),
+		VerifiedAt: identity.VerifiedAt,
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfaUsecase usecase.MFAUsecase
+}
+
+func NewMFAHandler(mfaUsecase usecase.MFAUsecase) *MFAHandler {
+	return &MFAHandler{mfaUsecase: mfaUsecase}
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// EnrollTOTP TOTPの登録を開始
+// @Summary      Enroll TOTP
+// @Description  Generate a TOTP secret and otpauth:// URI for the authenticated user
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  TOTPEnrollResponse
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	enrollment, err := h.mfaUsecase.EnrollTOTP(c.GetUint("userID"))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TOTPEnrollResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI})
+}
+
+// ConfirmTOTP 初回コードでTOTPを有効化
+// @Summary      Confirm TOTP
+// @Description  Confirm TOTP enrollment with the first code and receive recovery codes
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      MFACodeRequest  true  "TOTP code"
+// @Success      200   {object}  RecoveryCodesResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	var req MFACodeRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaUsecase.ConfirmTOTP(c.GetUint("userID"), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP TOTPを無効化
+// @Summary      Disable TOTP
+// @Description  Disable TOTP after verifying a current code or recovery code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      MFACodeRequest  true  "TOTP or recovery code"
+// @Success      200   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	var req MFACodeRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.mfaUsecase.DisableTOTP(c.GetUint("userID"), req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA disabled"})
+}
+
+// RegenerateRecoveryCodes リカバリーコードを再発行
+// @Summary      Regenerate Recovery Codes
+// @Description  Replace all recovery codes after verifying a current code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      MFACodeRequest  true  "TOTP or recovery code"
+// @Success      200   {object}  RecoveryCodesResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaUsecase.RegenerateRecoveryCodes(c.GetUint("userID"), req.Code)
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Verify MFAチャレンジを完了してJWTを発行
+// @Summary      Verify MFA
+// @Description  Complete sign-in with the MFA token from /auth/sign-in and a TOTP or recovery code
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        body  body      MFAVerifyRequest  true  "MFA verification payload"
+// @Success      200   {object}  SignInResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Router       /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c *gin.Context) {
+	var req MFAVerifyRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	token, err := h.mfaUsecase.Verify(req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SignInResponse{Token: token})
+}
+
+// bindAndValidate JSONボディのバインドとバリデーション（失敗時は応答済みでfalse）
+func bindAndValidate(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return false
+	}
+
+	validationErrors := utils.ValidateStruct(req)
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+		return false
+	}
+	return true
+}
+
+func respondMFAError(c *gin.Context, err error) {
+	var locked *domain.LockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many attempts, try again later"})
+	case errors.Is(err, domain.ErrInvalidMFACode), errors.Is(err, domain.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrMFANotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrAccountSuspended), errors.Is(err, domain.ErrApprovalPending):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/oidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 認可リクエストを開始したブラウザに state を結び付ける Cookie
+// 他人の callback URL を踏ませて攻撃者のアカウントでサインインさせる攻撃（login CSRF）を防ぐ
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcUsecase usecase.OIDCUsecase
+	cfg         config.OIDCConfig
+}
+
+func NewOIDCHandler(oidcUsecase usecase.OIDCUsecase, cfg config.OIDCConfig) *OIDCHandler {
+	return &OIDCHandler{oidcUsecase: oidcUsecase, cfg: cfg}
+}
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// ListProviders 利用できる外部プロバイダーの一覧
+// @Summary      List External Identity Providers
+// @Description  List the configured OpenID Connect providers that can be used to sign in
+// @Tags         oidc
+// @Produce      json
+// @Success      200  {object}  OIDCProvidersResponse
+// @Router       /auth/oidc/providers [get]
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, OIDCProvidersResponse{Providers: h.oidcUsecase.Providers()})
+}
+
+// Start 外部プロバイダーでのサインインを開始
+// @Summary      Start External Sign In
+// @Description  Redirect to the provider's authorization endpoint using the authorization code flow with PKCE and nonce
+// @Tags         oidc
+// @Param        provider  path  string  true  "Provider name (e.g. google, microsoft)"
+// @Success      302
+// @Failure      404  {object}  map[string]string
+// @Failure      502  {object}  map[string]string
+// @Router       /auth/oidc/{provider} [get]
+func (h *OIDCHandler) Start(c *gin.Context) {
+	authURL, state, err := h.oidcUsecase.Start(c.Param("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, oidc.ErrDiscovery):
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start external sign-in"})
+		}
+		return
+	}
+
+	// プロバイダーからのリダイレクト（トップレベルの GET）で送られるよう SameSite=Lax にする
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(h.cfg.StateTTL.Seconds()), oidcStateCookiePath, "", h.cfg.CookieSecure, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback 外部プロバイダーからのリダイレクトを受けてサインインを完了
+// @Summary      External Sign In Callback
+// @Description  Exchange the authorization code, validate the ID token against the provider's JWKS and return a JWT token
+// @Tags         oidc
+// @Produce      json
+// @Param        provider  path   string  true   "Provider name"
+// @Param        code      query  string  false  "Authorization code"
+// @Param        state     query  string  true   "State issued at the start of the flow"
+// @Success      200  {object}  SignInResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/oidc/{provider}/callback [get]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", h.cfg.CookieSecure, true)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "External sign-in was cancelled or denied", "provider_error": providerErr})
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "State and code are required"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "State does not match this browser"})
+		return
+	}
+
+	result, err := h.oidcUsecase.Callback(c.Param("provider"), state, code, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrInvalidToken), errors.Is(err, domain.ErrExternalEmailRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrExternalSignInFailed):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrEmailAlreadyExists):
+			// 既存のアカウントには元の方法でサインインしてもらう
+			c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists. Sign in with your existing method"})
+		case errors.Is(err, domain.ErrSignUpRejected):
+			respondScreeningError(c, err)
+		case errors.Is(err, domain.ErrExternalAccountNotLinked),
+			errors.Is(err, domain.ErrExternalEmailUnverified),
+			errors.Is(err, domain.ErrAccountSuspended),
+			errors.Is(err, domain.ErrApprovalPending):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete external sign-in"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, SignInResponse{
+		Token:       result.Token,
+		MFARequired: result.MFARequired(),
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
+	})
+}
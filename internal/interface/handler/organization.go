@@ -0,0 +1,378 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	orgUsecase usecase.OrganizationUsecase
+}
+
+func NewOrganizationHandler(orgUsecase usecase.OrganizationUsecase) *OrganizationHandler {
+	return &OrganizationHandler{orgUsecase: orgUsecase}
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+type OrganizationResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"` // 自分のロール
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListOrganizationsResponse struct {
+	Organizations []OrganizationResponse `json:"organizations"`
+}
+
+type OrgMemberResponse struct {
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+type ListOrgMembersResponse struct {
+	Members []OrgMemberResponse `json:"members"`
+}
+
+type CreateOrgInvitationRequest struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type OrgInvitationResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ListOrgInvitationsResponse struct {
+	Invitations []OrgInvitationResponse `json:"invitations"`
+}
+
+type AcceptOrgInvitationRequest struct {
+	Token string `json:"token" validate:"required,max=2048"`
+}
+
+// 招待からのサインアップ（メールアドレスは招待の宛先を使う）
+type OrgInvitationSignUpRequest struct {
+	Token                string `json:"token" validate:"required,max=2048"`
+	Password             string `json:"password" validate:"required,min=8"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required"`
+}
+
+type OrgInvitationSignUpResponse struct {
+	User         UserResponse         `json:"user"`
+	Organization OrganizationResponse `json:"organization"`
+	Message      string               `json:"message,omitempty"`
+}
+
+// Create 組織を作成
+// @Summary      Create Organization
+// @Description  Create an organization. The caller becomes its admin; the role appears in the orgs claim of tokens issued after the next sign-in
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        body  body  CreateOrganizationRequest  true  "Organization"
+// @Success      201  {object}  OrganizationResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /orgs [post]
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	org, err := h.orgUsecase.Create(c.GetUint("userID"), req.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+	c.JSON(http.StatusCreated, newOrganizationResponse(org, domain.OrgRoleAdmin))
+}
+
+// List 所属する組織の一覧
+// @Summary      List Organizations
+// @Description  List the organizations the authenticated user belongs to
+// @Tags         organizations
+// @Produce      json
+// @Success      200  {object}  ListOrganizationsResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /orgs [get]
+func (h *OrganizationHandler) List(c *gin.Context) {
+	orgs, err := h.orgUsecase.ListForUser(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list organizations"})
+		return
+	}
+
+	response := ListOrganizationsResponse{Organizations: make([]OrganizationResponse, 0, len(orgs))}
+	for _, org := range orgs {
+		response.Organizations = append(response.Organizations, newOrganizationResponse(org.Organization, org.Role))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ListMembers メンバーの一覧
+// @Summary      List Organization Members
+// @Description  List the members of an organization (members only)
+// @Tags         organizations
+// @Produce      json
+// @Param        id   path  string  true  "Organization ID"
+// @Success      200  {object}  ListOrgMembersResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /orgs/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	members, err := h.orgUsecase.ListMembers(c.GetUint("userID"), c.Param("id"))
+	if err != nil {
+		respondOrganizationError(c, err, "Failed to list members")
+		return
+	}
+
+	response := ListOrgMembersResponse{Members: make([]OrgMemberResponse, 0, len(members))}
+	for _, member := range members {
+		response.Members = append(response.Members, OrgMemberResponse{
+			UserID:      member.UserPublicID,
+			Email:       member.Email,
+			DisplayName: member.DisplayName,
+			Role:        member.Role,
+			JoinedAt:    member.JoinedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RemoveMember メンバーを外す
+// @Summary      Remove Organization Member
+// @Description  Remove a member (admin only) or leave the organization (own user ID). The last admin cannot be removed. The member's tokens are revoked
+// @Tags         organizations
+// @Param        id      path  string  true  "Organization ID"
+// @Param        userId  path  string  true  "User ID of the member"
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /orgs/{id}/members/{userId} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	if err := h.orgUsecase.RemoveMember(c.GetUint("userID"), c.Param("id"), c.Param("userId")); err != nil {
+		respondOrganizationError(c, err, "Failed to remove member")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Invite メールアドレスを組織に招待
+// @Summary      Invite to Organization
+// @Description  Invite an email address with a role and send a signed invitation link (admin only). A pending invitation to the same address is replaced
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        id    path  string                      true  "Organization ID"
+// @Param        body  body  CreateOrgInvitationRequest  true  "Invitation"
+// @Success      201  {object}  OrgInvitationResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /orgs/{id}/invitations [post]
+func (h *OrganizationHandler) Invite(c *gin.Context) {
+	var req CreateOrgInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	invitation, err := h.orgUsecase.Invite(c.GetUint("userID"), c.Param("id"), req.Email, req.Role)
+	if err != nil {
+		respondOrganizationError(c, err, "Failed to create invitation")
+		return
+	}
+	c.JSON(http.StatusCreated, newOrgInvitationResponse(invitation))
+}
+
+// ListInvitations 未使用の招待の一覧
+// @Summary      List Organization Invitations
+// @Description  List pending invitations of an organization (admin only)
+// @Tags         organizations
+// @Produce      json
+// @Param        id   path  string  true  "Organization ID"
+// @Success      200  {object}  ListOrgInvitationsResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /orgs/{id}/invitations [get]
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	invitations, err := h.orgUsecase.ListInvitations(c.GetUint("userID"), c.Param("id"))
+	if err != nil {
+		respondOrganizationError(c, err, "Failed to list invitations")
+		return
+	}
+
+	response := ListOrgInvitationsResponse{Invitations: make([]OrgInvitationResponse, 0, len(invitations))}
+	for _, invitation := range invitations {
+		response.Invitations = append(response.Invitations, newOrgInvitationResponse(invitation))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeInvitation 招待を取り消す
+// @Summary      Revoke Organization Invitation
+// @Description  Revoke a pending invitation so its link can no longer be used (admin only)
+// @Tags         organizations
+// @Param        id            path  string  true  "Organization ID"
+// @Param        invitationId  path  string  true  "Invitation ID"
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /orgs/{id}/invitations/{invitationId} [delete]
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	if err := h.orgUsecase.RevokeInvitation(c.GetUint("userID"), c.Param("id"), c.Param("invitationId")); err != nil {
+		if errors.Is(err, domain.ErrInvalidInvitation) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondOrganizationError(c, err, "Failed to revoke invitation")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Accept サインイン中のアカウントで招待を受諾
+// @Summary      Accept Organization Invitation
+// @Description  Join the organization with the authenticated account. The account email must match the invited address. Existing tokens are revoked so the new role is picked up at the next sign-in
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        body  body  AcceptOrgInvitationRequest  true  "Invitation token from the link"
+// @Success      200  {object}  OrganizationResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /org-invitations/accept [post]
+func (h *OrganizationHandler) Accept(c *gin.Context) {
+	var req AcceptOrgInvitationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	org, err := h.orgUsecase.Accept(c.GetUint("userID"), req.Token)
+	if err != nil {
+		respondOrganizationError(c, err, "Failed to accept invitation")
+		return
+	}
+	c.JSON(http.StatusOK, newOrganizationResponse(org, ""))
+}
+
+// SignUp 招待からアカウントを作成して受諾
+// @Summary      Sign Up with Organization Invitation
+// @Description  Create an account for the invited address and join the organization. The address is treated as verified. Domain restrictions, sign-up screening and admin approval still apply
+// @Tags         organizations
+// @Accept       json
+// @Produce      json
+// @Param        body  body  OrgInvitationSignUpRequest  true  "Invitation token and password"
+// @Param        X-Challenge-Response  header  string  false  "Proof-of-work response (challenge:nonce) when required"
+// @Success      201  {object}  OrgInvitationSignUpResponse
+// @Success      202  {object}  OrgInvitationSignUpResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /org-invitations/sign-up [post]
+func (h *OrganizationHandler) SignUp(c *gin.Context) {
+	var req OrgInvitationSignUpRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Password != req.PasswordConfirmation {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password and confirmation do not match"})
+		return
+	}
+
+	user, org, err := h.orgUsecase.AcceptWithSignUp(req.Token, req.Password, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrEmailAlreadyExists):
+			// 既存のアカウントはサインインしてから /org-invitations/accept で受諾する
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrEmailDomainNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrSignUpRejected):
+			respondScreeningError(c, err)
+		default:
+			respondOrganizationError(c, err, "Failed to sign up")
+		}
+		return
+	}
+
+	response := OrgInvitationSignUpResponse{
+		User:         UserResponse{ID: user.PublicID, Email: user.Email},
+		Organization: newOrganizationResponse(org, ""),
+	}
+	if !user.Approved() {
+		response.Message = "Your account is awaiting approval"
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// bindJSON リクエストボディを読み込んで検証する（失敗時は400を返して false）
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return false
+	}
+	if validationErrors := utils.ValidateStruct(req); validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+		return false
+	}
+	return true
+}
+
+// respondOrganizationError 組織の操作のエラーをステータスコードに変換する
+func respondOrganizationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, domain.ErrOrganizationNotFound), errors.Is(err, domain.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrOrgAdminRequired), errors.Is(err, domain.ErrInvitationEmailMismatch),
+		errors.Is(err, domain.ErrInvalidInvitation):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrAlreadyMember), errors.Is(err, domain.ErrLastOrgAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+func newOrganizationResponse(org domain.Organization, role string) OrganizationResponse {
+	return OrganizationResponse{ID: org.PublicID, Name: org.Name, Role: role, CreatedAt: org.CreatedAt}
+}
+
+func newOrgInvitationResponse(invitation domain.OrgInvitation) OrgInvitationResponse {
+	return OrgInvitationResponse{
+		ID:        invitation.PublicID,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
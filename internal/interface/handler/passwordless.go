@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordlessHandler struct {
+	passwordlessUsecase usecase.PasswordlessUsecase
+}
+
+func NewPasswordlessHandler(passwordlessUsecase usecase.PasswordlessUsecase) *PasswordlessHandler {
+	return &PasswordlessHandler{passwordlessUsecase: passwordlessUsecase}
+}
+
+type PasswordlessStartRequest struct {
+	Email  string `json:"email" validate:"required,email"`
+	Method string `json:"method" validate:"omitempty,oneof=link code"`
+}
+
+// email と code、または token のどちらかを指定する
+type PasswordlessVerifyRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+	Code  string `json:"code" validate:"omitempty,len=6,numeric"`
+	Token string `json:"token"`
+}
+
+// Start パスワードレスサインインを開始
+// @Summary      Start Passwordless Sign In
+// @Description  Send a magic link or a 6-digit code to the email address
+// @Tags         passwordless
+// @Accept       json
+// @Produce      json
+// @Param        body  body      PasswordlessStartRequest  true  "Passwordless start payload"
+// @Success      202   {object}  map[string]string
+// @Failure      400   {object}  map[string]string
+// @Router       /auth/passwordless/start [post]
+func (h *PasswordlessHandler) Start(c *gin.Context) {
+	var req PasswordlessStartRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if req.Method == "" {
+		req.Method = domain.PasswordlessMethodCode
+	}
+
+	if err := h.passwordlessUsecase.Start(req.Email, req.Method); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passwordless sign-in"})
+		return
+	}
+
+	// アカウントの有無にかかわらず同じ応答を返す
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your email to continue"})
+}
+
+// Verify パスワードレスサインインを完了
+// @Summary      Verify Passwordless Sign In
+// @Description  Verify a magic link token or a one-time code and return a JWT token
+// @Tags         passwordless
+// @Accept       json
+// @Produce      json
+// @Param        body  body      PasswordlessVerifyRequest  true  "Passwordless verify payload"
+// @Param        X-Challenge-Response  header  string  false  "Proof-of-work response (challenge:nonce) when an account is created and required"
+// @Success      200   {object}  SignInResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      401   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Failure      429   {object}  map[string]string
+// @Router       /auth/passwordless/verify [post]
+func (h *PasswordlessHandler) Verify(c *gin.Context) {
+	var req PasswordlessVerifyRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if req.Token == "" && (req.Email == "" || req.Code == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either token, or email and code are required"})
+		return
+	}
+
+	var (
+		result usecase.SignInResult
+		err    error
+	)
+	if req.Token != "" {
+		result, err = h.passwordlessUsecase.VerifyToken(req.Token, clientInfo(c))
+	} else {
+		result, err = h.passwordlessUsecase.VerifyCode(req.Email, req.Code, clientInfo(c))
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrAccountSuspended) || errors.Is(err, domain.ErrApprovalPending) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrSignUpRejected) {
+			// 初回サインインでアカウントを作成する場合のみ
+			respondScreeningError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify"})
+		return
+	}
+
+	c.JSON(http.StatusOK, SignInResponse{
+		Token:       result.Token,
+		MFARequired: result.MFARequired(),
+		MFAToken:    result.MFAToken,
+		MFAMethods:  result.MFAMethods,
+	})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RegistrationHandler struct {
+	registrationUsecase usecase.RegistrationUsecase
+}
+
+func NewRegistrationHandler(registrationUsecase usecase.RegistrationUsecase) *RegistrationHandler {
+	return &RegistrationHandler{registrationUsecase: registrationUsecase}
+}
+
+// email を指定すると、そのアドレスでのみ使える招待になり、招待メールも送る
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type InvitationResponse struct {
+	Token     string    `json:"token"`
+	Email     string    `json:"email,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// 却下の理由（申請者へのメールに記載する）
+type RejectUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// CreateInvitation サインアップの招待を発行
+// @Summary      Create Sign-up Invitation
+// @Description  Issue a single-use invitation token for invite-only sign-up (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        body  body      CreateInvitationRequest  false  "Invitee"
+// @Success      201   {object}  InvitationResponse
+// @Failure      400   {object}  map[string]string
+// @Failure      403   {object}  map[string]string
+// @Router       /admin/invitations [post]
+func (h *RegistrationHandler) CreateInvitation(c *gin.Context) {
+	var req CreateInvitationRequest
+	if c.Request.ContentLength != 0 && !bindAndValidate(c, &req) {
+		return
+	}
+
+	invitation, err := h.registrationUsecase.CreateInvitation(c.GetUint("userID"), req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrFeatureDisabled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Sign-up is not invite-only"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, InvitationResponse{
+		Token:     invitation.Token,
+		Email:     invitation.Email,
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+// ApproveUser 承認待ちのアカウントを承認
+// @Summary      Approve User
+// @Description  Approve a pending sign-up and notify the applicant by email (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/approve [post]
+func (h *RegistrationHandler) ApproveUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.registrationUsecase.Approve(c.GetUint("userID"), publicID); err != nil {
+		respondRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User approved"})
+}
+
+// RejectUser 承認待ちのアカウントを却下
+// @Summary      Reject User
+// @Description  Reject a pending sign-up, delete the account and notify the applicant by email (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string             true   "User public ID" Format(uuid)
+// @Param        body  body      RejectUserRequest  false  "Reason"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /admin/users/{id}/reject [post]
+func (h *RegistrationHandler) RejectUser(c *gin.Context) {
+	publicID := c.Param("id")
+	if !utils.IsUUID(publicID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req RejectUserRequest
+	if c.Request.ContentLength != 0 && !bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.registrationUsecase.Reject(c.GetUint("userID"), publicID, req.Reason); err != nil {
+		respondRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User rejected"})
+}
+
+func respondRegistrationError(c *gin.Context, err error) {
+	if errors.Is(err, domain.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending sign-up found for this user"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sign-up"})
+}
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"user-jwt/internal/domain"
 	"user-jwt/internal/usecase"
 	"user-jwt/pkg/utils"
 
@@ -19,40 +24,210 @@ func NewUserHandler(userUsecase usecase.UserUsecase) *UserHandler {
 }
 
 type GetUserByIDRequest struct {
-	ID uint `validate:"required,min=1"`
+	ID string `validate:"required,uuid"`
 }
 
 // GetUserByID ユーザーIDで情報を取得
+// 本人と管理者には全項目、他のユーザーには公開プロフィールのみを返す
 // @Summary      Get User by ID
-// @Description  Retrieve user information using the user ID
+// @Description  Retrieve user information using the user ID. Other users' records are limited to the public profile; private or missing accounts return 404
 // @Tags         user
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int     true  "User ID" Format(int64)
-// @Success      200  {object}  map[string]interface{}  "Successful Response"
+// @Param        id   path      string  true  "User public ID" Format(uuid)
+// @Success      200  {object}  ProfileResponse         "Own record or admin view (other users receive PublicProfileResponse)"
 // @Failure      400  {object}  map[string]string       "Invalid User ID"
 // @Failure      404  {object}  map[string]string       "User Not Found"
 // @Router       /user/{id} [get]
 func (h *UserHandler) GetUserByID(c *gin.Context) {
-	idParam := c.Param("id")
-	userID, err := strconv.Atoi(idParam)
+	req := GetUserByIDRequest{ID: c.Param("id")}
+	validationErrors := utils.ValidateStruct(&req)
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+		return
+	}
+
+	viewer := domain.Viewer{UserID: c.GetUint("userID"), Role: c.GetString("role")}
+	view, err := h.userUsecase.ViewUser(viewer, req.ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
 		return
 	}
 
-	req := GetUserByIDRequest{ID: uint(userID)}
-	validationErrors := utils.ValidateStruct(&req)
-	if validationErrors != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": validationErrors})
+	if !view.Full {
+		c.JSON(http.StatusOK, newPublicProfileResponse(view.User))
 		return
 	}
+	c.JSON(http.StatusOK, newProfileResponse(view.User))
+}
 
-	user, err := h.userUsecase.GetUserByID(uint(userID))
+// 他のユーザーに見せる公開プロフィール
+type PublicProfileResponse struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+func newPublicProfileResponse(user *domain.User) PublicProfileResponse {
+	return PublicProfileResponse{
+		ID:          user.PublicID,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+	}
+}
+
+// 自分のプロフィールの応答
+type ProfileResponse struct {
+	ID            string     `json:"id"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	DisplayName   string     `json:"display_name"`
+	Locale        string     `json:"locale"`
+	Timezone      string     `json:"timezone"`
+	AvatarURL     string     `json:"avatar_url"`
+	Visibility    string     `json:"visibility"`
+	DeletionDueAt *time.Time `json:"deletion_due_at,omitempty"` // 削除予定の場合のみ
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// プロフィール更新リクエスト（指定した項目のみ更新し、空文字で値を消去する）
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=64"`
+	Locale      *string `json:"locale" validate:"omitempty,bcp47_language_tag"`
+	Timezone    *string `json:"timezone" validate:"omitempty,timezone"`
+	AvatarURL   *string `json:"avatar_url" validate:"omitempty,http_url,max=2048"`
+	Visibility  *string `json:"visibility" validate:"omitempty,oneof=public private"`
+}
+
+// GetMe 認証中のユーザーのプロフィールを取得
+// @Summary      Get Current User
+// @Description  Retrieve the profile of the authenticated user
+// @Tags         user
+// @Produce      json
+// @Success      200  {object}  ProfileResponse
+// @Success      304  "Not Modified"
+// @Failure      401  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /user/me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	user, err := h.userUsecase.GetUserByID(c.GetUint("userID"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if errors.Is(err, domain.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"userID": user.ID, "email": user.Email})
+	etag := profileETag(user)
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, newProfileResponse(user))
+}
+
+// UpdateMe 認証中のユーザーのプロフィールを更新
+// @Summary      Update Current User
+// @Description  Partially update the profile of the authenticated user. Requires If-Match with the ETag from GET /user/me
+// @Tags         user
+// @Accept       json
+// @Produce      json
+// @Param        If-Match  header  string                true  "ETag of the profile"
+// @Param        body      body    UpdateProfileRequest  true  "Profile fields to update"
+// @Success      200  {object}  ProfileResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      412  {object}  map[string]string
+// @Failure      428  {object}  map[string]string
+// @Router       /user/me [patch]
+func (h *UserHandler) UpdateMe(c *gin.Context) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+	version, ok := parseProfileETag(ifMatch, c.GetString("publicID"))
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": domain.ErrVersionMismatch.Error()})
+		return
+	}
+
+	var req UpdateProfileRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	user, err := h.userUsecase.UpdateProfile(c.GetUint("userID"), version, domain.ProfileUpdate{
+		DisplayName: trimmed(req.DisplayName),
+		Locale:      req.Locale,
+		Timezone:    req.Timezone,
+		AvatarURL:   req.AvatarURL,
+		Visibility:  req.Visibility,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrVersionMismatch):
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		}
+		return
+	}
+
+	c.Header("ETag", profileETag(user))
+	c.JSON(http.StatusOK, newProfileResponse(user))
+}
+
+func newProfileResponse(user *domain.User) ProfileResponse {
+	return ProfileResponse{
+		ID:            user.PublicID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified(),
+		DisplayName:   user.DisplayName,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		AvatarURL:     user.AvatarURL,
+		Visibility:    user.Visibility,
+		DeletionDueAt: user.DeletionDueAt,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+}
+
+// profileETag プロフィールのバージョンからETagを作る（例: "<公開ID>.3"）
+func profileETag(user *domain.User) string {
+	return fmt.Sprintf(`"%s.%d"`, user.PublicID, user.Version)
+}
+
+// parseProfileETag If-Matchの値からバージョンを取り出す（他のユーザーのETagは受け付けない）
+func parseProfileETag(value string, publicID string) (int, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	id, version, ok := strings.Cut(value, ".")
+	if !ok || id != publicID {
+		return 0, false
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func trimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
 }
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPublicID = "0190a6f2-7b3c-7d4e-8f00-000000000001"
+
+// fakeUserUsecase 1人分のプロフィールをメモリに保持する
+// インターフェースを埋め込み、テストで使うメソッドだけを実装する
+type fakeUserUsecase struct {
+	usecase.UserUsecase
+	user    domain.User
+	updates int
+}
+
+func (u *fakeUserUsecase) GetUserByID(userID uint) (*domain.User, error) {
+	if userID != u.user.ID {
+		return nil, domain.ErrUserNotFound
+	}
+	user := u.user
+	return &user, nil
+}
+
+// ViewUser 本人には全項目、他のユーザーには公開プロフィールを返す
+func (u *fakeUserUsecase) ViewUser(viewer domain.Viewer, publicID string) (usecase.UserView, error) {
+	if publicID != u.user.PublicID {
+		return usecase.UserView{}, domain.ErrUserNotFound
+	}
+	user := u.user
+	return usecase.UserView{User: &user, Full: viewer.UserID == user.ID}, nil
+}
+
+func (u *fakeUserUsecase) UpdateProfile(userID uint, version int, update domain.ProfileUpdate) (*domain.User, error) {
+	if version != u.user.Version {
+		return nil, domain.ErrVersionMismatch
+	}
+	if update.DisplayName != nil {
+		u.user.DisplayName = *update.DisplayName
+	}
+	u.user.Version++
+	u.updates++
+	user := u.user
+	return &user, nil
+}
+
+func newUserTestRouter(uc *fakeUserUsecase) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewUserHandler(uc)
+	r := gin.New()
+	// 認証ミドルウェアの代わりにユーザーを設定する
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		c.Set("publicID", testPublicID)
+		c.Next()
+	})
+	r.GET("/user/me", h.GetMe)
+	r.PATCH("/user/me", h.UpdateMe)
+	r.GET("/user/:id", h.GetUserByID)
+	return r
+}
+
+func serve(r http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetMeETag(t *testing.T) {
+	uc := &fakeUserUsecase{user: domain.User{ID: 1, PublicID: testPublicID, Version: 3}}
+	r := newUserTestRouter(uc)
+	etag := `"` + testPublicID + `.3"`
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "no condition", wantStatus: http.StatusOK},
+		{name: "matching ETag", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "stale ETag", ifNoneMatch: `"` + testPublicID + `.2"`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/user/me", "", map[string]string{"If-None-Match": tt.ifNoneMatch})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("ETag = %q, want %q", got, etag)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("304 response has a body: %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestUpdateMeIfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifMatch     string
+		body        string
+		wantStatus  int
+		wantUpdated bool
+		wantETag    string
+	}{
+		{name: "missing If-Match", body: `{"display_name":"Alice"}`, wantStatus: http.StatusPreconditionRequired},
+		{name: "current version", ifMatch: `"` + testPublicID + `.3"`, body: `{"display_name":" Alice "}`,
+			wantStatus: http.StatusOK, wantUpdated: true, wantETag: `"` + testPublicID + `.4"`},
+		{name: "unquoted with spaces", ifMatch: ` ` + testPublicID + `.3 `, body: `{"display_name":"Alice"}`,
+			wantStatus: http.StatusOK, wantUpdated: true, wantETag: `"` + testPublicID + `.4"`},
+		{name: "stale version", ifMatch: `"` + testPublicID + `.2"`, body: `{"display_name":"Alice"}`, wantStatus: http.StatusPreconditionFailed},
+		{name: "another user's ETag", ifMatch: `"0190a6f2-7b3c-7d4e-8f00-000000000002.3"`, body: `{"display_name":"Alice"}`, wantStatus: http.StatusPreconditionFailed},
+		{name: "malformed ETag", ifMatch: `"` + testPublicID + `"`, body: `{"display_name":"Alice"}`, wantStatus: http.StatusPreconditionFailed},
+		{name: "non-numeric version", ifMatch: `"` + testPublicID + `.x"`, body: `{"display_name":"Alice"}`, wantStatus: http.StatusPreconditionFailed},
+		// 他のユーザーのETagは入力の検証より先に拒否する
+		{name: "another user's ETag with invalid body", ifMatch: `"0190a6f2-7b3c-7d4e-8f00-000000000002.3"`, body: `{"visibility":"friends"}`, wantStatus: http.StatusPreconditionFailed},
+		{name: "invalid body", ifMatch: `"` + testPublicID + `.3"`, body: `{"visibility":"friends"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &fakeUserUsecase{user: domain.User{ID: 1, PublicID: testPublicID, Version: 3}}
+			w := serve(newUserTestRouter(uc), http.MethodPatch, "/user/me", tt.body, map[string]string{"If-Match": tt.ifMatch})
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if (uc.updates == 1) != tt.wantUpdated {
+				t.Fatalf("updates = %d", uc.updates)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("ETag = %q, want %q", got, tt.wantETag)
+			}
+			if tt.wantUpdated {
+				var resp ProfileResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.DisplayName != "Alice" {
+					t.Fatalf("display_name = %q", resp.DisplayName)
+				}
+			}
+		})
+	}
+}
+
+// 取得したETagで更新でき、同じETagでの2回目の更新は競合として拒否される
+func TestUpdateMeLostUpdate(t *testing.T) {
+	uc := &fakeUserUsecase{user: domain.User{ID: 1, PublicID: testPublicID, Version: 1}}
+	r := newUserTestRouter(uc)
+
+	etag := serve(r, http.MethodGet, "/user/me", "", nil).Header().Get("ETag")
+	first := serve(r, http.MethodPatch, "/user/me", `{"display_name":"Alice"}`, map[string]string{"If-Match": etag})
+	if first.Code != http.StatusOK {
+		t.Fatalf("first update: status = %d", first.Code)
+	}
+	second := serve(r, http.MethodPatch, "/user/me", `{"display_name":"Bob"}`, map[string]string{"If-Match": etag})
+	if second.Code != http.StatusPreconditionFailed {
+		t.Fatalf("second update: status = %d", second.Code)
+	}
+	if uc.user.DisplayName != "Alice" {
+		t.Fatalf("display_name = %q", uc.user.DisplayName)
+	}
+}
+
+// 他のユーザーには公開プロフィールだけを返し、連番のIDでは参照できない
+func TestGetUserByIDProjection(t *testing.T) {
+	const otherPublicID = "0190a6f2-7b3c-7d4e-8f00-000000000002"
+	tests := []struct {
+		name       string
+		user       domain.User
+		path       string
+		wantStatus int
+		wantEmail  bool
+	}{
+		{name: "self", user: domain.User{ID: 1, PublicID: testPublicID, Email: "alice@example.com"},
+			path: "/user/" + testPublicID, wantStatus: http.StatusOK, wantEmail: true},
+		{name: "other user", user: domain.User{ID: 2, PublicID: otherPublicID, Email: "bob@example.com", DisplayName: "Bob"},
+			path: "/user/" + otherPublicID, wantStatus: http.StatusOK},
+		{name: "not found", user: domain.User{ID: 2, PublicID: otherPublicID},
+			path: "/user/0190a6f2-7b3c-7d4e-8f00-00000000ffff", wantStatus: http.StatusNotFound},
+		{name: "numeric id", user: domain.User{ID: 2, PublicID: otherPublicID},
+			path: "/user/2", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newUserTestRouter(&fakeUserUsecase{user: tt.user})
+			w := serve(r, http.MethodGet, tt.path, "", nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := body["email"]; ok != tt.wantEmail {
+				t.Fatalf("body = %v, want email included = %v", body, tt.wantEmail)
+			}
+			if body["id"] != tt.user.PublicID {
+				t.Fatalf("id = %v, want %s", body["id"], tt.user.PublicID)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/usecase"
+	"user-jwt/pkg/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnHandler struct {
+	webAuthnUsecase usecase.WebAuthnUsecase
+}
+
+func NewWebAuthnHandler(webAuthnUsecase usecase.WebAuthnUsecase) *WebAuthnHandler {
+	return &WebAuthnHandler{webAuthnUsecase: webAuthnUsecase}
+}
+
+type WebAuthnCreationResponse struct {
+	SessionID string                   `json:"session_id"`
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+type WebAuthnRequestResponse struct {
+	SessionID string                  `json:"session_id"`
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+type WebAuthnRegisterFinishRequest struct {
+	SessionID  string                       `json:"session_id" validate:"required"`
+	Name       string                       `json:"name" validate:"max=64"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type WebAuthnLoginFinishRequest struct {
+	SessionID  string                     `json:"session_id" validate:"required"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type WebAuthnMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+}
+
+type WebAuthnMFAFinishRequest struct {
+	MFAToken   string                     `json:"mfa_token" validate:"required"`
+	SessionID  string                     `json:"session_id" validate:"required"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ID                uint       `json:"id"`
+	Name              string     `json:"name"`
+	AttestationFormat string     `json:"attestation_format"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+}
+
+// BeginRegistration パスキー登録を開始
+// @Summary      Begin Passkey Registration
+// @Description  Start a WebAuthn registration ceremony for the authenticated user
+// @Tags         webauthn
+// @Produce      json
+// @Success      200  {object}  WebAuthnCreationResponse
+// @Router       /auth/webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	sessionID, opts, err := h.webAuthnUsecase.BeginRegistration(c.GetUint("userID"))
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, WebAuthnCreationResponse{SessionID: sessionID, PublicKey: opts})
+}
+
+// FinishRegistration パスキー登録を完了
+// @Summary      Finish Passkey Registration
+// @Description  Verify the attestation and store the new passkey
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        body  body      WebAuthnRegisterFinishRequest  true  "Attestation response"
+// @Success      201   {object}  WebAuthnCredentialResponse
+// @Failure      400   {object}  map[string]string
+// @Router       /auth/webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	var req WebAuthnRegisterFinishRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	cred, err := h.webAuthnUsecase.FinishRegistration(c.GetUint("userID"), req.SessionID, req.Name, req.Credential)
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toWebAuthnCredentialResponse(cred))
+}
+
+// BeginLogin パスキーのみでのサインインを開始
+// @Summary      Begin Passkey Sign In
+// @Description  Start a WebAuthn assertion ceremony for passwordless sign-in
+// @Tags         webauthn
+// @Produce      json
+// @Success      200  {object}  WebAuthnRequestResponse
+// @Failure      404  {object}  map[string]string
+// @Router       /auth/webauthn/login/begin [post]
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	sessionID, opts, err := h.webAuthnUsecase.BeginLogin()
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, WebAuthnRequestResponse{SessionID: sessionID, PublicKey: opts})
+}
+
+// FinishLogin パスキーのみでのサインインを完了
+// @Summary      Finish Passkey Sign In
+// @Description  Verify the assertion and return a JWT token
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        body  body      WebAuthnLoginFinishRequest  true  "Assertion response"
+// @Success      200   {object}  SignInResponse
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/webauthn/login/finish [post]
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var req WebAuthnLoginFinishRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	token, err := h.webAuthnUsecase.FinishLogin(req.SessionID, req.Credential, clientInfo(c))
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SignInResponse{Token: token})
+}
+
+// BeginMFA パスキーによる第二要素認証を開始
+// @Summary      Begin Passkey MFA
+// @Description  Start a WebAuthn assertion ceremony using the MFA token from /auth/sign-in
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        body  body      WebAuthnMFABeginRequest  true  "MFA token"
+// @Success      200   {object}  WebAuthnRequestResponse
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/webauthn/mfa/begin [post]
+func (h *WebAuthnHandler) BeginMFA(c *gin.Context) {
+	var req WebAuthnMFABeginRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	sessionID, opts, err := h.webAuthnUsecase.BeginMFA(req.MFAToken)
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, WebAuthnRequestResponse{SessionID: sessionID, PublicKey: opts})
+}
+
+// FinishMFA パスキーによる第二要素認証を完了
+// @Summary      Finish Passkey MFA
+// @Description  Verify the assertion and return a JWT token
+// @Tags         webauthn
+// @Accept       json
+// @Produce      json
+// @Param        body  body      WebAuthnMFAFinishRequest  true  "Assertion response"
+// @Success      200   {object}  SignInResponse
+// @Failure      401   {object}  map[string]string
+// @Router       /auth/webauthn/mfa/finish [post]
+func (h *WebAuthnHandler) FinishMFA(c *gin.Context) {
+	var req WebAuthnMFAFinishRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+
+	token, err := h.webAuthnUsecase.FinishMFA(req.MFAToken, req.SessionID, req.Credential, clientInfo(c))
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SignInResponse{Token: token})
+}
+
+// ListCredentials 登録済みパスキーの一覧
+// @Summary      List Passkeys
+// @Description  List passkeys registered by the authenticated user
+// @Tags         webauthn
+// @Produce      json
+// @Success      200  {array}  WebAuthnCredentialResponse
+// @Router       /auth/webauthn/credentials [get]
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	creds, err := h.webAuthnUsecase.ListCredentials(c.GetUint("userID"))
+	if err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+
+	response := make([]WebAuthnCredentialResponse, 0, len(creds))
+	for _, cred := range creds {
+		response = append(response, toWebAuthnCredentialResponse(cred))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteCredential パスキーを削除
+// @Summary      Delete Passkey
+// @Description  Remove a passkey registered by the authenticated user
+// @Tags         webauthn
+// @Produce      json
+// @Param        id   path      int  true  "Credential ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  map[string]string
+// @Router       /auth/webauthn/credentials/{id} [delete]
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credential ID"})
+		return
+	}
+
+	if err := h.webAuthnUsecase.DeleteCredential(c.GetUint("userID"), uint(id)); err != nil {
+		respondWebAuthnError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey removed"})
+}
+
+func toWebAuthnCredentialResponse(cred domain.WebAuthnCredential) WebAuthnCredentialResponse {
+	return WebAuthnCredentialResponse{
+		ID:                cred.ID,
+		Name:              cred.Name,
+		AttestationFormat: cred.AttestationFormat,
+		CreatedAt:         cred.CreatedAt,
+		LastUsedAt:        cred.LastUsedAt,
+	}
+}
+
+func respondWebAuthnError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrWebAuthnFailed), errors.Is(err, domain.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrCredentialNotFound), errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrFeatureDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrAccountSuspended), errors.Is(err, domain.ErrApprovalPending):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,26 @@
+package job
+
+import (
+	"log"
+	"time"
+
+	"user-jwt/internal/usecase"
+)
+
+// RunErasure 猶予期間を過ぎたアカウントを定期的に消去する（goroutineで起動する）
+// 消去は冪等なので複数のインスタンスで同時に動いても問題ない
+func RunErasure(accountUsecase usecase.AccountUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		erased, err := accountUsecase.EraseDue(time.Now())
+		if err != nil {
+			log.Printf("account erasure failed: %v", err)
+		}
+		if erased > 0 {
+			log.Printf("Erased %d accounts past their deletion grace period.", erased)
+		}
+		<-ticker.C
+	}
+}
@@ -2,16 +2,30 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"user-jwt/internal/domain"
 	"user-jwt/pkg/config"
 	"user-jwt/pkg/utils"
 )
 
+// UserStatusLookup トークンの公開IDからユーザーの内部IDと状態を引く（キャッシュ経由）
+type UserStatusLookup interface {
+	Get(publicID string) (*domain.UserStatus, error)
+}
+
+// RevocationChecker ユーザー単位で失効させたトークンの基準時刻を引く
+type RevocationChecker interface {
+	RevokedBefore(publicID string) (time.Time, error)
+}
+
 // AuthMiddleware JWTトークンを検証するミドルウェア
-func AuthMiddleware() gin.HandlerFunc {
+// トークンには公開IDのみが含まれるため、内部IDに変換してコンテキストに保存する
+func AuthMiddleware(statuses UserStatusLookup, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Authorizationヘッダーからトークンを取得
 		authHeader := c.GetHeader("Authorization")
@@ -45,11 +59,86 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// メールアドレス変更などでユーザーのトークンがまとめて失効されていないか
+		revokedBefore, err := revocations.RevokedBefore(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+			c.Abort()
+			return
+		}
+		if claims.IssuedBefore(revokedBefore) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		status, err := statuses.Get(claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+			c.Abort()
+			return
+		}
+		if status.UserID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+		// 利用停止されたユーザーの発行済みトークンも即座に拒否する
+		if status.Suspended(time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": domain.ErrAccountSuspended.Error()})
+			c.Abort()
+			return
+		}
+
 		// 検証成功後、コンテキストにユーザー情報を保存
-		c.Set("userID", claims.UserID)
+		c.Set("userID", status.UserID)
+		c.Set("publicID", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 
 		// 次の処理に進む
 		c.Next()
 	}
 }
+
+// RequireRole 指定したロールを持つユーザーのみ許可するミドルウェア（AuthMiddlewareの後に使用）
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRecentAuth 認証から maxAge 以内かつ acr 以上の強度で認証済みの場合のみ許可するミドルウェア
+// （AuthMiddlewareの後に使用）条件を満たさない場合は /auth/step-up での再認証を促す
+func RequireRecentAuth(maxAge time.Duration, acr string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		claims, ok := value.(*utils.Claims)
+		if ok && claims.AuthTime != nil &&
+			time.Since(claims.AuthTime.Time) <= maxAge &&
+			utils.ACRLevel(claims.ACR) >= utils.ACRLevel(acr) {
+			c.Next()
+			return
+		}
+
+		maxAgeSeconds := int(maxAge.Seconds())
+		challenge := fmt.Sprintf(`Bearer error="insufficient_user_authentication", max_age=%d`, maxAgeSeconds)
+		if acr != "" {
+			challenge += fmt.Sprintf(`, acr_values="%s"`, acr)
+		}
+		c.Header("WWW-Authenticate", challenge)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "reauthentication_required",
+			"message": "Recent authentication is required for this operation",
+			"max_age": maxAgeSeconds,
+			"acr":     acr,
+		})
+		c.Abort()
+	}
+}
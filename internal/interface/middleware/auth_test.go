@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMain(m *testing.M) {
+	if err := utils.SetJWTKey([]byte(strings.Repeat("k", 32))); err != nil {
+		panic(err)
+	}
+	// 個別に失効させたトークンの確認は接続できなければ読み飛ばされる
+	config.RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 10 * time.Millisecond})
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// fakeStatuses 公開IDごとのユーザーの状態（未登録は存在しないユーザー）
+type fakeStatuses map[string]domain.UserStatus
+
+func (s fakeStatuses) Get(publicID string) (*domain.UserStatus, error) {
+	status := s[publicID]
+	return &status, nil
+}
+
+type fakeRevocations map[string]time.Time
+
+func (r fakeRevocations) RevokedBefore(publicID string) (time.Time, error) {
+	return r[publicID], nil
+}
+
+// newAuthTestRouter 認証後にコンテキストの内部IDと公開IDを返すルーター
+func newAuthTestRouter(statuses fakeStatuses, revocations fakeRevocations) *gin.Engine {
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(statuses, revocations), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("userID"), "public_id": c.GetString("publicID")})
+	})
+	return r
+}
+
+func requestWithToken(t *testing.T, r http.Handler, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := utils.GenerateJWT(utils.TokenSubject{UserID: userID, Email: "alice@example.com"}, utils.NewAuthContext(utils.AMRPassword))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// トークンには公開IDだけを含め、内部IDへの変換はサーバー側で行う
+func TestAuthMiddlewarePublicID(t *testing.T) {
+	const publicID = "0190a6f2-7b3c-7d4e-8f00-000000000001"
+	r := newAuthTestRouter(fakeStatuses{publicID: {UserID: 42}}, fakeRevocations{})
+
+	w := requestWithToken(t, r, publicID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		UserID   uint   `json:"user_id"`
+		PublicID string `json:"public_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.UserID != 42 || body.PublicID != publicID {
+		t.Fatalf("context = %+v", body)
+	}
+
+	// 連番のIDや存在しない公開IDのトークンは受け付けない
+	for _, userID := range []string{"42", "0190a6f2-7b3c-7d4e-8f00-00000000ffff"} {
+		if w := requestWithToken(t, r, userID); w.Code != http.StatusUnauthorized {
+			t.Fatalf("user_id %q: status = %d, want 401", userID, w.Code)
+		}
+	}
+}
+
+// 利用停止されたユーザーの発行済みトークンは期限内でも拒否する
+func TestAuthMiddlewareSuspended(t *testing.T) {
+	const publicID = "0190a6f2-7b3c-7d4e-8f00-000000000001"
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		status     domain.UserStatus
+		wantStatus int
+	}{
+		{name: "active", status: domain.UserStatus{UserID: 1}, wantStatus: http.StatusOK},
+		{name: "suspended", status: domain.UserStatus{UserID: 1, DisabledAt: &past}, wantStatus: http.StatusForbidden},
+		{name: "suspended until later", status: domain.UserStatus{UserID: 1, DisabledAt: &past, DisabledUntil: &future}, wantStatus: http.StatusForbidden},
+		{name: "suspension expired", status: domain.UserStatus{UserID: 1, DisabledAt: &past, DisabledUntil: &past}, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAuthTestRouter(fakeStatuses{publicID: tt.status}, fakeRevocations{})
+			if w := requestWithToken(t, r, publicID); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	const maxAge = 5 * time.Minute
+
+	authAt := func(ago time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(time.Now().Add(-ago)) }
+	tests := []struct {
+		name     string
+		claims   *utils.Claims
+		acr      string
+		wantPass bool
+	}{
+		{name: "recent", claims: &utils.Claims{AuthTime: authAt(time.Minute), ACR: utils.ACRSingleFactor}, wantPass: true},
+		{name: "too old", claims: &utils.Claims{AuthTime: authAt(maxAge + time.Minute), ACR: utils.ACRSingleFactor}},
+		{name: "no auth_time", claims: &utils.Claims{ACR: utils.ACRMultiFactor}},
+		{name: "no claims"},
+		{name: "single factor where multi-factor is required", claims: &utils.Claims{AuthTime: authAt(time.Minute), ACR: utils.ACRSingleFactor}, acr: utils.ACRMultiFactor},
+		{name: "multi-factor", claims: &utils.Claims{AuthTime: authAt(time.Minute), ACR: utils.ACRMultiFactor}, acr: utils.ACRMultiFactor, wantPass: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.claims != nil {
+					c.Set("claims", tt.claims)
+				}
+				c.Next()
+			})
+			r.POST("/sensitive", RequireRecentAuth(maxAge, tt.acr), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sensitive", nil))
+			if tt.wantPass {
+				if w.Code != http.StatusNoContent {
+					t.Fatalf("status = %d, want 204", w.Code)
+				}
+				return
+			}
+
+			// クライアントが再認証して続行できるよう、必要な条件を返す
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", w.Code)
+			}
+			challenge := w.Header().Get("WWW-Authenticate")
+			if !strings.Contains(challenge, `error="insufficient_user_authentication"`) || !strings.Contains(challenge, "max_age=300") {
+				t.Fatalf("WWW-Authenticate = %q", challenge)
+			}
+			if tt.acr != "" && !strings.Contains(challenge, `acr_values="`+tt.acr+`"`) {
+				t.Fatalf("WWW-Authenticate = %q, want acr_values", challenge)
+			}
+			var body struct {
+				Error  string `json:"error"`
+				MaxAge int    `json:"max_age"`
+				ACR    string `json:"acr"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error != "reauthentication_required" || body.MaxAge != 300 || body.ACR != tt.acr {
+				t.Fatalf("body = %+v", body)
+			}
+		})
+	}
+}
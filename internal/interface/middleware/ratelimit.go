@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"user-jwt/pkg/ratelimit"
+	"user-jwt/pkg/utils"
+)
+
+// KeyFunc レート制限のキーをリクエストから取り出す（空文字の場合は制限しない）
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP クライアントIPをキーにする
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByEmail JSONボディのemailをキーにする（ボディは後続のハンドラーで再度読めるように戻す）
+func KeyByEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Email == "" {
+		return ""
+	}
+	return "email:" + strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// KeyByIdentifier JSONボディのidentifier（なければemail）をキーにする
+func KeyByIdentifier(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Identifier string `json:"identifier"`
+		Email      string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	identifier := payload.Identifier
+	if identifier == "" {
+		identifier = payload.Email
+	}
+	if identifier == "" {
+		return ""
+	}
+	return "identifier:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// KeyByUserID 認証済みユーザーIDをキーにする
+// AuthMiddlewareを通らないルートではBearerトークンから取り出す
+func KeyByUserID(c *gin.Context) string {
+	if publicID := c.GetString("publicID"); publicID != "" {
+		return "user:" + publicID
+	}
+
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if tokenString == "" {
+		return ""
+	}
+	claims, err := utils.VerifyJWT(tokenString)
+	if err != nil {
+		return ""
+	}
+	return "user:" + claims.UserID
+}
+
+// RateLimit レート制限ミドルウェア
+// name はルートごとのキーの名前空間として使う
+func RateLimit(limiter ratelimit.Limiter, name string, rule ratelimit.Rule, keyFunc KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			return
+		}
+
+		res, err := limiter.Allow(name+":"+key, rule)
+		if err != nil {
+			// 判定できない場合はリクエストを通す
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			c.Abort()
+			return
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"log"
+
+	"user-jwt/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *auditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Record(event domain.AuditEvent) error {
+	// アラート用にログにも出力
+	log.Printf("[audit] type=%s user_id=%v email=%q ip=%q detail=%s",
+		event.Type, derefUint(event.UserID), event.Email, event.IP, event.Detail)
+	return r.db.Create(&event).Error
+}
+
+func derefUint(v *uint) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func (r *auditRepository) ListByUser(userID uint) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&events).Error
+	return events, err
+}
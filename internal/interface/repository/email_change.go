@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type emailChangeRepository struct {
+	client *redis.Client
+}
+
+func NewEmailChangeRepository(client *redis.Client) *emailChangeRepository {
+	return &emailChangeRepository{client: client}
+}
+
+func emailChangeKey(userID uint) string {
+	return "email_change:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func emailChangeConfirmKey(tokenHash string) string {
+	return "email_change:confirm:" + tokenHash
+}
+
+func emailChangeRevertKey(tokenHash string) string {
+	return "email_change:revert:" + tokenHash
+}
+
+func (r *emailChangeRepository) Save(change domain.EmailChange, confirmTTL, revertTTL time.Duration) error {
+	ctx := context.Background()
+	if err := r.DeletePending(change.UserID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, emailChangeKey(change.UserID), data, confirmTTL)
+	pipe.Set(ctx, emailChangeConfirmKey(change.ConfirmHash), change.UserID, confirmTTL)
+	pipe.Set(ctx, emailChangeRevertKey(change.RevertHash), data, revertTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *emailChangeRepository) FindByConfirmToken(tokenHash string) (*domain.EmailChange, error) {
+	ctx := context.Background()
+	userID, err := r.client.Get(ctx, emailChangeConfirmKey(tokenHash)).Uint64()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := r.get(ctx, emailChangeKey(uint(userID)))
+	if err != nil || change == nil {
+		return nil, err
+	}
+	// 新しい変更要求で置き換えられたトークンは使えない
+	if change.ConfirmHash != tokenHash {
+		return nil, nil
+	}
+	return change, nil
+}
+
+func (r *emailChangeRepository) FindByRevertToken(tokenHash string) (*domain.EmailChange, error) {
+	return r.get(context.Background(), emailChangeRevertKey(tokenHash))
+}
+
+func (r *emailChangeRepository) DeletePending(userID uint) error {
+	ctx := context.Background()
+	existing, err := r.get(ctx, emailChangeKey(userID))
+	if err != nil {
+		return err
+	}
+	keys := []string{emailChangeKey(userID)}
+	if existing != nil {
+		keys = append(keys, emailChangeConfirmKey(existing.ConfirmHash))
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *emailChangeRepository) DeleteRevert(tokenHash string) error {
+	return r.client.Del(context.Background(), emailChangeRevertKey(tokenHash)).Err()
+}
+
+func (r *emailChangeRepository) get(ctx context.Context, key string) (*domain.EmailChange, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var change domain.EmailChange
+	if err := json.Unmarshal(data, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
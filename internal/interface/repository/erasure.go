@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"fmt"
+
+	"user-jwt/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type erasureRepository struct {
+	db *gorm.DB
+}
+
+func NewErasureRepository(db *gorm.DB) *erasureRepository {
+	return &erasureRepository{db: db}
+}
+
+// EraseUser ユーザーを参照するすべてのテーブルから1トランザクションで消去する
+// ユーザーを参照するテーブルを追加した場合はここにも追加すること
+func (r *erasureRepository) EraseUser(userID uint, tombstone domain.AuditEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user domain.User
+		if err := tx.Unscoped().First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		// 所属する組織の管理者の行をロックし、他の管理者の脱退と同時に管理者がいなくなるのを防ぐ
+		var orgIDs []uint
+		if err := tx.Model(&domain.Membership{}).Where("user_id = ?", userID).
+			Pluck("organization_id", &orgIDs).Error; err != nil {
+			return err
+		}
+		if len(orgIDs) > 0 {
+			var admins []uint
+			if err := tx.Model(&domain.Membership{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("organization_id IN ? AND role = ?", orgIDs, domain.OrgRoleAdmin).
+				Pluck("user_id", &admins).Error; err != nil {
+				return err
+			}
+			var blocked int64
+			if err := tx.Model(&domain.Organization{}).Where("id IN (?)", soleAdminOrgIDs(tx, userID)).
+				Count(&blocked).Error; err != nil {
+				return err
+			}
+			if blocked > 0 {
+				return domain.ErrOrgAdminHandoverRequired
+			}
+		}
+
+		for _, model := range []interface{}{
+			&domain.TOTPCredential{},
+			&domain.RecoveryCode{},
+			&domain.WebAuthnCredential{},
+			&domain.Identity{},
+			&domain.Membership{},
+			&domain.AuditEvent{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// 管理者として行った操作の記録は残し、本人との紐づけだけを外す
+		if err := tx.Model(&domain.AuditEvent{}).Where("actor_id = ?", userID).
+			UpdateColumn("actor_id", nil).Error; err != nil {
+			return err
+		}
+
+		// アカウントに紐づかない記録（登録の拒否・招待など）はメールアドレスで探し、個人を特定できる項目を消す
+		// 記録のメールアドレスは入力されたままなので、小文字にしたものと正規化したものの両方で照合する
+		if err := tx.Model(&domain.AuditEvent{}).
+			Where("user_id IS NULL AND lower(email) IN (lower(?), ?)", user.Email, user.EmailNormalized).
+			Updates(map[string]interface{}{"email": "", "ip": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+
+		// 本人宛ての組織への招待はメールアドレスを含むため削除し、招待した・受諾した記録は紐づけだけを外す
+		if err := tx.Where("email_normalized = ?", user.EmailNormalized).
+			Delete(&domain.OrgInvitation{}).Error; err != nil {
+			return err
+		}
+		for _, column := range []string{"invited_by", "accepted_by"} {
+			if err := tx.Model(&domain.OrgInvitation{}).Where(column+" = ?", userID).
+				UpdateColumn(column, nil).Error; err != nil {
+				return err
+			}
+		}
+
+		// 本人だけが所属していた組織は管理する人がいなくなるため招待とともに削除する
+		if len(orgIDs) > 0 {
+			empty := "NOT EXISTS (SELECT 1 FROM memberships WHERE memberships.organization_id = %s)"
+			if err := tx.Where("organization_id IN ?", orgIDs).Where(fmt.Sprintf(empty, "org_invitations.organization_id")).
+				Delete(&domain.OrgInvitation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", orgIDs).Where(fmt.Sprintf(empty, "organizations.id")).
+				Delete(&domain.Organization{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// 論理削除ではなく行ごと削除する
+		if err := tx.Unscoped().Delete(&domain.User{}, userID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&tombstone).Error
+	})
+}
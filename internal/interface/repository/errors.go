@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+
+	"user-jwt/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// 一意制約違反（unique_violation）のエラーコード
+const pgUniqueViolation = "23505"
+
+// 一意制約の名前と重複した項目の対応
+var conflictFields = map[string]string{
+	"idx_users_email_normalized": domain.ConflictFieldEmail,
+	"idx_users_public_id":        "public_id",
+	"idx_identities_type_key":    domain.ConflictFieldIdentifier,
+	"idx_identities_user_type":   domain.ConflictFieldIdentifier,
+	"idx_memberships_org_user":   domain.ConflictFieldMembership,
+}
+
+// translateError Postgresの一意制約違反を domain.ConflictError に変換する（それ以外はそのまま返す）
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgUniqueViolation {
+		return err
+	}
+	field, ok := conflictFields[pgErr.ConstraintName]
+	if !ok {
+		field = pgErr.ConstraintName
+	}
+	return &domain.ConflictError{Field: field}
+}
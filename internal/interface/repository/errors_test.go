@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"user-jwt/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestTranslateError(t *testing.T) {
+	dbErr := errors.New("connection reset")
+	tests := []struct {
+		name      string
+		err       error
+		wantField string  // 空なら ConflictError に変換しない
+		wantIs    []error // errors.Is で一致するエラー
+		wantIsNot []error
+	}{
+		{
+			name:      "email",
+			err:       &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email_normalized"},
+			wantField: domain.ConflictFieldEmail,
+			wantIs:    []error{domain.ErrConflict, domain.ErrEmailAlreadyExists},
+			wantIsNot: []error{domain.ErrIdentifierTaken, domain.ErrAlreadyMember},
+		},
+		{
+			name:      "identifier",
+			err:       &pgconn.PgError{Code: "23505", ConstraintName: "idx_identities_type_key"},
+			wantField: domain.ConflictFieldIdentifier,
+			wantIs:    []error{domain.ErrConflict, domain.ErrIdentifierTaken},
+			wantIsNot: []error{domain.ErrEmailAlreadyExists},
+		},
+		{
+			name:      "identifier type per user",
+			err:       &pgconn.PgError{Code: "23505", ConstraintName: "idx_identities_user_type"},
+			wantField: domain.ConflictFieldIdentifier,
+			wantIs:    []error{domain.ErrIdentifierTaken},
+		},
+		{
+			name:      "membership",
+			err:       &pgconn.PgError{Code: "23505", ConstraintName: "idx_memberships_org_user"},
+			wantField: domain.ConflictFieldMembership,
+			wantIs:    []error{domain.ErrConflict, domain.ErrAlreadyMember},
+			wantIsNot: []error{domain.ErrEmailAlreadyExists},
+		},
+		{
+			name:      "wrapped",
+			err:       fmt.Errorf("insert user: %w", &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email_normalized"}),
+			wantField: domain.ConflictFieldEmail,
+			wantIs:    []error{domain.ErrEmailAlreadyExists},
+		},
+		{
+			name:      "unknown constraint",
+			err:       &pgconn.PgError{Code: "23505", ConstraintName: "idx_sessions_token"},
+			wantField: "idx_sessions_token",
+			wantIs:    []error{domain.ErrConflict},
+			wantIsNot: []error{domain.ErrEmailAlreadyExists, domain.ErrIdentifierTaken},
+		},
+		{
+			name:      "other postgres error",
+			err:       &pgconn.PgError{Code: "23503", ConstraintName: "fk_memberships_user"},
+			wantIsNot: []error{domain.ErrConflict},
+		},
+		{
+			name:      "not a postgres error",
+			err:       dbErr,
+			wantIs:    []error{dbErr},
+			wantIsNot: []error{domain.ErrConflict},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := translateError(tt.err)
+
+			var conflict *domain.ConflictError
+			if tt.wantField == "" {
+				if errors.As(got, &conflict) || got != tt.err {
+					t.Fatalf("translateError = %v, want the original error", got)
+				}
+			} else if !errors.As(got, &conflict) || conflict.Field != tt.wantField {
+				t.Fatalf("translateError = %#v, want ConflictError{%q}", got, tt.wantField)
+			}
+			for _, target := range tt.wantIs {
+				if !errors.Is(got, target) {
+					t.Errorf("errors.Is(%v, %v) = false", got, target)
+				}
+			}
+			for _, target := range tt.wantIsNot {
+				if errors.Is(got, target) {
+					t.Errorf("errors.Is(%v, %v) = true", got, target)
+				}
+			}
+		})
+	}
+}
+
+func TestTranslateErrorNil(t *testing.T) {
+	if err := translateError(nil); err != nil {
+		t.Fatalf("translateError(nil) = %v", err)
+	}
+}
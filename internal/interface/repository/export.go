@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type dataExportRepository struct {
+	client *redis.Client
+}
+
+func NewDataExportRepository(client *redis.Client) *dataExportRepository {
+	return &dataExportRepository{client: client}
+}
+
+func exportJobKey(id string) string {
+	return "export:job:" + id
+}
+
+func exportArchiveKey(id string) string {
+	return "export:archive:" + id
+}
+
+// exportUserKey ユーザーのジョブIDの集合（アカウント消去時にアーカイブを探すため）
+func exportUserKey(userID uint) string {
+	return "export:user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func (r *dataExportRepository) SaveJob(job domain.DataExport, ttl time.Duration) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	userKey := exportUserKey(job.UserID)
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, exportJobKey(job.ID), data, ttl)
+	pipe.SAdd(ctx, userKey, job.ID)
+	// 集合は最も長く残るジョブに合わせて期限を延ばす
+	pipe.ExpireNX(ctx, userKey, ttl)
+	pipe.ExpireGT(ctx, userKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *dataExportRepository) FindJob(id string) (*domain.DataExport, error) {
+	data, err := r.client.Get(context.Background(), exportJobKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job domain.DataExport
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *dataExportRepository) SaveArchive(id string, data []byte, ttl time.Duration) error {
+	return r.client.Set(context.Background(), exportArchiveKey(id), data, ttl).Err()
+}
+
+func (r *dataExportRepository) FindArchive(id string) ([]byte, error) {
+	data, err := r.client.Get(context.Background(), exportArchiveKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (r *dataExportRepository) DeleteByUser(userID uint) error {
+	ctx := context.Background()
+	userKey := exportUserKey(userID)
+	ids, err := r.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{userKey}
+	for _, id := range ids {
+		keys = append(keys, exportJobKey(id), exportArchiveKey(id))
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
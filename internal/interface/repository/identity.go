@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) *identityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) ListByUser(userID uint) ([]domain.Identity, error) {
+	var identities []domain.Identity
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+func (r *identityRepository) Find(userID uint, identityType string) (*domain.Identity, error) {
+	var identity domain.Identity
+	if err := r.db.Where("user_id = ? AND type = ?", userID, identityType).First(&identity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) FindVerified(identityType, key string) (*domain.Identity, error) {
+	var identity domain.Identity
+	if err := r.db.Where("type = ? AND key = ? AND verified_at IS NOT NULL", identityType, key).
+		First(&identity).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) Save(identity domain.Identity) (domain.Identity, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND type = ?", identity.UserID, identity.Type).
+			Delete(&domain.Identity{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		return domain.Identity{}, translateError(err)
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) MarkVerified(id uint, codeHash string, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.Identity{}).
+		Where("id = ? AND code_hash = ? AND code_expires_at > ?", id, codeHash, at).
+		Updates(map[string]interface{}{
+			"verified_at":     at,
+			"code_hash":       "",
+			"code_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *identityRepository) Delete(userID uint, identityType string) (bool, error) {
+	result := r.db.Where("user_id = ? AND type = ?", userID, identityType).Delete(&domain.Identity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type invitationRepository struct {
+	client *redis.Client
+}
+
+func NewInvitationRepository(client *redis.Client) *invitationRepository {
+	return &invitationRepository{client: client}
+}
+
+func invitationKey(tokenHash string) string {
+	return "invitation:" + tokenHash
+}
+
+// invitationEmailKey メールアドレスを指定した招待のトークンハッシュの集合（アカウント消去時に削除するため）
+func invitationEmailKey(email string) string {
+	return "invitation:email:" + utils.NormalizeEmail(email)
+}
+
+func (r *invitationRepository) Save(tokenHash string, invitation domain.Invitation) error {
+	ttl := time.Until(invitation.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(invitation)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if invitation.Email == "" {
+		return r.client.Set(ctx, invitationKey(tokenHash), data, ttl).Err()
+	}
+	emailKey := invitationEmailKey(invitation.Email)
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, invitationKey(tokenHash), data, ttl)
+	pipe.SAdd(ctx, emailKey, tokenHash)
+	// 集合は最も長く残る招待に合わせて期限を延ばす
+	pipe.ExpireNX(ctx, emailKey, ttl)
+	pipe.ExpireGT(ctx, emailKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *invitationRepository) Take(tokenHash string) (*domain.Invitation, error) {
+	ctx := context.Background()
+	// GETDEL で取り出すので同じ招待を同時に使われても1件しか成功しない
+	data, err := r.client.GetDel(ctx, invitationKey(tokenHash)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var invitation domain.Invitation
+	if err := json.Unmarshal(data, &invitation); err != nil {
+		return nil, err
+	}
+	if invitation.Email != "" {
+		if err := r.client.SRem(ctx, invitationEmailKey(invitation.Email), tokenHash).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) DeleteByEmail(email string) error {
+	ctx := context.Background()
+	emailKey := invitationEmailKey(email)
+	tokenHashes, err := r.client.SMembers(ctx, emailKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := []string{emailKey}
+	for _, tokenHash := range tokenHashes {
+		keys = append(keys, invitationKey(tokenHash))
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
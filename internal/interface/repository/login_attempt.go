@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// loginAttemptRepository namespace ごとに独立した失敗回数・ロック
+// サインインの識別子は利用者の入力そのものなので、MFA など内部のキーとは namespace を分けて衝突させない
+type loginAttemptRepository struct {
+	client    *redis.Client
+	namespace string
+}
+
+func NewLoginAttemptRepository(client *redis.Client, namespace string) *loginAttemptRepository {
+	return &loginAttemptRepository{client: client, namespace: namespace}
+}
+
+func (r *loginAttemptRepository) loginAttemptKey(kind, email string) string {
+	return r.namespace + ":" + kind + ":" + strings.ToLower(email)
+}
+
+func (r *loginAttemptRepository) RecordFailure(email string, window time.Duration) (int, error) {
+	ctx := context.Background()
+	key := r.loginAttemptKey("fail", email)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+func (r *loginAttemptRepository) Reset(email string) error {
+	return r.client.Del(context.Background(),
+		r.loginAttemptKey("fail", email),
+		r.loginAttemptKey("delay", email),
+		r.loginAttemptKey("lock", email),
+	).Err()
+}
+
+func (r *loginAttemptRepository) Delay(email string, duration time.Duration) error {
+	return r.client.Set(context.Background(), r.loginAttemptKey("delay", email), "1", duration).Err()
+}
+
+func (r *loginAttemptRepository) Lock(email string, duration time.Duration) error {
+	return r.client.Set(context.Background(), r.loginAttemptKey("lock", email), "1", duration).Err()
+}
+
+func (r *loginAttemptRepository) BlockedFor(email string) (time.Duration, bool, error) {
+	ctx := context.Background()
+
+	lockTTL, err := r.client.PTTL(ctx, r.loginAttemptKey("lock", email)).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	if lockTTL > 0 {
+		return lockTTL, true, nil
+	}
+
+	delayTTL, err := r.client.PTTL(ctx, r.loginAttemptKey("delay", email)).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	if delayTTL > 0 {
+		return delayTTL, false, nil
+	}
+	return 0, false, nil
+}
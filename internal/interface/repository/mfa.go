@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *mfaRepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) FindTOTP(userID uint) (*domain.TOTPCredential, error) {
+	var cred domain.TOTPCredential
+	if err := r.db.Where("user_id = ?", userID).First(&cred).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (r *mfaRepository) SaveTOTP(cred domain.TOTPCredential) error {
+	return r.db.Save(&cred).Error
+}
+
+func (r *mfaRepository) UpdateTOTPStep(userID uint, lastStep, step int64) (bool, error) {
+	result := r.db.Model(&domain.TOTPCredential{}).
+		Where("user_id = ? AND last_step = ?", userID, lastStep).
+		Update("last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *mfaRepository) DeleteTOTP(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.TOTPCredential{}).Error
+	})
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
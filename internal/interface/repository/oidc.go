@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type oidcLoginRepository struct {
+	client *redis.Client
+}
+
+func NewOIDCLoginRepository(client *redis.Client) *oidcLoginRepository {
+	return &oidcLoginRepository{client: client}
+}
+
+func oidcLoginKey(stateHash string) string {
+	return "oidc:state:" + stateHash
+}
+
+func (r *oidcLoginRepository) Save(stateHash string, login domain.OIDCLogin, ttl time.Duration) error {
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), oidcLoginKey(stateHash), data, ttl).Err()
+}
+
+func (r *oidcLoginRepository) Take(stateHash string) (*domain.OIDCLogin, error) {
+	// GETDEL で取り出すので同じ callback を繰り返し使えない
+	data, err := r.client.GetDel(context.Background(), oidcLoginKey(stateHash)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var login domain.OIDCLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) *organizationRepository {
+	return &organizationRepository{db: db}
+}
+
+func (r *organizationRepository) Create(org domain.Organization, adminUserID uint) (domain.Organization, error) {
+	if org.PublicID == "" {
+		publicID, err := utils.NewUUIDv7()
+		if err != nil {
+			return domain.Organization{}, err
+		}
+		org.PublicID = publicID
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&domain.Membership{OrganizationID: org.ID, UserID: adminUserID, Role: domain.OrgRoleAdmin}).Error
+	})
+	if err != nil {
+		return domain.Organization{}, translateError(err)
+	}
+	return org, nil
+}
+
+func (r *organizationRepository) FindByID(id uint) (*domain.Organization, error) {
+	var org domain.Organization
+	if err := r.db.First(&org, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) FindByPublicID(publicID string) (*domain.Organization, error) {
+	var org domain.Organization
+	if err := r.db.Where("public_id = ?", publicID).First(&org).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) ListByUser(userID uint) ([]domain.UserOrganization, error) {
+	var rows []struct {
+		domain.Organization
+		Role     string
+		JoinedAt time.Time
+	}
+	if err := r.db.Model(&domain.Organization{}).
+		Select("organizations.*, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN memberships ON memberships.organization_id = organizations.id").
+		Where("memberships.user_id = ?", userID).
+		Order("organizations.id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	orgs := make([]domain.UserOrganization, 0, len(rows))
+	for _, row := range rows {
+		orgs = append(orgs, domain.UserOrganization{Organization: row.Organization, Role: row.Role, JoinedAt: row.JoinedAt})
+	}
+	return orgs, nil
+}
+
+func (r *organizationRepository) FindMembership(orgID, userID uint) (*domain.Membership, error) {
+	var membership domain.Membership
+	if err := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&membership).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *organizationRepository) ListMembers(orgID uint) ([]domain.OrgMember, error) {
+	var members []domain.OrgMember
+	err := r.db.Model(&domain.Membership{}).
+		Select("users.public_id AS user_public_id, users.email, users.display_name, memberships.role, memberships.created_at AS joined_at").
+		Joins("JOIN users ON users.id = memberships.user_id AND users.deleted_at IS NULL").
+		Where("memberships.organization_id = ?", orgID).
+		Order("memberships.id").Scan(&members).Error
+	return members, err
+}
+
+func (r *organizationRepository) RemoveMember(orgID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 管理者の行をロックし、同時に複数の管理者が外されて管理者がいなくなるのを防ぐ
+		var admins []uint
+		if err := tx.Model(&domain.Membership{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("organization_id = ? AND role = ?", orgID, domain.OrgRoleAdmin).
+			Pluck("user_id", &admins).Error; err != nil {
+			return err
+		}
+		if len(admins) == 1 && admins[0] == userID {
+			return domain.ErrLastOrgAdmin
+		}
+
+		result := tx.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&domain.Membership{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrMemberNotFound
+		}
+		return nil
+	})
+}
+
+func (r *organizationRepository) ListSoleAdminOrgs(userID uint) ([]domain.Organization, error) {
+	var orgs []domain.Organization
+	err := r.db.Where("id IN (?)", soleAdminOrgIDs(r.db, userID)).Order("id").Find(&orgs).Error
+	return orgs, err
+}
+
+// soleAdminOrgIDs ユーザーが唯一の管理者で、他にもメンバーがいる組織のIDを返すサブクエリ
+// 管理者を引き継がずにユーザーがいなくなると、残ったメンバーが組織を管理できなくなる
+func soleAdminOrgIDs(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&domain.Membership{}).Select("memberships.organization_id").
+		Where("memberships.user_id = ? AND memberships.role = ?", userID, domain.OrgRoleAdmin).
+		Where("NOT EXISTS (SELECT 1 FROM memberships a WHERE a.organization_id = memberships.organization_id AND a.role = ? AND a.user_id <> ?)", domain.OrgRoleAdmin, userID).
+		Where("EXISTS (SELECT 1 FROM memberships o WHERE o.organization_id = memberships.organization_id AND o.user_id <> ?)", userID)
+}
+
+func (r *userRepository) OrgRoles(userID uint) (map[string]string, error) {
+	var rows []struct {
+		PublicID string
+		Role     string
+	}
+	if err := r.db.Model(&domain.Membership{}).
+		Select("organizations.public_id, memberships.role").
+		Joins("JOIN organizations ON organizations.id = memberships.organization_id").
+		Where("memberships.user_id = ?", userID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	roles := make(map[string]string, len(rows))
+	for _, row := range rows {
+		roles[row.PublicID] = row.Role
+	}
+	return roles, nil
+}
+
+type orgInvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrgInvitationRepository(db *gorm.DB) *orgInvitationRepository {
+	return &orgInvitationRepository{db: db}
+}
+
+// pendingInvitation 受諾・取り消しされておらず期限内の招待の条件
+func pendingInvitation(tx *gorm.DB, now time.Time) *gorm.DB {
+	return tx.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+}
+
+func (r *orgInvitationRepository) Create(invitation domain.OrgInvitation) (domain.OrgInvitation, error) {
+	if invitation.PublicID == "" {
+		publicID, err := utils.NewUUIDv7()
+		if err != nil {
+			return domain.OrgInvitation{}, err
+		}
+		invitation.PublicID = publicID
+	}
+	invitation.EmailNormalized = utils.NormalizeEmail(invitation.Email)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 同じ宛先への招待を送り直した場合は古いリンクを使えなくする
+		now := time.Now()
+		if err := pendingInvitation(tx.Model(&domain.OrgInvitation{}), now).
+			Where("organization_id = ? AND email_normalized = ?", invitation.OrganizationID, invitation.EmailNormalized).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&invitation).Error
+	})
+	if err != nil {
+		return domain.OrgInvitation{}, err
+	}
+	return invitation, nil
+}
+
+func (r *orgInvitationRepository) FindByPublicID(publicID string) (*domain.OrgInvitation, error) {
+	var invitation domain.OrgInvitation
+	if err := r.db.Where("public_id = ?", publicID).First(&invitation).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *orgInvitationRepository) ListPending(orgID uint, now time.Time) ([]domain.OrgInvitation, error) {
+	var invitations []domain.OrgInvitation
+	err := pendingInvitation(r.db, now).Where("organization_id = ?", orgID).
+		Order("id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *orgInvitationRepository) Revoke(orgID uint, publicID string, at time.Time) (bool, error) {
+	result := pendingInvitation(r.db.Model(&domain.OrgInvitation{}), at).
+		Where("organization_id = ? AND public_id = ?", orgID, publicID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *orgInvitationRepository) Accept(invitation domain.OrgInvitation, user domain.User, at time.Time) (domain.User, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if user.ID == 0 {
+			if err := prepareNewUser(&user); err != nil {
+				return err
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		// 同じ招待を同時に使われても1件しか成功しない
+		result := pendingInvitation(tx.Model(&domain.OrgInvitation{}), at).
+			Where("id = ?", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": at, "accepted_by": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInvalidInvitation
+		}
+
+		return tx.Create(&domain.Membership{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         user.ID,
+			Role:           invitation.Role,
+		}).Error
+	})
+	if err != nil {
+		return domain.User{}, translateError(err)
+	}
+	return user, nil
+}
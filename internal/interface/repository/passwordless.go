@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 保存中のチャレンジが検証したものと同じ場合のみ、チャレンジとマジックリンクのトークンを削除する
+// KEYS: 1=チャレンジ, 2=トークン（なければチャレンジと同じキー）, ARGV: 1=検証したチャレンジ
+var consumeChallengeScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'challenge') ~= ARGV[1] then
+  return 0
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+return 1
+`)
+
+// 試行回数を加算し、新しく作成した場合のみ有効期限を設定する（期限のないキーを残さない）
+// KEYS: 1=試行回数, ARGV: 1=有効期限(ms)
+var incrementAttemptsScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 or redis.call('PTTL', KEYS[1]) < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+type passwordlessRepository struct {
+	client *redis.Client
+}
+
+func NewPasswordlessRepository(client *redis.Client) *passwordlessRepository {
+	return &passwordlessRepository{client: client}
+}
+
+func passwordlessKey(email string) string {
+	return "passwordless:" + strings.ToLower(email)
+}
+
+func passwordlessTokenKey(tokenHash string) string {
+	return "passwordless:token:" + tokenHash
+}
+
+// チャレンジの再発行で失敗回数がリセットされないよう、チャレンジとは別のキーで数える
+func passwordlessAttemptsKey(email string) string {
+	return "passwordless:attempts:" + strings.ToLower(email)
+}
+
+func (r *passwordlessRepository) Save(challenge domain.PasswordlessChallenge, ttl time.Duration) error {
+	ctx := context.Background()
+	if err := r.Delete(challenge.Email); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	key := passwordlessKey(challenge.Email)
+	pipe.HSet(ctx, key, "challenge", data)
+	pipe.Expire(ctx, key, ttl)
+	if challenge.TokenHash != "" {
+		pipe.Set(ctx, passwordlessTokenKey(challenge.TokenHash), challenge.Email, ttl)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *passwordlessRepository) Find(email string) (*domain.PasswordlessChallenge, error) {
+	data, err := r.client.HGet(context.Background(), passwordlessKey(email), "challenge").Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var challenge domain.PasswordlessChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (r *passwordlessRepository) FindByToken(tokenHash string) (*domain.PasswordlessChallenge, error) {
+	email, err := r.client.Get(context.Background(), passwordlessTokenKey(tokenHash)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Find(email)
+}
+
+func (r *passwordlessRepository) Consume(challenge domain.PasswordlessChallenge) (bool, error) {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return false, err
+	}
+	key := passwordlessKey(challenge.Email)
+	tokenKey := key
+	if challenge.TokenHash != "" {
+		tokenKey = passwordlessTokenKey(challenge.TokenHash)
+	}
+	n, err := consumeChallengeScript.Run(context.Background(), r.client, []string{key, tokenKey}, data).Int()
+	return n == 1, err
+}
+
+func (r *passwordlessRepository) Delete(email string) error {
+	ctx := context.Background()
+	existing, err := r.Find(email)
+	if err != nil {
+		return err
+	}
+	keys := []string{passwordlessKey(email)}
+	if existing != nil && existing.TokenHash != "" {
+		keys = append(keys, passwordlessTokenKey(existing.TokenHash))
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+func (r *passwordlessRepository) IncrementAttempts(email string, window time.Duration) (int, error) {
+	n, err := incrementAttemptsScript.Run(context.Background(), r.client,
+		[]string{passwordlessAttemptsKey(email)}, window.Milliseconds()).Int()
+	return n, err
+}
+
+func (r *passwordlessRepository) ResetAttempts(email string) error {
+	return r.client.Del(context.Background(), passwordlessAttemptsKey(email)).Err()
+}
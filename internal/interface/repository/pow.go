@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type proofOfWorkRepository struct {
+	client *redis.Client
+}
+
+func NewProofOfWorkRepository(client *redis.Client) *proofOfWorkRepository {
+	return &proofOfWorkRepository{client: client}
+}
+
+func powKey(challenge string) string {
+	return "pow:" + challenge
+}
+
+func (r *proofOfWorkRepository) Save(challenge string, difficulty int, ttl time.Duration) error {
+	return r.client.Set(context.Background(), powKey(challenge), difficulty, ttl).Err()
+}
+
+func (r *proofOfWorkRepository) Take(challenge string) (int, error) {
+	// 取り出すと同時に削除し、同じ解答を使い回せないようにする
+	difficulty, err := r.client.GetDel(context.Background(), powKey(challenge)).Int()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return difficulty, err
+}
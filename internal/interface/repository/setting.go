@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type settingRepository struct {
+	client *redis.Client
+}
+
+func NewSettingRepository(client *redis.Client) *settingRepository {
+	return &settingRepository{client: client}
+}
+
+func (r *settingRepository) Get(name string, v interface{}) (bool, error) {
+	data, err := r.client.Get(context.Background(), "setting:"+name).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (r *settingRepository) Set(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), "setting:"+name, data, 0).Err()
+}
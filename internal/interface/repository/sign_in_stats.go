@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 集計バケットの幅
+const signInStatsBucket = time.Minute
+
+type signInStatsRepository struct {
+	client *redis.Client
+}
+
+func NewSignInStatsRepository(client *redis.Client) *signInStatsRepository {
+	return &signInStatsRepository{client: client}
+}
+
+func signInStatsKey(key string, bucket int64) string {
+	return "signin:stats:" + key + ":" + strconv.FormatInt(bucket, 10)
+}
+
+func (r *signInStatsRepository) Record(keys []string, failed bool, window time.Duration) error {
+	ctx := context.Background()
+	bucket := time.Now().Unix() / int64(signInStatsBucket.Seconds())
+
+	pipe := r.client.Pipeline()
+	for _, key := range keys {
+		k := signInStatsKey(key, bucket)
+		pipe.HIncrBy(ctx, k, "attempts", 1)
+		if failed {
+			pipe.HIncrBy(ctx, k, "failures", 1)
+		}
+		pipe.Expire(ctx, k, window+signInStatsBucket)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *signInStatsRepository) Counts(key string, window time.Duration) (int, int, error) {
+	ctx := context.Background()
+	bucket := time.Now().Unix() / int64(signInStatsBucket.Seconds())
+	buckets := int64(window / signInStatsBucket)
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, 0, buckets)
+	for i := int64(0); i < buckets; i++ {
+		cmds = append(cmds, pipe.HMGet(ctx, signInStatsKey(key, bucket-i), "attempts", "failures"))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+
+	attempts, failures := 0, 0
+	for _, cmd := range cmds {
+		vals := cmd.Val()
+		attempts += toInt(vals[0])
+		failures += toInt(vals[1])
+	}
+	return attempts, failures, nil
+}
+
+func (r *signInStatsRepository) MarkOnce(key string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(context.Background(), "signin:mark:"+key, "1", ttl).Result()
+}
+
+func toInt(v interface{}) int {
+	s, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
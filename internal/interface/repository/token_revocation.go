@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"user-jwt/pkg/utils"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type tokenRevocationRepository struct {
+	client *redis.Client
+}
+
+func NewTokenRevocationRepository(client *redis.Client) *tokenRevocationRepository {
+	return &tokenRevocationRepository{client: client}
+}
+
+func revokedBeforeKey(publicID string) string {
+	return "token:revoked_before:" + publicID
+}
+
+// 失効の記録はアクセストークンの有効期限が過ぎれば不要になる
+// iat と同じミリ秒単位で保存する（失効の直後に再発行したトークンは有効なまま）
+func (r *tokenRevocationRepository) RevokeUserTokens(publicID string, at time.Time) error {
+	return r.client.Set(context.Background(), revokedBeforeKey(publicID), at.UnixMilli(), utils.AccessTokenTTL).Err()
+}
+
+func (r *tokenRevocationRepository) RevokedBefore(publicID string) (time.Time, error) {
+	millis, err := r.client.Get(context.Background(), revokedBeforeKey(publicID)).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(millis), nil
+}
@@ -1,22 +1,27 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"user-jwt/internal/domain"
+	"user-jwt/pkg/utils"
 
 	"gorm.io/gorm"
 )
 
 type userRepository struct {
-	db *gorm.DB
+	db               *gorm.DB
+	deletedEmailHold time.Duration // 論理削除したユーザーのメールアドレスを再利用できるまでの期間
 }
 
-func NewUserRepository(db *gorm.DB) *userRepository {
-	return &userRepository{db: db}
+func NewUserRepository(db *gorm.DB, deletedEmailHold time.Duration) *userRepository {
+	return &userRepository{db: db, deletedEmailHold: deletedEmailHold}
 }
 
 func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.Where("email_normalized = ?", utils.NormalizeEmail(email)).First(&user).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -25,13 +30,38 @@ func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
 	return &user, nil
 }
 
+func (r *userRepository) EmailTaken(email string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&domain.User{}).
+		Where("email_normalized = ? AND (deleted_at IS NULL OR deleted_at > ?)",
+			utils.NormalizeEmail(email), time.Now().Add(-r.deletedEmailHold)).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *userRepository) Create(user domain.User) (domain.User, error) {
-	if err := r.db.Create(&user).Error; err != nil {
+	if err := prepareNewUser(&user); err != nil {
 		return domain.User{}, err
 	}
+	if err := r.db.Create(&user).Error; err != nil {
+		return domain.User{}, translateError(err)
+	}
 	return user, nil
 }
 
+// prepareNewUser 作成するユーザーに公開IDと正規化したメールアドレスを設定する
+func prepareNewUser(user *domain.User) error {
+	if user.PublicID == "" {
+		publicID, err := utils.NewUUIDv7()
+		if err != nil {
+			return err
+		}
+		user.PublicID = publicID
+	}
+	user.EmailNormalized = utils.NormalizeEmail(user.Email)
+	return nil
+}
+
 func (r *userRepository) FindByID(userID uint) (*domain.User, error) {
 	var user domain.User
 	if err := r.db.First(&user, userID).Error; err != nil {
@@ -42,3 +72,227 @@ func (r *userRepository) FindByID(userID uint) (*domain.User, error) {
 	}
 	return &user, nil
 }
+
+func (r *userRepository) UpdatePassword(userID uint, hashedPassword string) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", userID).Update("password", hashedPassword).Error
+}
+
+func (r *userRepository) MarkEmailVerified(userID uint, email string, at time.Time) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND email = ?", userID, email).
+		Updates(map[string]interface{}{
+			"email_verified_at": at,
+			"version":           gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) UpdateProfile(userID uint, version int, update domain.ProfileUpdate) (bool, error) {
+	fields := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	if update.DisplayName != nil {
+		fields["display_name"] = *update.DisplayName
+	}
+	if update.Locale != nil {
+		fields["locale"] = *update.Locale
+	}
+	if update.Timezone != nil {
+		fields["timezone"] = *update.Timezone
+	}
+	if update.AvatarURL != nil {
+		fields["avatar_url"] = *update.AvatarURL
+	}
+	if update.Visibility != nil {
+		fields["visibility"] = *update.Visibility
+	}
+
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND version = ?", userID, version).
+		Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) FindByPublicID(publicID string) (*domain.User, error) {
+	var user domain.User
+	if err := r.db.Where("public_id = ?", publicID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindIDByPublicID(publicID string) (uint, error) {
+	var ids []uint
+	if err := r.db.Model(&domain.User{}).Where("public_id = ?", publicID).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[0], nil
+}
+
+func (r *userRepository) UpdateEmail(userID uint, from, to string, verifiedAt time.Time) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND email = ?", userID, from).
+		Updates(map[string]interface{}{
+			"email":             to,
+			"email_normalized":  utils.NormalizeEmail(to),
+			"email_verified_at": verifiedAt,
+			"version":           gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) ScheduleDeletion(userID uint, dueAt time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"deletion_due_at": dueAt,
+			"version":         gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *userRepository) CancelDeletion(userID uint) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND deletion_due_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"deletion_due_at": nil,
+			"version":         gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) FindDueForDeletion(now time.Time, afterID uint, limit int) ([]domain.User, error) {
+	var users []domain.User
+	// 論理削除されたユーザーも消去の対象にする
+	err := r.db.Unscoped().Where("deletion_due_at <= ? AND id > ?", now, afterID).Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// メールアドレスの前方一致検索は lower(email) の text_pattern_ops インデックスを使う
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (r *userRepository) List(filter domain.UserFilter, limit int) ([]domain.User, error) {
+	query := r.db.Model(&domain.User{})
+	if filter.Deleted {
+		query = r.db.Unscoped().Model(&domain.User{}).Where("deleted_at IS NOT NULL")
+	}
+	if filter.EmailPrefix != "" {
+		query = query.Where("lower(email) LIKE ?", likeEscaper.Replace(strings.ToLower(filter.EmailPrefix))+"%")
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.Approval != "" {
+		query = query.Where("approval_status = ?", filter.Approval)
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			query = query.Where("email_verified_at IS NOT NULL")
+		} else {
+			query = query.Where("email_verified_at IS NULL")
+		}
+	}
+	if filter.Disabled != nil {
+		disabled := "deletion_due_at IS NOT NULL OR (disabled_at IS NOT NULL AND (disabled_until IS NULL OR disabled_until > ?))"
+		if *filter.Disabled {
+			query = query.Where(disabled, time.Now())
+		} else {
+			query = query.Where("NOT ("+disabled+")", time.Now())
+		}
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Before != nil {
+		query = query.Where("(created_at, public_id) < (?, ?)", filter.Before.CreatedAt, filter.Before.PublicID)
+	}
+
+	var users []domain.User
+	err := query.Order("created_at DESC, public_id DESC").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) Suspend(userID uint, at time.Time, reason string, until *time.Time) error {
+	return r.db.Model(&domain.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"disabled_at":     at,
+			"disabled_reason": reason,
+			"disabled_until":  until,
+		}).Error
+}
+
+func (r *userRepository) Reinstate(userID uint) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND disabled_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"disabled_at":     nil,
+			"disabled_reason": "",
+			"disabled_until":  nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) UpdateApproval(userID uint, from, to string) (bool, error) {
+	result := r.db.Model(&domain.User{}).
+		Where("id = ? AND approval_status = ?", userID, from).
+		Updates(map[string]interface{}{
+			"approval_status": to,
+			"version":         gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) SoftDelete(userID uint) (bool, error) {
+	result := r.db.Delete(&domain.User{}, userID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) Restore(userID uint) (bool, error) {
+	result := r.db.Unscoped().Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", userID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) FindDeletedByPublicID(publicID string) (*domain.User, error) {
+	var user domain.User
+	if err := r.db.Unscoped().Where("public_id = ? AND deleted_at IS NOT NULL", publicID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
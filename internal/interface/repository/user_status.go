@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type userStatusRepository struct {
+	db     *gorm.DB
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewUserStatusRepository ユーザー状態をRedisにキャッシュするリポジトリ
+// 状態を変更したときは Invalidate で即座に反映させ、ttl は取りこぼしへの保険とする
+func NewUserStatusRepository(db *gorm.DB, client *redis.Client, ttl time.Duration) *userStatusRepository {
+	return &userStatusRepository{db: db, client: client, ttl: ttl}
+}
+
+func userStatusKey(publicID string) string {
+	return "user:status:" + publicID
+}
+
+func (r *userStatusRepository) Get(publicID string) (*domain.UserStatus, error) {
+	ctx := context.Background()
+	data, err := r.client.Get(ctx, userStatusKey(publicID)).Bytes()
+	if err == nil {
+		var status domain.UserStatus
+		if err := json.Unmarshal(data, &status); err == nil {
+			return &status, nil
+		}
+	} else if err != redis.Nil {
+		return nil, err
+	}
+
+	var user domain.User
+	status := domain.UserStatus{}
+	err = r.db.Select("id", "disabled_at", "disabled_until").Where("public_id = ?", publicID).First(&user).Error
+	switch {
+	case err == nil:
+		status = domain.UserStatus{UserID: user.ID, DisabledAt: user.DisabledAt, DisabledUntil: user.DisabledUntil}
+	case err != gorm.ErrRecordNotFound:
+		return nil, err
+	}
+
+	// 存在しないユーザーも UserID=0 としてキャッシュする
+	if data, err := json.Marshal(status); err == nil {
+		r.client.Set(ctx, userStatusKey(publicID), data, r.ttl)
+	}
+	return &status, nil
+}
+
+func (r *userStatusRepository) Invalidate(publicID string) error {
+	return r.client.Del(context.Background(), userStatusKey(publicID)).Err()
+}
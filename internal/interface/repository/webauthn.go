@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"user-jwt/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+type webAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) *webAuthnRepository {
+	return &webAuthnRepository{db: db}
+}
+
+func (r *webAuthnRepository) ListByUser(userID uint) ([]domain.WebAuthnCredential, error) {
+	var creds []domain.WebAuthnCredential
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&creds).Error; err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+func (r *webAuthnRepository) FindByCredentialID(credentialID string) (*domain.WebAuthnCredential, error) {
+	var cred domain.WebAuthnCredential
+	if err := r.db.Where("credential_id = ?", credentialID).First(&cred).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (r *webAuthnRepository) Create(cred domain.WebAuthnCredential) (domain.WebAuthnCredential, error) {
+	if err := r.db.Create(&cred).Error; err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+	return cred, nil
+}
+
+func (r *webAuthnRepository) UpdateSignCount(id uint, oldCount, newCount uint32, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&domain.WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", id, oldCount).
+		Updates(map[string]interface{}{"sign_count": newCount, "last_used_at": usedAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *webAuthnRepository) Delete(userID, id uint) (bool, error) {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&domain.WebAuthnCredential{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+type webAuthnSessionRepository struct {
+	client *redis.Client
+}
+
+func NewWebAuthnSessionRepository(client *redis.Client) *webAuthnSessionRepository {
+	return &webAuthnSessionRepository{client: client}
+}
+
+func (r *webAuthnSessionRepository) Save(sessionID string, session domain.WebAuthnSession, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), "webauthn:session:"+sessionID, data, ttl).Err()
+}
+
+func (r *webAuthnSessionRepository) Take(sessionID string) (*domain.WebAuthnSession, error) {
+	data, err := r.client.GetDel(context.Background(), "webauthn:session:"+sessionID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var session domain.WebAuthnSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
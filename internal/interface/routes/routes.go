@@ -1,11 +1,14 @@
 package routes
 
 import (
+	"user-jwt/internal/domain"
 	"user-jwt/internal/interface/handler"
+	"user-jwt/internal/interface/job"
 	"user-jwt/internal/interface/middleware"
 	"user-jwt/internal/interface/repository"
 	"user-jwt/internal/usecase"
 	"user-jwt/pkg/config"
+	"user-jwt/pkg/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,23 +16,230 @@ import (
 func SetupRoutes(router *gin.Engine) {
 	db := config.DB
 
-	userRepo := repository.NewUserRepository(db)
+	accountCfg := config.LoadAccountConfig()
+	userRepo := repository.NewUserRepository(db, accountCfg.DeletedEmailHold)
 	userUsecase := usecase.NewUserUsecase(userRepo)
 	userHandler := handler.NewUserHandler(userUsecase)
-	authUsecase := usecase.NewAuthUsecase(userRepo)
-	authHandler := handler.NewAuthHandler(authUsecase)
+	auditRepo := repository.NewAuditRepository(db)
+	attemptRepo := repository.NewLoginAttemptRepository(config.RedisClient, "login")
+	statsRepo := repository.NewSignInStatsRepository(config.RedisClient)
+	settingRepo := repository.NewSettingRepository(config.RedisClient)
+	detector := usecase.NewStuffingDetector(statsRepo, settingRepo, auditRepo, config.LoadStuffingThresholds())
+	mfaRepo := repository.NewMFARepository(db)
+	authCfg := config.LoadAuthConfig()
+	lockoutCfg := config.LoadLockoutConfig()
+	mail := config.NewMailer()
+	mfaAttemptRepo := repository.NewLoginAttemptRepository(config.RedisClient, "mfa-attempt")
+	mfaUsecase := usecase.NewMFAUsecase(userRepo, mfaRepo, mfaAttemptRepo, auditRepo, lockoutCfg, authCfg.MFAIssuer)
+	mfaHandler := handler.NewMFAHandler(mfaUsecase)
+	webAuthnRepo := repository.NewWebAuthnRepository(db)
+	webAuthnSessionRepo := repository.NewWebAuthnSessionRepository(config.RedisClient)
+	webAuthnUsecase := usecase.NewWebAuthnUsecase(userRepo, webAuthnRepo, webAuthnSessionRepo, auditRepo, config.LoadWebAuthnConfig())
+	webAuthnHandler := handler.NewWebAuthnHandler(webAuthnUsecase)
+	secondFactors := []usecase.SecondFactor{mfaUsecase, webAuthnUsecase}
+	identityRepo := repository.NewIdentityRepository(db)
+	// 電話番号の確認コードの失敗回数もサインインの識別子と衝突しないよう namespace を分ける
+	phoneAttemptRepo := repository.NewLoginAttemptRepository(config.RedisClient, "phone-attempt")
+	identityUsecase := usecase.NewIdentityUsecase(userRepo, identityRepo, phoneAttemptRepo, auditRepo, config.NewSMSSender(), lockoutCfg, authCfg)
+	identityHandler := handler.NewIdentityHandler(identityUsecase)
+	limiter := ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(config.RedisClient), ratelimit.NewMemoryLimiter())
+	limits := config.LoadRateLimitConfig()
+	screeningCfg := config.LoadScreeningConfig()
+	powUsecase := usecase.NewProofOfWorkUsecase(repository.NewProofOfWorkRepository(config.RedisClient), screeningCfg)
+	challengeHandler := handler.NewChallengeHandler(powUsecase)
+	screener := usecase.NewSignUpScreener(config.NewDisposableDomainList(), config.NewMXChecker(), limiter, powUsecase, screeningCfg)
+	signUpCfg := config.LoadSignUpConfig()
+	invitationRepo := repository.NewInvitationRepository(config.RedisClient)
+	authUsecase := usecase.NewAuthUsecase(userRepo, identityRepo, invitationRepo, secondFactors, attemptRepo, auditRepo,
+		lockoutCfg, detector, powUsecase, screener, mail, authCfg, signUpCfg)
+	registrationHandler := handler.NewRegistrationHandler(
+		usecase.NewRegistrationUsecase(userRepo, invitationRepo, auditRepo, mail, signUpCfg))
+	authHandler := handler.NewAuthHandler(authUsecase, authCfg)
+	passwordlessRepo := repository.NewPasswordlessRepository(config.RedisClient)
+	passwordlessCfg := config.LoadPasswordlessConfig()
+	// 招待・承認などの受付条件を迂回させないよう、自動作成は誰でも登録できる場合に限る
+	passwordlessCfg.AutoSignUp = passwordlessCfg.AutoSignUp && signUpCfg.Open()
+	passwordlessUsecase := usecase.NewPasswordlessUsecase(userRepo, passwordlessRepo, secondFactors, auditRepo, screener, mail, passwordlessCfg)
+	passwordlessHandler := handler.NewPasswordlessHandler(passwordlessUsecase)
+	oidcCfg := config.LoadOIDCConfig()
+	// パスワードレスと同じく、外部アカウントでの自動作成は誰でも登録できる場合に限る
+	oidcUsecase := usecase.NewOIDCUsecase(config.NewOIDCProviders(oidcCfg), userRepo, identityRepo,
+		repository.NewOIDCLoginRepository(config.RedisClient), secondFactors, auditRepo, screener, oidcCfg, signUpCfg.Open())
+	oidcHandler := handler.NewOIDCHandler(oidcUsecase, oidcCfg)
+	revocationRepo := repository.NewTokenRevocationRepository(config.RedisClient)
+	emailChangeRepo := repository.NewEmailChangeRepository(config.RedisClient)
+	emailChangeUsecase := usecase.NewEmailChangeUsecase(userRepo, emailChangeRepo, revocationRepo, auditRepo, mail, authCfg)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeUsecase)
+	exportRepo := repository.NewDataExportRepository(config.RedisClient)
+	userStatusRepo := repository.NewUserStatusRepository(db, config.RedisClient, accountCfg.StatusCacheTTL)
+	orgRepo := repository.NewOrganizationRepository(db)
+	accountUsecase := usecase.NewAccountUsecase(userRepo, repository.NewErasureRepository(db), orgRepo, revocationRepo,
+		passwordlessRepo, emailChangeRepo, attemptRepo, mfaAttemptRepo, phoneAttemptRepo,
+		exportRepo, invitationRepo, userStatusRepo, auditRepo, mail, accountCfg)
+	accountHandler := handler.NewAccountHandler(accountUsecase)
+	exportUsecase := usecase.NewDataExportUsecase(userRepo, exportRepo,
+		auditRepo, mfaRepo, webAuthnRepo, identityRepo, orgRepo, revocationRepo, accountCfg, authCfg.APIBaseURL)
+	exportHandler := handler.NewDataExportHandler(exportUsecase)
+	adminUserUsecase := usecase.NewAdminUserUsecase(userRepo, mfaRepo, webAuthnRepo, attemptRepo, userStatusRepo, auditRepo)
+	adminHandler := handler.NewAdminHandler(authUsecase, detector, adminUserUsecase)
+	orgUsecase := usecase.NewOrganizationUsecase(orgRepo, repository.NewOrgInvitationRepository(db),
+		userRepo, revocationRepo, auditRepo, screener, mail, signUpCfg, config.LoadOrgConfig())
+	orgHandler := handler.NewOrganizationHandler(orgUsecase)
+
+	// 削除の猶予期間を過ぎたアカウントの消去
+	go job.RunErasure(accountUsecase, accountCfg.ErasureInterval)
 
 	auth := router.Group("/auth")
 	{
-		auth.POST("/sign-up", authHandler.SignUp)
-		auth.POST("/sign-in", authHandler.SignIn)
+		auth.GET("/challenge",
+			middleware.RateLimit(limiter, "challenge", limits.ChallengeIP, middleware.KeyByIP),
+			challengeHandler.Issue)
+		auth.POST("/sign-up",
+			middleware.RateLimit(limiter, "sign-up", limits.SignUpIP, middleware.KeyByIP),
+			authHandler.SignUp)
+		auth.POST("/sign-in",
+			middleware.RateLimit(limiter, "sign-in", limits.SignInIP, middleware.KeyByIP),
+			middleware.RateLimit(limiter, "sign-in", limits.SignInEmail, middleware.KeyByIdentifier),
+			authHandler.SignIn)
+		auth.POST("/mfa/verify",
+			middleware.RateLimit(limiter, "mfa-verify", limits.MFAVerifyIP, middleware.KeyByIP),
+			mfaHandler.Verify)
+		auth.POST("/webauthn/login/begin",
+			middleware.RateLimit(limiter, "webauthn-login", limits.WebAuthnIP, middleware.KeyByIP),
+			webAuthnHandler.BeginLogin)
+		auth.POST("/webauthn/login/finish",
+			middleware.RateLimit(limiter, "webauthn-login-finish", limits.WebAuthnIP, middleware.KeyByIP),
+			webAuthnHandler.FinishLogin)
+		auth.POST("/webauthn/mfa/begin",
+			middleware.RateLimit(limiter, "webauthn-mfa", limits.WebAuthnIP, middleware.KeyByIP),
+			webAuthnHandler.BeginMFA)
+		auth.POST("/webauthn/mfa/finish",
+			middleware.RateLimit(limiter, "webauthn-mfa-finish", limits.WebAuthnIP, middleware.KeyByIP),
+			webAuthnHandler.FinishMFA)
+		auth.POST("/passwordless/start",
+			middleware.RateLimit(limiter, "passwordless-start", limits.PasswordlessStartIP, middleware.KeyByIP),
+			middleware.RateLimit(limiter, "passwordless-start", limits.PasswordlessStartEmail, middleware.KeyByEmail),
+			passwordlessHandler.Start)
+		auth.POST("/passwordless/verify",
+			middleware.RateLimit(limiter, "passwordless-verify", limits.PasswordlessVerifyIP, middleware.KeyByIP),
+			passwordlessHandler.Verify)
+		auth.GET("/oidc/providers", oidcHandler.ListProviders)
+		auth.GET("/oidc/:provider",
+			middleware.RateLimit(limiter, "oidc-start", limits.OIDCIP, middleware.KeyByIP),
+			oidcHandler.Start)
+		auth.GET("/oidc/:provider/callback",
+			middleware.RateLimit(limiter, "oidc-callback", limits.OIDCIP, middleware.KeyByIP),
+			oidcHandler.Callback)
+		auth.GET("/verify-email",
+			middleware.RateLimit(limiter, "verify-email", limits.VerifyEmailIP, middleware.KeyByIP),
+			authHandler.VerifyEmail)
+		auth.POST("/verify-email/resend",
+			middleware.RateLimit(limiter, "verify-email-resend", limits.VerifyEmailIP, middleware.KeyByIP),
+			middleware.RateLimit(limiter, "verify-email-resend", limits.VerifyEmailResendEmail, middleware.KeyByEmail),
+			authHandler.ResendVerification)
+	}
+
+	requireAuth := middleware.AuthMiddleware(userStatusRepo, revocationRepo)
+	// 重要な操作には直近の再認証を要求する
+	recentAuth := middleware.RequireRecentAuth(authCfg.StepUpMaxAge, "")
+
+	router.POST("/auth/step-up", requireAuth,
+		middleware.RateLimit(limiter, "step-up", limits.StepUpUser, middleware.KeyByUserID),
+		authHandler.StepUp)
+
+	mfa := router.Group("/auth/mfa")
+	mfa.Use(requireAuth)
+	{
+		mfa.POST("/totp/enroll", recentAuth, mfaHandler.EnrollTOTP)
+		mfa.POST("/totp/confirm", mfaHandler.ConfirmTOTP)
+		mfa.POST("/totp/disable", recentAuth, mfaHandler.DisableTOTP)
+		mfa.POST("/recovery-codes", recentAuth, mfaHandler.RegenerateRecoveryCodes)
+	}
+
+	passkey := router.Group("/auth/webauthn")
+	passkey.Use(requireAuth)
+	{
+		passkey.POST("/register/begin", recentAuth, webAuthnHandler.BeginRegistration)
+		passkey.POST("/register/finish", webAuthnHandler.FinishRegistration)
+		passkey.GET("/credentials", webAuthnHandler.ListCredentials)
+		passkey.DELETE("/credentials/:id", recentAuth, webAuthnHandler.DeleteCredential)
 	}
 
-	handler.RegisterHandlers(router, authHandler)
+	handler.RegisterHandlersWithOptions(router, authHandler, handler.GinServerOptions{
+		Middlewares: []handler.MiddlewareFunc{
+			handler.MiddlewareFunc(middleware.RateLimit(limiter, "sign-out", limits.SignOutUser, middleware.KeyByUserID)),
+		},
+	})
 
 	user := router.Group("/user")
-	user.Use(middleware.AuthMiddleware())
+	user.Use(requireAuth)
 	{
+		user.GET("/me", userHandler.GetMe)
+		user.PATCH("/me", userHandler.UpdateMe)
+		user.DELETE("/me", recentAuth, accountHandler.DeleteMe)
+		user.POST("/me/export",
+			middleware.RateLimit(limiter, "data-export", limits.ExportUser, middleware.KeyByUserID),
+			exportHandler.Start)
+		user.GET("/me/export/:id", exportHandler.Status)
+		user.POST("/me/email", recentAuth,
+			middleware.RateLimit(limiter, "email-change", limits.EmailChangeUser, middleware.KeyByUserID),
+			emailChangeHandler.Request)
+		user.GET("/me/identities", identityHandler.List)
+		user.PUT("/me/username", recentAuth, identityHandler.SetUsername)
+		user.DELETE("/me/username", recentAuth, identityHandler.RemoveUsername)
+		user.POST("/me/phone", recentAuth,
+			middleware.RateLimit(limiter, "phone-verification", limits.PhoneUser, middleware.KeyByUserID),
+			identityHandler.StartPhone)
+		user.POST("/me/phone/verify", identityHandler.VerifyPhone)
+		user.DELETE("/me/phone", recentAuth, identityHandler.RemovePhone)
 		user.GET("/:id", userHandler.GetUserByID)
 	}
+
+	// メールのリンクから開くため認証は不要（トークンで本人確認する）
+	router.GET("/user/email/confirm",
+		middleware.RateLimit(limiter, "email-change-confirm", limits.EmailLinkIP, middleware.KeyByIP),
+		emailChangeHandler.Confirm)
+	router.GET("/user/email/revert",
+		middleware.RateLimit(limiter, "email-change-revert", limits.EmailLinkIP, middleware.KeyByIP),
+		emailChangeHandler.Revert)
+	router.GET("/user/exports/download",
+		middleware.RateLimit(limiter, "data-export-download", limits.ExportDownloadIP, middleware.KeyByIP),
+		exportHandler.Download)
+
+	orgs := router.Group("/orgs")
+	orgs.Use(requireAuth)
+	{
+		orgs.POST("", orgHandler.Create)
+		orgs.GET("", orgHandler.List)
+		orgs.GET("/:id/members", orgHandler.ListMembers)
+		orgs.DELETE("/:id/members/:userId", orgHandler.RemoveMember)
+		orgs.POST("/:id/invitations",
+			middleware.RateLimit(limiter, "org-invitation", limits.OrgInvitationUser, middleware.KeyByUserID),
+			orgHandler.Invite)
+		orgs.GET("/:id/invitations", orgHandler.ListInvitations)
+		orgs.DELETE("/:id/invitations/:invitationId", orgHandler.RevokeInvitation)
+	}
+
+	router.POST("/org-invitations/accept", requireAuth, orgHandler.Accept)
+	// 招待メールのリンクから新規に登録する場合（トークンで宛先を確認する）
+	router.POST("/org-invitations/sign-up",
+		middleware.RateLimit(limiter, "sign-up", limits.SignUpIP, middleware.KeyByIP),
+		orgHandler.SignUp)
+
+	admin := router.Group("/admin")
+	admin.Use(requireAuth, middleware.RequireRole(domain.RoleAdmin))
+	{
+		admin.GET("/users", adminHandler.ListUsers)
+		admin.GET("/users/:id", adminHandler.GetUser)
+		admin.DELETE("/users/:id", adminHandler.DeleteUser)
+		admin.POST("/users/:id/restore", adminHandler.RestoreUser)
+		admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		admin.POST("/users/:id/suspend", adminHandler.SuspendUser)
+		admin.POST("/users/:id/reinstate", adminHandler.ReinstateUser)
+		admin.POST("/users/:id/approve", registrationHandler.ApproveUser)
+		admin.POST("/users/:id/reject", registrationHandler.RejectUser)
+		admin.POST("/invitations", registrationHandler.CreateInvitation)
+		admin.GET("/security/stuffing-thresholds", adminHandler.GetStuffingThresholds)
+		admin.PUT("/security/stuffing-thresholds", adminHandler.UpdateStuffingThresholds)
+	}
 }
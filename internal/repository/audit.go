@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"user-jwt/internal/domain"
+)
+
+// AuditRepository 監査ログのインターフェース
+type AuditRepository interface {
+	Record(event domain.AuditEvent) error                // 監査イベントを記録
+	ListByUser(userID uint) ([]domain.AuditEvent, error) // ユーザーの監査イベントを古い順に取得
+}
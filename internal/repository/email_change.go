@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// EmailChangeRepository メールアドレス変更の一時状態のインターフェース
+type EmailChangeRepository interface {
+	Save(change domain.EmailChange, confirmTTL, revertTTL time.Duration) error // 変更要求を保存（同じユーザーの確認待ちは置き換え）
+	FindByConfirmToken(tokenHash string) (*domain.EmailChange, error)          // 確認リンクのトークンで取得
+	FindByRevertToken(tokenHash string) (*domain.EmailChange, error)           // 取り消しリンクのトークンで取得
+	DeletePending(userID uint) error                                           // 確認待ちの変更要求を削除
+	DeleteRevert(tokenHash string) error                                       // 取り消しリンクを無効化
+}
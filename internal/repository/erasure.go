@@ -0,0 +1,8 @@
+package repository
+
+import "user-jwt/internal/domain"
+
+// ErasureRepository ユーザーに紐づく全データの消去のインターフェース
+type ErasureRepository interface {
+	EraseUser(userID uint, tombstone domain.AuditEvent) error // 関連テーブルを含めて消去し、匿名の記録だけを残す
+}
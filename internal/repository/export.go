@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// DataExportRepository データエクスポートのジョブとアーカイブのインターフェース
+type DataExportRepository interface {
+	SaveJob(job domain.DataExport, ttl time.Duration) error      // ジョブの状態を保存
+	FindJob(id string) (*domain.DataExport, error)               // ジョブを取得
+	SaveArchive(id string, data []byte, ttl time.Duration) error // 完成したアーカイブを保存
+	FindArchive(id string) ([]byte, error)                       // アーカイブを取得（期限切れの場合はnil）
+	DeleteByUser(userID uint) error                              // ユーザーのジョブとアーカイブをすべて削除
+}
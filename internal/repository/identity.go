@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// IdentityRepository ユーザー名・電話番号などの識別子のインターフェース
+type IdentityRepository interface {
+	ListByUser(userID uint) ([]domain.Identity, error)                 // ユーザーの識別子一覧
+	Find(userID uint, identityType string) (*domain.Identity, error)   // ユーザーの指定した種類の識別子
+	FindVerified(identityType, key string) (*domain.Identity, error)   // 正規化した値で確認済みの識別子を検索
+	Save(identity domain.Identity) (domain.Identity, error)            // 種類ごとに1件として作成または置き換え（重複時は ConflictError）
+	MarkVerified(id uint, codeHash string, at time.Time) (bool, error) // 確認コードが一致する場合に確認済みにする（重複時は ConflictError）
+	Delete(userID uint, identityType string) (bool, error)             // 識別子を削除
+}
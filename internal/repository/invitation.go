@@ -0,0 +1,10 @@
+package repository
+
+import "user-jwt/internal/domain"
+
+// InvitationRepository サインアップの招待のインターフェース
+type InvitationRepository interface {
+	Save(tokenHash string, invitation domain.Invitation) error // 招待を保存（ExpiresAt まで有効）
+	Take(tokenHash string) (*domain.Invitation, error)         // 招待を取り出して削除（1回限り）
+	DeleteByEmail(email string) error                          // メールアドレスを指定した招待をすべて削除
+}
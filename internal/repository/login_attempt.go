@@ -0,0 +1,12 @@
+package repository
+
+import "time"
+
+// LoginAttemptRepository サインイン失敗回数とロック状態のインターフェース
+type LoginAttemptRepository interface {
+	RecordFailure(email string, window time.Duration) (int, error) // 失敗回数を加算して返す
+	Reset(email string) error                                      // 失敗回数・遅延・ロックを解除
+	Delay(email string, duration time.Duration) error              // 次の試行を一定時間遅らせる
+	Lock(email string, duration time.Duration) error               // アカウントをロック
+	BlockedFor(email string) (time.Duration, bool, error)          // 残りブロック時間とロック中かどうか
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"user-jwt/internal/domain"
+)
+
+// MFARepository MFA設定のインターフェース
+type MFARepository interface {
+	FindTOTP(userID uint) (*domain.TOTPCredential, error)           // TOTP設定を取得
+	SaveTOTP(cred domain.TOTPCredential) error                      // TOTP設定を作成・更新
+	UpdateTOTPStep(userID uint, lastStep, step int64) (bool, error) // 使用済みステップを更新（競合時はfalse）
+	DeleteTOTP(userID uint) error                                   // TOTP設定とリカバリーコードを削除
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error    // リカバリーコードを再発行
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)     // 未使用のコードを使用済みにする
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// OIDCLoginRepository 外部プロバイダーへの認可リクエストの状態のインターフェース
+type OIDCLoginRepository interface {
+	Save(stateHash string, login domain.OIDCLogin, ttl time.Duration) error // 状態を保存
+	Take(stateHash string) (*domain.OIDCLogin, error)                       // 状態を取り出して削除（1回限り）
+}
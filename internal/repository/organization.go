@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// OrganizationRepository 組織と所属のインターフェース
+type OrganizationRepository interface {
+	Create(org domain.Organization, adminUserID uint) (domain.Organization, error) // 組織を作成し、作成者を管理者として追加
+	FindByID(id uint) (*domain.Organization, error)                                // IDで検索
+	FindByPublicID(publicID string) (*domain.Organization, error)                  // 公開IDで検索
+	ListByUser(userID uint) ([]domain.UserOrganization, error)                     // ユーザーが所属する組織の一覧
+	FindMembership(orgID, userID uint) (*domain.Membership, error)                 // 所属を取得（メンバーでなければnil）
+	ListMembers(orgID uint) ([]domain.OrgMember, error)                            // メンバーの一覧
+	RemoveMember(orgID, userID uint) error                                         // メンバーを外す（最後の管理者は ErrLastOrgAdmin）
+	ListSoleAdminOrgs(userID uint) ([]domain.Organization, error)                  // ユーザーが唯一の管理者で、他にもメンバーがいる組織
+}
+
+// OrgInvitationRepository 組織への招待のインターフェース
+type OrgInvitationRepository interface {
+	Create(invitation domain.OrgInvitation) (domain.OrgInvitation, error)  // 招待を作成（同じ宛先への未使用の招待は取り消す）
+	FindByPublicID(publicID string) (*domain.OrgInvitation, error)         // 公開IDで検索
+	ListPending(orgID uint, now time.Time) ([]domain.OrgInvitation, error) // 受諾・取り消しされていない期限内の招待
+	Revoke(orgID uint, publicID string, at time.Time) (bool, error)        // 未使用の招待を取り消す
+	// Accept 招待を使用済みにしてメンバーに追加する（user.ID が0の場合はユーザーも作成する）
+	// 招待が使えない場合は ErrInvalidInvitation、既にメンバーの場合は ConflictError を返す
+	Accept(invitation domain.OrgInvitation, user domain.User, at time.Time) (domain.User, error)
+}
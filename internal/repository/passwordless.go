@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// PasswordlessRepository パスワードレスサインインの一時状態のインターフェース
+type PasswordlessRepository interface {
+	Save(challenge domain.PasswordlessChallenge, ttl time.Duration) error // チャレンジを保存（既存のものは置き換え）
+	Find(email string) (*domain.PasswordlessChallenge, error)             // メールアドレスで取得
+	FindByToken(tokenHash string) (*domain.PasswordlessChallenge, error)  // マジックリンクのトークンで取得
+	Consume(challenge domain.PasswordlessChallenge) (bool, error)         // 保存中のチャレンジと一致する場合のみ削除（削除できたかを返す）
+	Delete(email string) error                                            // チャレンジを削除
+	IncrementAttempts(email string, window time.Duration) (int, error)    // コード検証の試行回数を加算（チャレンジを再発行しても維持する）
+	ResetAttempts(email string) error                                     // コード検証の試行回数を削除
+}
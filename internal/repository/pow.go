@@ -0,0 +1,9 @@
+package repository
+
+import "time"
+
+// ProofOfWorkRepository 発行したProof of Workのチャレンジのインターフェース
+type ProofOfWorkRepository interface {
+	Save(challenge string, difficulty int, ttl time.Duration) error // チャレンジと難易度を保存
+	Take(challenge string) (int, error)                             // チャレンジを取り出して削除（存在しない場合は0）
+}
@@ -0,0 +1,7 @@
+package repository
+
+// SettingRepository 実行時に変更可能な設定のインターフェース
+type SettingRepository interface {
+	Get(name string, v interface{}) (bool, error) // 設定を取得（未設定の場合はfalse）
+	Set(name string, v interface{}) error         // 設定を保存
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "time"
+
+// SignInStatsRepository サインイン試行の集計のインターフェース
+type SignInStatsRepository interface {
+	Record(keys []string, failed bool, window time.Duration) error // 各キーの試行・失敗回数を加算
+	Counts(key string, window time.Duration) (int, int, error)     // 期間内の試行回数と失敗回数
+	MarkOnce(key string, ttl time.Duration) (bool, error)          // 期間内で初回の場合にtrue
+}
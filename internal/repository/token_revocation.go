@@ -0,0 +1,9 @@
+package repository
+
+import "time"
+
+// TokenRevocationRepository ユーザー単位のアクセストークン失効のインターフェース
+type TokenRevocationRepository interface {
+	RevokeUserTokens(publicID string, at time.Time) error // at より前（ミリ秒単位）に発行されたトークンを失効させる
+	RevokedBefore(publicID string) (time.Time, error)     // この時刻より前の iat を失効とする基準（未設定の場合はゼロ値）
+}
@@ -1,12 +1,32 @@
 package repository
 
 import (
+	"time"
+
 	"user-jwt/internal/domain"
 )
 
 // UserRepository インターフェース
 type UserRepository interface {
-	FindByEmail(email string) (*domain.User, error) // ユーザーをメールで検索
-	Create(user domain.User) (domain.User, error)   // ユーザーを作成
+	FindByEmail(email string) (*domain.User, error) // ユーザーを正規化したメールアドレスで検索
+	EmailTaken(email string) (bool, error)          // 新規登録・変更に使えないメールアドレスか（削除後の保留期間中を含む）
+	Create(user domain.User) (domain.User, error)   // ユーザーを作成（メールアドレスの重複時は ConflictError）
 	FindByID(userID uint) (*domain.User, error)
+	List(filter domain.UserFilter, limit int) ([]domain.User, error)                   // 条件に一致するユーザーを新しい順に取得
+	FindByPublicID(publicID string) (*domain.User, error)                              // 公開IDで検索
+	FindIDByPublicID(publicID string) (uint, error)                                    // 公開IDから内部IDを引く（見つからない場合は0）
+	UpdatePassword(userID uint, hashedPassword string) error                           // パスワードハッシュを更新
+	MarkEmailVerified(userID uint, email string, at time.Time) (bool, error)           // メールアドレスが一致する場合に確認済みにする
+	UpdateEmail(userID uint, from, to string, verifiedAt time.Time) (bool, error)      // メールアドレスが from の場合に to へ変更（確認済みとする。重複時は ConflictError）
+	ScheduleDeletion(userID uint, dueAt time.Time) error                               // 削除予定日時を設定
+	CancelDeletion(userID uint) (bool, error)                                          // 削除予定を取り消す（予定がなければfalse）
+	FindDueForDeletion(now time.Time, afterID uint, limit int) ([]domain.User, error)  // 消去期限を過ぎたユーザーをID順に afterID の次から取得
+	Suspend(userID uint, at time.Time, reason string, until *time.Time) error          // 利用停止にする
+	Reinstate(userID uint) (bool, error)                                               // 利用停止を解除（停止中でなければfalse）
+	UpdateApproval(userID uint, from, to string) (bool, error)                         // 承認状態が from の場合に to へ変更
+	SoftDelete(userID uint) (bool, error)                                              // 論理削除する（削除済みならfalse）
+	Restore(userID uint) (bool, error)                                                 // 論理削除を取り消す（削除されていなければfalse。メールアドレスの重複時は ConflictError）
+	FindDeletedByPublicID(publicID string) (*domain.User, error)                       // 論理削除されたユーザーを公開IDで検索
+	UpdateProfile(userID uint, version int, update domain.ProfileUpdate) (bool, error) // バージョンが一致する場合にプロフィールを更新
+	OrgRoles(userID uint) (map[string]string, error)                                   // 所属する組織の公開IDとロール（トークンに含める）
 }
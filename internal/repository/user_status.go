@@ -0,0 +1,9 @@
+package repository
+
+import "user-jwt/internal/domain"
+
+// UserStatusRepository 認証時に確認するユーザー状態のキャッシュのインターフェース
+type UserStatusRepository interface {
+	Get(publicID string) (*domain.UserStatus, error) // 状態を取得（キャッシュになければDBから読み込む）
+	Invalidate(publicID string) error                // 状態が変わったときにキャッシュを破棄
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// WebAuthnRepository パスキーのインターフェース
+type WebAuthnRepository interface {
+	ListByUser(userID uint) ([]domain.WebAuthnCredential, error)                        // ユーザーのパスキー一覧
+	FindByCredentialID(credentialID string) (*domain.WebAuthnCredential, error)         // クレデンシャルIDで検索
+	Create(cred domain.WebAuthnCredential) (domain.WebAuthnCredential, error)           // パスキーを登録
+	UpdateSignCount(id uint, oldCount, newCount uint32, usedAt time.Time) (bool, error) // 署名カウンターを更新（競合時はfalse）
+	Delete(userID, id uint) (bool, error)                                               // パスキーを削除
+}
+
+// WebAuthnSessionRepository セレモニー中の一時状態のインターフェース
+type WebAuthnSessionRepository interface {
+	Save(sessionID string, session domain.WebAuthnSession, ttl time.Duration) error // 状態を保存
+	Take(sessionID string) (*domain.WebAuthnSession, error)                         // 状態を取り出して削除
+}
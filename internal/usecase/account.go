@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
+	"user-jwt/pkg/utils"
+)
+
+// 消去ジョブが1回に処理する件数
+const erasureBatchSize = 100
+
+// AccountUsecase アカウント削除のユースケース
+type AccountUsecase interface {
+	ScheduleDeletion(userID uint) (time.Time, error) // 猶予期間の後に消去する予定を登録し、全トークンを失効させる
+	EraseDue(now time.Time) (int, error)             // 猶予期間を過ぎたアカウントを消去して件数を返す
+}
+
+type accountUsecase struct {
+	userRepo         repository.UserRepository
+	erasureRepo      repository.ErasureRepository
+	orgRepo          repository.OrganizationRepository
+	revocationRepo   repository.TokenRevocationRepository
+	passwordlessRepo repository.PasswordlessRepository
+	emailChangeRepo  repository.EmailChangeRepository
+	attemptRepo      repository.LoginAttemptRepository
+	mfaAttemptRepo   repository.LoginAttemptRepository
+	phoneAttemptRepo repository.LoginAttemptRepository
+	exportRepo       repository.DataExportRepository
+	invitationRepo   repository.InvitationRepository
+	statusRepo       repository.UserStatusRepository
+	auditRepo        repository.AuditRepository
+	mailer           mailer.Mailer
+	cfg              config.AccountConfig
+}
+
+// NewAccountUsecase AccountUsecaseのコンストラクタ
+// attemptRepo はメールアドレス、mfaAttemptRepo・phoneAttemptRepo はユーザーIDをキーとする失敗回数
+func NewAccountUsecase(
+	userRepo repository.UserRepository,
+	erasureRepo repository.ErasureRepository,
+	orgRepo repository.OrganizationRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	passwordlessRepo repository.PasswordlessRepository,
+	emailChangeRepo repository.EmailChangeRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	mfaAttemptRepo repository.LoginAttemptRepository,
+	phoneAttemptRepo repository.LoginAttemptRepository,
+	exportRepo repository.DataExportRepository,
+	invitationRepo repository.InvitationRepository,
+	statusRepo repository.UserStatusRepository,
+	auditRepo repository.AuditRepository,
+	mailer mailer.Mailer,
+	cfg config.AccountConfig,
+) AccountUsecase {
+	return &accountUsecase{
+		userRepo:         userRepo,
+		erasureRepo:      erasureRepo,
+		orgRepo:          orgRepo,
+		revocationRepo:   revocationRepo,
+		passwordlessRepo: passwordlessRepo,
+		emailChangeRepo:  emailChangeRepo,
+		attemptRepo:      attemptRepo,
+		mfaAttemptRepo:   mfaAttemptRepo,
+		phoneAttemptRepo: phoneAttemptRepo,
+		exportRepo:       exportRepo,
+		invitationRepo:   invitationRepo,
+		statusRepo:       statusRepo,
+		auditRepo:        auditRepo,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+func (u *accountUsecase) ScheduleDeletion(userID uint) (time.Time, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if user == nil {
+		return time.Time{}, domain.ErrUserNotFound
+	}
+	if user.PendingDeletion() {
+		return *user.DeletionDueAt, nil
+	}
+	// 唯一の管理者である組織があれば、他のメンバーに管理者を引き継ぐまで削除できない
+	soleAdmin, err := u.orgRepo.ListSoleAdminOrgs(user.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(soleAdmin) > 0 {
+		return time.Time{}, domain.ErrOrgAdminHandoverRequired
+	}
+
+	now := time.Now()
+	dueAt := now.Add(u.cfg.DeletionGracePeriod)
+	if err := u.userRepo.ScheduleDeletion(user.ID, dueAt); err != nil {
+		return time.Time{}, err
+	}
+
+	// 発行済みのトークンと進行中のサインイン・変更手続きをすべて無効にする
+	if err := u.revocationRepo.RevokeUserTokens(user.PublicID, now); err != nil {
+		return time.Time{}, err
+	}
+	u.clearTransientState(user)
+
+	go func() {
+		body := fmt.Sprintf("Your account is scheduled for deletion on %s.\n"+
+			"All of your data will be erased permanently after that date.\n"+
+			"If you change your mind, simply sign in before then to cancel the deletion.",
+			dueAt.UTC().Format(time.RFC1123))
+		if err := u.mailer.Send(user.Email, "Your account will be deleted", body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+
+	if err := u.auditRepo.Record(domain.AuditEvent{Type: domain.AuditDeletionScheduled, UserID: &user.ID}); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return dueAt, nil
+}
+
+// EraseDue 消去に失敗したアカウントは記録して次のアカウントに進み、次回の実行で再び対象にする
+// 猶予期間中に唯一の管理者になった組織がある場合も、管理者を引き継ぐまで消去しない
+func (u *accountUsecase) EraseDue(now time.Time) (int, error) {
+	erased := 0
+	var afterID uint
+	for {
+		users, err := u.userRepo.FindDueForDeletion(now, afterID, erasureBatchSize)
+		if err != nil {
+			return erased, err
+		}
+		if len(users) == 0 {
+			return erased, nil
+		}
+
+		for i := range users {
+			user := &users[i]
+			afterID = user.ID
+			// 個人を特定できる情報を含まない記録だけを残す
+			tombstone := domain.AuditEvent{Type: domain.AuditAccountErased}
+			if err := u.erasureRepo.EraseUser(user.ID, tombstone); err != nil {
+				log.Printf("failed to erase account: user_id=%d: %v", user.ID, err)
+				continue
+			}
+			u.clearTransientState(user)
+			erased++
+		}
+	}
+}
+
+// clearTransientState Redisに残るユーザーの一時状態を削除する
+// トークン失効の記録（公開IDと時刻のみ）は発行済みトークンを拒否し続けるため残し、アクセストークンの有効期限で消える
+// サインイン統計（credential stuffing 検知）はIP単位の集計でユーザーに紐づかないため対象外とする
+func (u *accountUsecase) clearTransientState(user *domain.User) {
+	if err := u.passwordlessRepo.Delete(user.Email); err != nil {
+		log.Printf("failed to clear passwordless challenge: %v", err)
+	}
+	if err := u.passwordlessRepo.ResetAttempts(user.Email); err != nil {
+		log.Printf("failed to clear passwordless attempts: %v", err)
+	}
+	if err := u.emailChangeRepo.DeletePending(user.ID); err != nil {
+		log.Printf("failed to clear pending email change: %v", err)
+	}
+	if err := u.attemptRepo.Reset(user.Email); err != nil {
+		log.Printf("failed to clear sign-in attempts: %v", err)
+	}
+	userKey := strconv.FormatUint(uint64(user.ID), 10)
+	for _, repo := range []repository.LoginAttemptRepository{u.mfaAttemptRepo, u.phoneAttemptRepo} {
+		if err := repo.Reset(userKey); err != nil {
+			log.Printf("failed to clear verification attempts: %v", err)
+		}
+	}
+	// 作成済みのエクスポートには個人データがそのまま含まれる
+	if err := u.exportRepo.DeleteByUser(user.ID); err != nil {
+		log.Printf("failed to delete data exports: %v", err)
+	}
+	if err := u.invitationRepo.DeleteByEmail(user.Email); err != nil {
+		log.Printf("failed to delete invitations: %v", err)
+	}
+	if err := u.statusRepo.Invalidate(user.PublicID); err != nil {
+		log.Printf("failed to invalidate user status: %v", err)
+	}
+}
+
+// issueAccessToken 認証を完了したユーザーにアクセストークンを発行し、サインイン履歴に記録する
+// 削除の猶予期間中のアカウントはサインインによって削除を取り消す（利用停止中は発行しない）
+func issueAccessToken(userRepo repository.UserRepository, auditRepo repository.AuditRepository, user *domain.User, auth utils.AuthContext, client domain.ClientInfo) (string, error) {
+	if user.Suspended(time.Now()) {
+		return "", domain.ErrAccountSuspended
+	}
+	if !user.Approved() {
+		return "", domain.ErrApprovalPending
+	}
+	if user.PendingDeletion() {
+		canceled, err := userRepo.CancelDeletion(user.ID)
+		if err != nil {
+			return "", err
+		}
+		if canceled {
+			log.Printf("account deletion canceled by sign-in: user_id=%d", user.ID)
+		}
+		user.DeletionDueAt = nil
+	}
+
+	subject, err := tokenSubject(userRepo, user)
+	if err != nil {
+		return "", err
+	}
+	token, err := utils.GenerateJWT(subject, auth)
+	if err != nil {
+		return "", err
+	}
+
+	event := domain.AuditEvent{
+		Type:      domain.AuditSignIn,
+		UserID:    &user.ID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Detail:    auditDetail(map[string]interface{}{"amr": auth.AMR, "acr": auth.ACR}),
+	}
+	if err := auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+// fakeErasureRepo 指定したユーザーの消去だけを失敗させる
+type fakeErasureRepo struct {
+	userRepo *fakeUserRepo
+	fail     map[uint]bool
+	erased   []uint
+}
+
+func (r *fakeErasureRepo) EraseUser(userID uint, tombstone domain.AuditEvent) error {
+	if r.fail[userID] {
+		return errors.New("erasure failed")
+	}
+	r.erased = append(r.erased, userID)
+	r.userRepo.mu.Lock()
+	defer r.userRepo.mu.Unlock()
+	r.userRepo.users = slices.DeleteFunc(r.userRepo.users, func(user *domain.User) bool { return user.ID == userID })
+	return nil
+}
+
+type fakeExportRepo struct {
+	repository.DataExportRepository
+}
+
+func (r *fakeExportRepo) DeleteByUser(userID uint) error { return nil }
+
+func newTestAccountUsecase(users ...*domain.User) (*accountUsecase, *fakeUserRepo, *fakeErasureRepo, *fakeRevocationRepo) {
+	userRepo := newFakeUserRepo(users...)
+	erasureRepo := &fakeErasureRepo{userRepo: userRepo, fail: map[uint]bool{}}
+	revocationRepo := newFakeRevocationRepo()
+	u := &accountUsecase{
+		userRepo:         userRepo,
+		erasureRepo:      erasureRepo,
+		orgRepo:          &fakeOrgRepo{},
+		revocationRepo:   revocationRepo,
+		passwordlessRepo: newFakePasswordlessRepo(),
+		emailChangeRepo:  &fakeEmailChangeRepo{},
+		attemptRepo:      newFakeAttemptRepo(),
+		mfaAttemptRepo:   newFakeAttemptRepo(),
+		phoneAttemptRepo: newFakeAttemptRepo(),
+		exportRepo:       &fakeExportRepo{},
+		invitationRepo:   &fakeInvitationRepo{invitations: map[string]domain.Invitation{}},
+		statusRepo:       &fakeStatusRepo{},
+		auditRepo:        &fakeAuditRepo{},
+		mailer:           &fakeMailer{},
+		cfg:              config.AccountConfig{DeletionGracePeriod: 30 * 24 * time.Hour},
+	}
+	return u, userRepo, erasureRepo, revocationRepo
+}
+
+// 削除を予約すると、それまでに発行したトークンだけが失効する（直後に発行したものは有効）
+func TestScheduleDeletionRevocationCutoff(t *testing.T) {
+	user := &domain.User{Email: "alice@example.com"}
+	u, _, _, revocations := newTestAccountUsecase(user)
+
+	subject := utils.TokenSubject{UserID: user.PublicID, Email: user.Email}
+	issue := func() *utils.Claims {
+		t.Helper()
+		token, err := utils.GenerateJWT(subject, utils.NewAuthContext(utils.AMRPassword))
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := utils.VerifyJWT(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return claims
+	}
+
+	before := issue()
+	time.Sleep(2 * time.Millisecond)
+	if _, err := u.ScheduleDeletion(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	after := issue()
+
+	cutoff, _ := revocations.RevokedBefore(user.PublicID)
+	// 同じ秒に発行されていても区別できる
+	if !before.IssuedBefore(cutoff) {
+		t.Fatalf("token issued at %v not revoked by cutoff %v", before.IssuedAt, cutoff)
+	}
+	if after.IssuedBefore(cutoff) {
+		t.Fatalf("token issued at %v revoked by cutoff %v", after.IssuedAt, cutoff)
+	}
+}
+
+func TestEraseDueContinuesAfterFailure(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	users := []*domain.User{
+		{Email: "a@example.com", DeletionDueAt: &past},
+		{Email: "b@example.com", DeletionDueAt: &past},
+		{Email: "c@example.com", DeletionDueAt: &future},
+		{Email: "d@example.com", DeletionDueAt: &past},
+	}
+	u, userRepo, erasure, _ := newTestAccountUsecase(users...)
+	erasure.fail[users[1].ID] = true
+
+	erased, err := u.EraseDue(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased != 2 || !slices.Equal(erasure.erased, []uint{users[0].ID, users[3].ID}) {
+		t.Fatalf("erased = %d %v", erased, erasure.erased)
+	}
+	// 失敗したアカウントは残り、次回の実行で再び対象になる
+	if remaining, _ := userRepo.FindDueForDeletion(time.Now(), 0, erasureBatchSize); len(remaining) != 1 || remaining[0].ID != users[1].ID {
+		t.Fatalf("remaining = %v", remaining)
+	}
+}
+
+// 他のメンバーがいる組織の唯一の管理者は、管理者を引き継ぐまで削除を予約できない
+func TestScheduleDeletionSoleOrgAdmin(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	bob := &domain.User{Email: "bob@example.com"}
+	u, userRepo, _, _ := newTestAccountUsecase(alice, bob)
+	orgs := u.orgRepo.(*fakeOrgRepo)
+	orgs.addOrg("Solo", map[uint]string{alice.ID: domain.OrgRoleAdmin})
+	team := orgs.addOrg("Team", map[uint]string{alice.ID: domain.OrgRoleAdmin, bob.ID: domain.OrgRoleMember})
+
+	if _, err := u.ScheduleDeletion(alice.ID); !errors.Is(err, domain.ErrOrgAdminHandoverRequired) {
+		t.Fatalf("err = %v, want ErrOrgAdminHandoverRequired", err)
+	}
+	if user, _ := userRepo.FindByID(alice.ID); user.PendingDeletion() {
+		t.Fatal("deletion scheduled for sole admin")
+	}
+
+	// 他のメンバーを管理者にすれば削除できる（自分だけの組織は一緒に消える）
+	orgs.memberships = slices.DeleteFunc(orgs.memberships, func(m domain.Membership) bool { return m.UserID == bob.ID })
+	orgs.addMember(team.ID, bob.ID, domain.OrgRoleAdmin)
+	if _, err := u.ScheduleDeletion(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := userRepo.FindByID(alice.ID); !user.PendingDeletion() {
+		t.Fatal("deletion not scheduled after handover")
+	}
+}
@@ -0,0 +1,275 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/utils"
+)
+
+// ユーザー一覧の1ページの件数
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// AdminUserUsecase 管理者向けのユーザー管理ユースケース
+type AdminUserUsecase interface {
+	ListUsers(filter domain.UserFilter, cursor string, limit int) (UserPage, error) // 条件に一致するユーザーを新しい順にページ単位で取得
+	GetUserDetail(publicID string) (UserDetail, error)                              // ユーザーの詳細を取得
+	Suspend(actorID uint, publicID, reason string, until *time.Time) error          // 利用停止にする（発行済みトークンも即座に無効）
+	Reinstate(actorID uint, publicID string) error                                  // 利用停止を解除
+	Delete(actorID uint, publicID string) error                                     // 論理削除する（監査ログなどの参照は残る）
+	Restore(actorID uint, publicID string) error                                    // 論理削除を取り消す
+}
+
+// UserPage ユーザー一覧の1ページ
+type UserPage struct {
+	Users      []domain.User
+	NextCursor string // 次のページがない場合は空
+}
+
+// UserDetail 管理者向けのユーザー詳細
+type UserDetail struct {
+	User        *domain.User
+	TOTPEnabled bool
+	Passkeys    int
+	Locked      bool
+	BlockedFor  time.Duration // サインインがブロックされている残り時間
+}
+
+type adminUserUsecase struct {
+	userRepo     repository.UserRepository
+	mfaRepo      repository.MFARepository
+	webAuthnRepo repository.WebAuthnRepository
+	attemptRepo  repository.LoginAttemptRepository
+	statusRepo   repository.UserStatusRepository
+	auditRepo    repository.AuditRepository
+}
+
+// NewAdminUserUsecase AdminUserUsecaseのコンストラクタ
+func NewAdminUserUsecase(
+	userRepo repository.UserRepository,
+	mfaRepo repository.MFARepository,
+	webAuthnRepo repository.WebAuthnRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	statusRepo repository.UserStatusRepository,
+	auditRepo repository.AuditRepository,
+) AdminUserUsecase {
+	return &adminUserUsecase{
+		userRepo:     userRepo,
+		mfaRepo:      mfaRepo,
+		webAuthnRepo: webAuthnRepo,
+		attemptRepo:  attemptRepo,
+		statusRepo:   statusRepo,
+		auditRepo:    auditRepo,
+	}
+}
+
+func (u *adminUserUsecase) ListUsers(filter domain.UserFilter, cursor string, limit int) (UserPage, error) {
+	if limit <= 0 {
+		limit = DefaultUserPageSize
+	}
+	if limit > MaxUserPageSize {
+		limit = MaxUserPageSize
+	}
+	if cursor != "" {
+		before, err := decodeUserCursor(cursor)
+		if err != nil {
+			return UserPage{}, err
+		}
+		filter.Before = &before
+	}
+
+	// 1件多く取得して次のページの有無を判定する
+	users, err := u.userRepo.List(filter, limit+1)
+	if err != nil {
+		return UserPage{}, err
+	}
+	page := UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeUserCursor(&page.Users[limit-1])
+	}
+	return page, nil
+}
+
+func (u *adminUserUsecase) GetUserDetail(publicID string) (UserDetail, error) {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return UserDetail{}, err
+	}
+	if user == nil {
+		// 論理削除されたユーザーも参照できる
+		if user, err = u.userRepo.FindDeletedByPublicID(publicID); err != nil {
+			return UserDetail{}, err
+		}
+	}
+	if user == nil {
+		return UserDetail{}, domain.ErrUserNotFound
+	}
+
+	totp, err := u.mfaRepo.FindTOTP(user.ID)
+	if err != nil {
+		return UserDetail{}, err
+	}
+	passkeys, err := u.webAuthnRepo.ListByUser(user.ID)
+	if err != nil {
+		return UserDetail{}, err
+	}
+	blockedFor, locked, err := u.attemptRepo.BlockedFor(user.Email)
+	if err != nil {
+		return UserDetail{}, err
+	}
+
+	return UserDetail{
+		User:        user,
+		TOTPEnabled: totp.Enabled(),
+		Passkeys:    len(passkeys),
+		Locked:      locked,
+		BlockedFor:  blockedFor,
+	}, nil
+}
+
+func (u *adminUserUsecase) Suspend(actorID uint, publicID, reason string, until *time.Time) error {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	if err := u.userRepo.Suspend(user.ID, time.Now(), reason, until); err != nil {
+		return err
+	}
+	if err := u.statusRepo.Invalidate(user.PublicID); err != nil {
+		return err
+	}
+
+	detail := map[string]interface{}{"reason": reason}
+	if until != nil {
+		detail["until"] = until
+	}
+	u.audit(domain.AuditUserSuspended, actorID, user, detail)
+	return nil
+}
+
+func (u *adminUserUsecase) Reinstate(actorID uint, publicID string) error {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	reinstated, err := u.userRepo.Reinstate(user.ID)
+	if err != nil {
+		return err
+	}
+	if !reinstated {
+		return nil
+	}
+	if err := u.statusRepo.Invalidate(user.PublicID); err != nil {
+		return err
+	}
+
+	u.audit(domain.AuditUserReinstated, actorID, user, nil)
+	return nil
+}
+
+func (u *adminUserUsecase) Delete(actorID uint, publicID string) error {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	deleted, err := u.userRepo.SoftDelete(user.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return nil
+	}
+	// 発行済みトークンも認証時の状態確認で拒否されるようにする
+	if err := u.statusRepo.Invalidate(user.PublicID); err != nil {
+		return err
+	}
+
+	u.audit(domain.AuditUserDeleted, actorID, user, nil)
+	return nil
+}
+
+func (u *adminUserUsecase) Restore(actorID uint, publicID string) error {
+	user, err := u.userRepo.FindDeletedByPublicID(publicID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	// 削除後に同じアドレスで別のアカウントが作られていれば戻せない
+	existing, err := u.userRepo.FindByEmail(user.Email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return domain.ErrEmailAlreadyExists
+	}
+
+	restored, err := u.userRepo.Restore(user.ID)
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return nil
+	}
+	if err := u.statusRepo.Invalidate(user.PublicID); err != nil {
+		return err
+	}
+
+	u.audit(domain.AuditUserRestored, actorID, user, nil)
+	return nil
+}
+
+func (u *adminUserUsecase) audit(eventType string, actorID uint, user *domain.User, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &user.ID, ActorID: &actorID, Email: user.Email}
+	if detail != nil {
+		event.Detail = auditDetail(detail)
+	}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// カーソルは作成日時（マイクロ秒）と公開IDから作り、内部の連番IDは含めない
+func encodeUserCursor(user *domain.User) string {
+	raw := strconv.FormatInt(user.CreatedAt.UnixMicro(), 10) + "." + user.PublicID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(cursor string) (domain.UserCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return domain.UserCursor{}, domain.ErrInvalidCursor
+	}
+	micros, publicID, ok := strings.Cut(string(b), ".")
+	if !ok || !utils.IsUUID(publicID) {
+		return domain.UserCursor{}, domain.ErrInvalidCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return domain.UserCursor{}, domain.ErrInvalidCursor
+	}
+	return domain.UserCursor{CreatedAt: time.UnixMicro(usec), PublicID: publicID}, nil
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+func newTestAdminUserUsecase(users ...*domain.User) (*adminUserUsecase, *fakeUserRepo, *fakeStatusRepo, *fakeAuditRepo) {
+	userRepo := newFakeUserRepo(users...)
+	statusRepo := &fakeStatusRepo{userRepo: userRepo}
+	auditRepo := &fakeAuditRepo{}
+	u := &adminUserUsecase{
+		userRepo:    userRepo,
+		attemptRepo: newFakeAttemptRepo(),
+		statusRepo:  statusRepo,
+		auditRepo:   auditRepo,
+	}
+	return u, userRepo, statusRepo, auditRepo
+}
+
+func TestListUsersCursorPagination(t *testing.T) {
+	// 同じ作成日時のユーザーも公開IDの順で重複・欠落なく辿れる（マイクロ秒の精度を保つ）
+	base := time.Date(2024, 1, 1, 0, 0, 0, 123456000, time.UTC)
+	var users []*domain.User
+	for i, offset := range []time.Duration{0, time.Microsecond, time.Microsecond, time.Microsecond, time.Second} {
+		users = append(users, &domain.User{Email: string(rune('a'+i)) + "@example.com", CreatedAt: base.Add(offset)})
+	}
+	u, userRepo, _, _ := newTestAdminUserUsecase(users...)
+	want, _ := userRepo.List(domain.UserFilter{}, len(users))
+
+	var got []string
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > len(users) {
+			t.Fatal("pagination did not terminate")
+		}
+		result, err := u.ListUsers(domain.UserFilter{}, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range result.Users {
+			got = append(got, user.PublicID)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d users, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i].PublicID {
+			t.Fatalf("user %d = %s, want %s", i, got[i], want[i].PublicID)
+		}
+	}
+}
+
+func TestListUsersInvalidCursor(t *testing.T) {
+	u, _, _, _ := newTestAdminUserUsecase()
+	for _, cursor := range []string{"!!!", "bm90LWEtY3Vyc29y", encodeUserCursor(&domain.User{PublicID: "42"})} {
+		if _, err := u.ListUsers(domain.UserFilter{}, cursor, 10); !errors.Is(err, domain.ErrInvalidCursor) {
+			t.Errorf("cursor %q: err = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+// 利用停止・解除はキャッシュ済みの状態も無効にし、発行済みトークンの次のリクエストから反映する
+func TestSuspendInvalidatesStatusCache(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, _, statusRepo, audit := newTestAdminUserUsecase(alice)
+
+	suspended := func() bool {
+		t.Helper()
+		status, err := statusRepo.Get(alice.PublicID)
+		if err != nil || status.UserID != alice.ID {
+			t.Fatalf("status = %+v, err = %v", status, err)
+		}
+		return status.Suspended(time.Now())
+	}
+	if suspended() {
+		t.Fatal("suspended before Suspend")
+	}
+
+	if err := u.Suspend(1, alice.PublicID, "spam", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !suspended() {
+		t.Fatal("cached status not invalidated after Suspend")
+	}
+	if err := u.Reinstate(1, alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if suspended() {
+		t.Fatal("cached status not invalidated after Reinstate")
+	}
+	if !slices.Equal(audit.types(), []string{domain.AuditUserSuspended, domain.AuditUserReinstated}) {
+		t.Fatalf("audit = %v", audit.types())
+	}
+
+	// 停止中でなければ解除しても何もしない
+	invalidations := len(statusRepo.invalidated)
+	if err := u.Reinstate(1, alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if len(statusRepo.invalidated) != invalidations || len(audit.events) != 2 {
+		t.Fatal("reinstating an active user changed state")
+	}
+
+	if err := u.Suspend(1, "0190a6f2-7b3c-7d4e-8f00-00000000ffff", "spam", nil); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("unknown user: err = %v", err)
+	}
+}
+
+// 期限付きの利用停止は期限を過ぎると自動的に解ける
+func TestSuspendUntil(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, _, statusRepo, _ := newTestAdminUserUsecase(alice)
+	until := time.Now().Add(time.Hour)
+
+	if err := u.Suspend(1, alice.PublicID, "cool-down", &until); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := statusRepo.Get(alice.PublicID)
+	if !status.Suspended(time.Now()) || status.Suspended(until.Add(time.Second)) {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+// 論理削除したユーザーは通常の検索から消え、発行済みトークンも拒否され、復元すると元に戻る
+func TestDeleteAndRestoreUser(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	bob := &domain.User{Email: "bob@example.com"}
+	u, userRepo, statusRepo, audit := newTestAdminUserUsecase(alice, bob)
+	listed := func(deleted bool) []string {
+		t.Helper()
+		page, err := u.ListUsers(domain.UserFilter{Deleted: deleted}, "", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var emails []string
+		for _, user := range page.Users {
+			emails = append(emails, user.Email)
+		}
+		slices.Sort(emails)
+		return emails
+	}
+	if _, err := statusRepo.Get(alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.Delete(1, alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := userRepo.FindByPublicID(alice.PublicID); user != nil {
+		t.Fatal("deleted user still found")
+	}
+	if status, _ := statusRepo.Get(alice.PublicID); status.UserID != 0 {
+		t.Fatalf("cached status not invalidated after Delete: %+v", status)
+	}
+	if got := listed(true); !slices.Equal(got, []string{"alice@example.com"}) {
+		t.Fatalf("deleted users = %v", got)
+	}
+	if got := listed(false); !slices.Equal(got, []string{"bob@example.com"}) {
+		t.Fatalf("active users = %v", got)
+	}
+	if err := u.Delete(1, alice.PublicID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("second delete: err = %v, want ErrUserNotFound", err)
+	}
+
+	if err := u.Restore(1, alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := statusRepo.Get(alice.PublicID); status.UserID != alice.ID {
+		t.Fatalf("cached status not invalidated after Restore: %+v", status)
+	}
+	if got := listed(false); !slices.Equal(got, []string{"alice@example.com", "bob@example.com"}) {
+		t.Fatalf("active users after restore = %v", got)
+	}
+	if err := u.Restore(1, alice.PublicID); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("restoring an active user: err = %v, want ErrUserNotFound", err)
+	}
+	if !slices.Equal(audit.types(), []string{domain.AuditUserDeleted, domain.AuditUserRestored}) {
+		t.Fatalf("audit = %v", audit.types())
+	}
+}
+
+// 削除後に同じアドレスで作られたアカウントがあれば復元しない
+func TestRestoreUserEmailReused(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, userRepo, _, _ := newTestAdminUserUsecase(alice)
+	if err := u.Delete(1, alice.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userRepo.Create(domain.User{Email: "Alice@Example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.Restore(1, alice.PublicID); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Fatalf("err = %v, want ErrEmailAlreadyExists", err)
+	}
+	if user, _ := userRepo.FindDeletedByPublicID(alice.PublicID); user == nil {
+		t.Fatal("user restored despite the conflict")
+	}
+}
@@ -1,69 +1,526 @@
 package usecase
 
 import (
-	"errors"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
 	"user-jwt/internal/domain"
 	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
 	"user-jwt/pkg/utils"
 )
 
+// SignInResult サインインの結果
+// MFAが有効な場合は Token の代わりに MFAToken を返す
+type SignInResult struct {
+	Token      string
+	MFAToken   string
+	MFAMethods []string // 利用可能な第二要素
+}
+
+// MFARequired MFAの検証が必要か
+func (r SignInResult) MFARequired() bool {
+	return r.MFAToken != ""
+}
+
 // AuthUsecase インターフェース
 type AuthUsecase interface {
-	SignUp(email, password string) (domain.User, error)
-	SignIn(email, password string) (string, error) // JWTトークンを返す
+	SignUp(email, password, invitation string, client domain.ClientInfo) (domain.User, error) // 招待制の場合は招待トークンが必要
+	SignIn(identifier, password string, client domain.ClientInfo) (SignInResult, error)       // メールアドレス・ユーザー名・電話番号で認証してJWTトークンを返す
+	UnlockAccount(actorID uint, publicID string) error                                        // 管理者によるロック解除
+	StepUp(userID uint, password, code string) (string, error)                                // 再認証して認証時刻・強度を更新したJWTを返す
+	VerifyEmail(token string) error                                                           // 確認リンクのトークンでメールアドレスを確認済みにする
+	ResendVerification(email string) error                                                    // 確認メールを再送
 }
 
 type authUsecase struct {
-	userRepo repository.UserRepository
+	userRepo       repository.UserRepository
+	identityRepo   repository.IdentityRepository
+	invitationRepo repository.InvitationRepository
+	factors        []SecondFactor
+	attemptRepo    repository.LoginAttemptRepository
+	auditRepo      repository.AuditRepository
+	lockout        config.LockoutConfig
+	detector       StuffingDetector
+	challenge      ChallengeVerifier
+	screener       SignUpScreener
+	mailer         mailer.Mailer
+	cfg            config.AuthConfig
+	signUp         config.SignUpConfig
 }
 
-func NewAuthUsecase(userRepo repository.UserRepository) AuthUsecase {
-	return &authUsecase{userRepo: userRepo}
+func NewAuthUsecase(
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	invitationRepo repository.InvitationRepository,
+	factors []SecondFactor,
+	attemptRepo repository.LoginAttemptRepository,
+	auditRepo repository.AuditRepository,
+	lockout config.LockoutConfig,
+	detector StuffingDetector,
+	challenge ChallengeVerifier,
+	screener SignUpScreener,
+	mailer mailer.Mailer,
+	cfg config.AuthConfig,
+	signUp config.SignUpConfig,
+) AuthUsecase {
+	return &authUsecase{
+		userRepo:       userRepo,
+		identityRepo:   identityRepo,
+		invitationRepo: invitationRepo,
+		factors:        factors,
+		attemptRepo:    attemptRepo,
+		auditRepo:      auditRepo,
+		lockout:        lockout,
+		detector:       detector,
+		challenge:      challenge,
+		screener:       screener,
+		mailer:         mailer,
+		cfg:            cfg,
+		signUp:         signUp,
+	}
 }
 
-func (u *authUsecase) SignUp(email, password string) (domain.User, error) {
-	// 重複チェック
-	existingUser, _ := u.userRepo.FindByEmail(email)
-	if existingUser != nil {
-		return domain.User{}, errors.New("email already exists")
+func (u *authUsecase) SignUp(email, password, invitation string, client domain.ClientInfo) (domain.User, error) {
+	// パスワードハッシュ化（重複時も同じ処理時間になるよう先に行う）
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return domain.User{}, err
 	}
 
-	// パスワードハッシュ化
-	hashedPassword, err := utils.HashPassword(password)
+	if !u.signUp.DomainAllowed(email) {
+		return domain.User{}, domain.ErrEmailDomainNotAllowed
+	}
+
+	// 使い捨てアドレス・ボットによる登録を拒否し、理由を監査ログに残す
+	if err := screenSignUp(u.screener, u.auditRepo, email, client); err != nil {
+		return domain.User{}, err
+	}
+
+	// 重複チェック（論理削除されたアカウントのアドレスも保留期間中は使えない）
+	taken, err := u.userRepo.EmailTaken(email)
 	if err != nil {
 		return domain.User{}, err
 	}
+	if taken {
+		if u.cfg.EnumerationProtection {
+			// 既存アカウントの所有者にだけ通知し、応答は新規登録と区別しない
+			u.sendMail(email, "Sign-up attempt for your account",
+				"Someone tried to create an account with this email address.\n"+
+					"If this was you, sign in or reset your password instead.\n"+
+					"If not, you can safely ignore this email.")
+		}
+		return domain.User{}, domain.ErrEmailAlreadyExists
+	}
+
+	// 招待は使った時点で無効にする（登録に失敗した場合は戻す）
+	var invited *domain.Invitation
+	if u.signUp.Mode == config.SignUpInvite {
+		if invited, err = u.takeInvitation(invitation, email); err != nil {
+			return domain.User{}, err
+		}
+	}
 
 	// ユーザー作成
 	user := domain.User{
-		Email:    email,
-		Password: hashedPassword,
+		Email:          email,
+		Password:       hashedPassword,
+		Role:           domain.RoleUser,
+		ApprovalStatus: domain.ApprovalApproved,
+	}
+	if u.signUp.Mode == config.SignUpApproval {
+		user.ApprovalStatus = domain.ApprovalPending
 	}
 	createdUser, err := u.userRepo.Create(user)
 	if err != nil {
+		if invited != nil {
+			if err := u.invitationRepo.Save(utils.HashToken(invitation), *invited); err != nil {
+				log.Printf("failed to restore invitation: %v", err)
+			}
+		}
 		return domain.User{}, err
 	}
 
+	if err := u.sendVerification(&createdUser); err != nil {
+		log.Printf("failed to send verification email: %v", err)
+	}
+
 	return createdUser, nil
 }
 
-func (u *authUsecase) SignIn(email, password string) (string, error) {
-	// ユーザー取得
+// takeInvitation 招待トークンを消費する（宛先が指定された招待は同じアドレスでのみ使える）
+func (u *authUsecase) takeInvitation(token, email string) (*domain.Invitation, error) {
+	if token == "" {
+		return nil, domain.ErrInvalidInvitation
+	}
+	invited, err := u.invitationRepo.Take(utils.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invited == nil {
+		return nil, domain.ErrInvalidInvitation
+	}
+	if invited.Email != "" && utils.NormalizeEmail(invited.Email) != utils.NormalizeEmail(email) {
+		// 他のアドレスで使おうとした場合は招待を残す
+		if err := u.invitationRepo.Save(utils.HashToken(token), *invited); err != nil {
+			log.Printf("failed to restore invitation: %v", err)
+		}
+		return nil, domain.ErrInvalidInvitation
+	}
+	return invited, nil
+}
+
+func (u *authUsecase) VerifyEmail(token string) error {
+	claims, err := utils.VerifyPurposeJWT(token, utils.PurposeVerifyEmail)
+	if err != nil {
+		return domain.ErrInvalidToken
+	}
+
+	userID, err := u.userRepo.FindIDByPublicID(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if userID == 0 {
+		return domain.ErrInvalidToken
+	}
+
+	// トークン発行後にメールアドレスが変わっていれば無効
+	verified, err := u.userRepo.MarkEmailVerified(userID, claims.Email, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return domain.ErrInvalidToken
+	}
+	return nil
+}
+
+func (u *authUsecase) ResendVerification(email string) error {
 	user, err := u.userRepo.FindByEmail(email)
+	if err != nil {
+		return err
+	}
+	// 未登録・確認済みの場合も応答は変えない
+	if user == nil || user.EmailVerified() {
+		return nil
+	}
+	return u.sendVerification(user)
+}
+
+// sendVerification 署名付きの確認リンクをメールで送る
+func (u *authUsecase) sendVerification(user *domain.User) error {
+	token, err := utils.GeneratePurposeJWT(utils.PurposeVerifyEmail, u.cfg.EmailVerificationTTL,
+		utils.Claims{UserID: user.PublicID, Email: user.Email})
+	if err != nil {
+		return err
+	}
+
+	link := u.cfg.APIBaseURL + "/auth/verify-email?token=" + url.QueryEscape(token)
+	u.sendMail(user.Email, "Verify your email address",
+		"Please confirm your email address by opening the link below.\n"+
+			"The link expires in "+u.cfg.EmailVerificationTTL.String()+".\n\n"+link)
+	return nil
+}
+
+// sendMail メールを非同期で送信（送信の有無で応答時間が変わらないようにする）
+func (u *authUsecase) sendMail(to, subject, body string) {
+	go func() {
+		if err := u.mailer.Send(to, subject, body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+}
+
+func (u *authUsecase) SignIn(identifier, password string, client domain.ClientInfo) (SignInResult, error) {
+	// アカウント横断の不審な失敗率を判定
+	switch u.detector.Check(client) {
+	case domain.StuffingBlock:
+		return SignInResult{}, domain.ErrSignInBlocked
+	case domain.StuffingChallenge:
+		// チャレンジを検証する仕組みがなければ、解けない要求でブロックせず記録だけ残して通す
+		if u.challenge == nil {
+			log.Printf("credential stuffing challenge mode is active but no challenge verifier is configured; allowing sign-in from %s", client.IP)
+			break
+		}
+		if !u.challenge.Verify(client.ChallengeResponse) {
+			return SignInResult{}, domain.ErrChallengeRequired
+		}
+	}
+
+	// ユーザー取得
+	user, err := u.findByIdentifier(identifier)
+
+	// 失敗回数はアカウント単位で数える（識別子を変えてもロックを回避できないようにする）
+	attemptKey := identifier
+	if err == nil && user != nil {
+		attemptKey = user.Email
+	}
+
+	// ロック・遅延中は認証処理を行わない
+	blockedFor, _, lockErr := u.attemptRepo.BlockedFor(attemptKey)
+	if lockErr != nil {
+		log.Printf("failed to check sign-in lockout: %v", lockErr)
+	}
+	if blockedFor > 0 {
+		return SignInResult{}, &domain.LockedError{RetryAfter: blockedFor}
+	}
+
 	if err != nil || user == nil {
-		return "", errors.New("invalid email or password")
+		// 実在ユーザーと応答時間を揃えるため、必ず1回ハッシュを照合する
+		utils.CompareDummyHash(password)
+		u.detector.Observe(client, true)
+		return SignInResult{}, u.recordFailure(attemptKey, nil)
 	}
 
 	// パスワードチェック
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", errors.New("invalid email or password")
+		u.detector.Observe(client, true)
+		return SignInResult{}, u.recordFailure(attemptKey, user)
+	}
+	u.detector.Observe(client, false)
+
+	if u.cfg.RequireVerifiedEmail && !user.EmailVerified() {
+		return SignInResult{}, domain.ErrEmailNotVerified
+	}
+
+	if err := u.attemptRepo.Reset(attemptKey); err != nil {
+		log.Printf("failed to reset sign-in failures: %v", err)
+	}
+
+	// 古いペッパーバージョンのハッシュは透過的に更新
+	if utils.NeedsRehash(user.Password) {
+		if rehashed, err := utils.HashPassword(password); err == nil {
+			if err := u.userRepo.UpdatePassword(user.ID, rehashed); err != nil {
+				log.Printf("failed to upgrade password hash for user %d: %v", user.ID, err)
+			}
+		}
+	}
+
+	return completeSignIn(u.userRepo, u.auditRepo, user, u.factors, []string{utils.AMRPassword}, client)
+}
+
+// findByIdentifier メールアドレス・ユーザー名・電話番号のいずれかでユーザーを探す
+// ユーザー名と電話番号は確認済みのものだけを受け付ける
+func (u *authUsecase) findByIdentifier(identifier string) (*domain.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	if strings.Contains(identifier, "@") {
+		return u.userRepo.FindByEmail(identifier)
+	}
+
+	identityType, key := domain.IdentityUsername, utils.UsernameKey(identifier)
+	if phone := utils.NormalizePhone(identifier); utils.ValidPhone(phone) {
+		identityType, key = domain.IdentityPhone, phone
+	}
+	identity, err := u.identityRepo.FindVerified(identityType, key)
+	if err != nil || identity == nil {
+		return nil, err
+	}
+	return u.userRepo.FindByID(identity.UserID)
+}
+
+// completeSignIn 第二要素が有効な場合はMFAチャレンジトークン、それ以外はJWTを発行する
+// amr には完了した第一要素の認証方式を渡す
+func completeSignIn(userRepo repository.UserRepository, auditRepo repository.AuditRepository, user *domain.User, factors []SecondFactor, amr []string, client domain.ClientInfo) (SignInResult, error) {
+	// 利用停止中のアカウントは第一要素が正しくてもサインインさせない
+	if user.Suspended(time.Now()) {
+		return SignInResult{}, domain.ErrAccountSuspended
+	}
+	if !user.Approved() {
+		return SignInResult{}, domain.ErrApprovalPending
+	}
+
+	var methods []string
+	for _, factor := range factors {
+		enabled, err := factor.Enabled(user.ID)
+		if err != nil {
+			return SignInResult{}, err
+		}
+		if enabled {
+			methods = append(methods, factor.Method())
+		}
+	}
+	if len(methods) > 0 {
+		mfaToken, err := utils.GeneratePurposeJWT(utils.PurposeMFA, mfaTokenTTL, utils.Claims{UserID: user.PublicID, AMR: amr})
+		if err != nil {
+			return SignInResult{}, err
+		}
+		return SignInResult{MFAToken: mfaToken, MFAMethods: methods}, nil
 	}
 
 	// JWTトークン生成
-	token, err := utils.GenerateJWT(user.ID, user.Email)
+	token, err := issueAccessToken(userRepo, auditRepo, user, utils.NewAuthContext(amr...), client)
+	if err != nil {
+		return SignInResult{}, err
+	}
+
+	return SignInResult{Token: token}, nil
+}
+
+// tokenSubject ユーザーからトークンに含める情報を作る
+func tokenSubject(userRepo repository.UserRepository, user *domain.User) (utils.TokenSubject, error) {
+	orgs, err := userRepo.OrgRoles(user.ID)
+	if err != nil {
+		return utils.TokenSubject{}, err
+	}
+	return utils.TokenSubject{
+		UserID:        user.PublicID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified(),
+		Role:          user.Role,
+		Orgs:          orgs,
+	}, nil
+}
+
+// recordFailure 失敗回数を記録し、回数に応じて遅延またはロックを設定する
+func (u *authUsecase) recordFailure(email string, user *domain.User) error {
+	failures, err := u.attemptRepo.RecordFailure(email, u.lockout.Window)
+	if err != nil {
+		log.Printf("failed to record sign-in failure: %v", err)
+		return domain.ErrInvalidCredentials
+	}
+
+	if failures >= u.lockout.Threshold {
+		if err := u.attemptRepo.Lock(email, u.lockout.LockDuration); err != nil {
+			log.Printf("failed to lock account: %v", err)
+			return domain.ErrInvalidCredentials
+		}
+
+		event := domain.AuditEvent{
+			Type:   domain.AuditAccountLocked,
+			Email:  email,
+			Detail: auditDetail(map[string]interface{}{"failures": failures, "duration": u.lockout.LockDuration.String()}),
+		}
+		if user != nil {
+			event.UserID = &user.ID
+		}
+		if err := u.auditRepo.Record(event); err != nil {
+			log.Printf("failed to record audit event: %v", err)
+		}
+		return &domain.LockedError{RetryAfter: u.lockout.LockDuration}
+	}
+
+	// 失敗が続くほど次の試行までの待ち時間を指数的に延ばす
+	if delay := backoffDelay(failures, u.lockout.BaseDelay, u.lockout.MaxDelay); delay > 0 {
+		if err := u.attemptRepo.Delay(email, delay); err != nil {
+			log.Printf("failed to set sign-in delay: %v", err)
+		}
+	}
+	return domain.ErrInvalidCredentials
+}
+
+func (u *authUsecase) UnlockAccount(actorID uint, publicID string) error {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil || user == nil {
+		return domain.ErrUserNotFound
+	}
+
+	if err := u.attemptRepo.Reset(user.Email); err != nil {
+		return err
+	}
+
+	event := domain.AuditEvent{
+		Type:    domain.AuditAccountUnlocked,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+		Email:   user.Email,
+	}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+func (u *authUsecase) StepUp(userID uint, password, code string) (string, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return "", domain.ErrUserNotFound
+	}
+
+	var amr []string
+	if password != "" {
+		blockedFor, _, err := u.attemptRepo.BlockedFor(user.Email)
+		if err != nil {
+			log.Printf("failed to check sign-in lockout: %v", err)
+		}
+		if blockedFor > 0 {
+			return "", &domain.LockedError{RetryAfter: blockedFor}
+		}
+		if !utils.CheckPasswordHash(password, user.Password) {
+			return "", u.recordFailure(user.Email, user)
+		}
+		amr = append(amr, utils.AMRPassword)
+	}
+
+	if code != "" {
+		factor, err := u.enabledCodeFactor(user.ID)
+		if err != nil {
+			return "", err
+		}
+		if factor == nil {
+			return "", domain.ErrMFANotEnrolled
+		}
+		if err := factor.VerifyCode(user.ID, code); err != nil {
+			return "", err
+		}
+		amr = append(amr, utils.AMROTP)
+	}
+
+	if len(amr) == 0 {
+		return "", domain.ErrInvalidCredentials
+	}
+	if len(amr) > 1 {
+		amr = append(amr, utils.AMRMFA)
+	}
+
+	subject, err := tokenSubject(u.userRepo, user)
 	if err != nil {
 		return "", err
 	}
+	return utils.GenerateJWT(subject, utils.NewAuthContext(amr...))
+}
+
+// enabledCodeFactor ユーザーが有効にしているコード入力型の第二要素を返す
+func (u *authUsecase) enabledCodeFactor(userID uint) (CodeFactor, error) {
+	for _, factor := range u.factors {
+		codeFactor, ok := factor.(CodeFactor)
+		if !ok {
+			continue
+		}
+		enabled, err := codeFactor.Enabled(userID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			return codeFactor, nil
+		}
+	}
+	return nil, nil
+}
+
+// backoffDelay 失敗回数に応じた指数バックオフの待ち時間（初回の失敗は遅延なし）
+func backoffDelay(failures int, base, max time.Duration) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+	delay := base
+	for i := 2; i < failures; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
 
-	return token, nil
+// auditDetail 監査ログの補足情報をJSON文字列にする
+func auditDetail(detail map[string]interface{}) string {
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
 }
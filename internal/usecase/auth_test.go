@@ -0,0 +1,409 @@
+package usecase
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	const base, max = time.Second, 10 * time.Second
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 1, want: 0}, // 初回の失敗は遅延なし
+		{failures: 2, want: time.Second},
+		{failures: 3, want: 2 * time.Second},
+		{failures: 4, want: 4 * time.Second},
+		{failures: 5, want: 8 * time.Second},
+		{failures: 6, want: max}, // 上限で頭打ち
+		{failures: 100, want: max},
+	}
+	for _, tt := range tests {
+		if got := backoffDelay(tt.failures, base, max); got != tt.want {
+			t.Errorf("backoffDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// newLockoutTestUsecase パスワード「correct-password」のユーザーを1人持つ authUsecase
+func newLockoutTestUsecase(t *testing.T, lockout config.LockoutConfig) (*authUsecase, *fakeAttemptRepo, *fakeAuditRepo, *domain.User) {
+	t.Helper()
+	hash, err := utils.HashPassword("correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &domain.User{Email: "alice@example.com", Password: hash}
+	attemptRepo := newFakeAttemptRepo()
+	auditRepo := &fakeAuditRepo{}
+	u := &authUsecase{
+		userRepo:     newFakeUserRepo(user),
+		identityRepo: &fakeIdentityRepo{},
+		attemptRepo:  attemptRepo,
+		auditRepo:    auditRepo,
+		lockout:      lockout,
+		detector:     &fakeDetector{verdict: domain.StuffingAllow},
+	}
+	return u, attemptRepo, auditRepo, user
+}
+
+func TestSignInLockout(t *testing.T) {
+	lockout := config.LockoutConfig{
+		Threshold:    4,
+		Window:       time.Hour,
+		LockDuration: 15 * time.Minute,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+	}
+	u, attempts, audit, user := newLockoutTestUsecase(t, lockout)
+	client := domain.ClientInfo{IP: "192.0.2.1"}
+
+	type step struct {
+		password  string
+		wait      bool          // 前回の遅延が過ぎるまで待つ
+		wantErr   error         // 期待するエラー（LockedError は wantRetry で判定）
+		wantRetry time.Duration // LockedError の RetryAfter
+		wantDelay time.Duration // 試行後に設定される遅延
+	}
+	steps := []step{
+		{password: "wrong", wantErr: domain.ErrInvalidCredentials, wantDelay: 0},
+		{password: "wrong", wantErr: domain.ErrInvalidCredentials, wantDelay: time.Second},
+		// 遅延中は正しいパスワードでも照合せずに拒否し、失敗回数も増やさない
+		{password: "correct-password", wantRetry: time.Second, wantDelay: time.Second},
+		{password: "wrong", wait: true, wantErr: domain.ErrInvalidCredentials, wantDelay: 2 * time.Second},
+		// しきい値に達するとロックする
+		{password: "wrong", wait: true, wantRetry: lockout.LockDuration},
+		{password: "correct-password", wait: true, wantRetry: lockout.LockDuration},
+	}
+
+	for i, s := range steps {
+		if s.wait {
+			attempts.expireDelays()
+		}
+		_, err := u.SignIn(user.Email, s.password, client)
+
+		var locked *domain.LockedError
+		switch {
+		case s.wantRetry > 0:
+			if !errors.As(err, &locked) || locked.RetryAfter != s.wantRetry {
+				t.Fatalf("step %d: err = %v, want LockedError(%v)", i, err, s.wantRetry)
+			}
+		case !errors.Is(err, s.wantErr):
+			t.Fatalf("step %d: err = %v, want %v", i, err, s.wantErr)
+		}
+		if s.wantRetry != lockout.LockDuration {
+			if got := attempts.delays[user.Email]; got != s.wantDelay {
+				t.Fatalf("step %d: delay = %v, want %v", i, got, s.wantDelay)
+			}
+		}
+	}
+	if attempts.failures[user.Email] != lockout.Threshold {
+		t.Fatalf("failures = %d, want %d", attempts.failures[user.Email], lockout.Threshold)
+	}
+	if !slices.Contains(audit.types(), domain.AuditAccountLocked) {
+		t.Fatalf("account lock not audited: %v", audit.types())
+	}
+
+	// 管理者がロックを解除するとサインインできる
+	if err := u.UnlockAccount(1, user.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	result, err := u.SignIn(user.Email, "correct-password", client)
+	if err != nil || result.Token == "" {
+		t.Fatalf("sign-in after unlock: result = %+v, err = %v", result, err)
+	}
+	if _, ok := attempts.failures[user.Email]; ok {
+		t.Fatal("failures not reset after successful sign-in")
+	}
+}
+
+// 存在しないアカウントへの試行も同じように遅延・ロックされる（応答でアカウントの有無がわからない）
+func TestSignInLockoutUnknownAccount(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 2, Window: time.Hour, LockDuration: time.Minute, BaseDelay: time.Second, MaxDelay: time.Minute}
+	u, attempts, _, _ := newLockoutTestUsecase(t, lockout)
+
+	if _, err := u.SignIn("nobody@example.com", "wrong", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("err = %v", err)
+	}
+	var locked *domain.LockedError
+	if _, err := u.SignIn("nobody@example.com", "wrong", domain.ClientInfo{}); !errors.As(err, &locked) {
+		t.Fatalf("err = %v, want LockedError", err)
+	}
+	if attempts.failures["nobody@example.com"] != 2 {
+		t.Fatalf("failures = %v", attempts.failures)
+	}
+}
+
+// ユーザー名でサインインしても失敗回数はアカウント（メールアドレス）単位で数える
+func TestSignInLockoutSharedAcrossIdentifiers(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 10, Window: time.Hour, LockDuration: time.Minute, BaseDelay: time.Second, MaxDelay: time.Minute}
+	u, attempts, _, user := newLockoutTestUsecase(t, lockout)
+	u.identityRepo = &fakeIdentityRepo{identities: []domain.Identity{
+		{UserID: user.ID, Type: domain.IdentityUsername, Key: utils.UsernameKey("alice"), VerifiedAt: verifiedAt()},
+	}}
+
+	if _, err := u.SignIn("alice", "wrong", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := u.SignIn(user.Email, "wrong", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("err = %v", err)
+	}
+	if attempts.failures[user.Email] != 2 || len(attempts.failures) != 1 {
+		t.Fatalf("failures = %v", attempts.failures)
+	}
+}
+
+// サインイン履歴（データエクスポートに含める）に接続元を記録する
+func TestSignInAuditClientInfo(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute, BaseDelay: time.Second, MaxDelay: time.Minute}
+	u, _, audit, user := newLockoutTestUsecase(t, lockout)
+	client := domain.ClientInfo{IP: "192.0.2.1", UserAgent: "test-agent/1.0"}
+
+	if _, err := u.SignIn(user.Email, "correct-password", client); err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(audit.events, func(event domain.AuditEvent) bool { return event.Type == domain.AuditSignIn })
+	if i < 0 {
+		t.Fatalf("sign-in not audited: %v", audit.types())
+	}
+	if event := audit.events[i]; event.IP != client.IP || event.UserAgent != client.UserAgent {
+		t.Fatalf("sign-in event = %+v, want client %+v", event, client)
+	}
+}
+
+// 存在しないアカウントでも実在するアカウントと同じくハッシュを照合し、応答時間で区別できない
+func TestSignInUnknownAccountTiming(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 100, Window: time.Hour, LockDuration: time.Minute, BaseDelay: 0, MaxDelay: 0}
+	u, _, _, user := newLockoutTestUsecase(t, lockout)
+
+	measure := func(email string) time.Duration {
+		fastest := time.Duration(math.MaxInt64)
+		for range 3 {
+			start := time.Now()
+			if _, err := u.SignIn(email, "wrong-password", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCredentials) {
+				t.Fatalf("%s: err = %v, want ErrInvalidCredentials", email, err)
+			}
+			fastest = min(fastest, time.Since(start))
+		}
+		return fastest
+	}
+	known := measure(user.Email)
+	unknown := measure("nobody@example.com")
+	if unknown < known/2 {
+		t.Fatalf("unknown account answered in %v, known account in %v", unknown, known)
+	}
+}
+
+// 再認証で発行するトークンには、使った要素に応じた amr・acr と新しい認証時刻を含める
+func TestStepUp(t *testing.T) {
+	mfa, _, user, _, codes := newEnrolledMFAUsecase(t)
+	hash, err := utils.HashPassword("correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Password = hash
+	u := &authUsecase{
+		userRepo:    mfa.userRepo,
+		factors:     []SecondFactor{mfa},
+		attemptRepo: newFakeAttemptRepo(),
+		auditRepo:   &fakeAuditRepo{},
+		lockout:     config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		code     string
+		wantErr  error
+		wantAMR  []string
+		wantACR  string
+	}{
+		{name: "password", password: "correct-password", wantAMR: []string{utils.AMRPassword}, wantACR: utils.ACRSingleFactor},
+		{name: "code", code: codes[0], wantAMR: []string{utils.AMROTP}, wantACR: utils.ACRSingleFactor},
+		{name: "password and code", password: "correct-password", code: codes[1],
+			wantAMR: []string{utils.AMRPassword, utils.AMROTP, utils.AMRMFA}, wantACR: utils.ACRMultiFactor},
+		{name: "wrong password", password: "wrong", wantErr: domain.ErrInvalidCredentials},
+		{name: "wrong code", password: "correct-password", code: "AAAA-BBBB-CCCC-DDDD", wantErr: domain.ErrInvalidMFACode},
+		{name: "nothing", wantErr: domain.ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now().Truncate(time.Second)
+			token, err := u.StepUp(user.ID, tt.password, tt.code)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := utils.VerifyJWT(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(claims.AMR, tt.wantAMR) || claims.ACR != tt.wantACR {
+				t.Fatalf("amr = %v, acr = %q, want %v %q", claims.AMR, claims.ACR, tt.wantAMR, tt.wantACR)
+			}
+			if claims.AuthTime == nil || claims.AuthTime.Before(start) {
+				t.Fatalf("auth_time = %v, want >= %v", claims.AuthTime, start)
+			}
+		})
+	}
+}
+
+// 第二要素を有効にしていないユーザーはコードで再認証できない
+func TestStepUpCodeNotEnrolled(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute}
+	u, _, _, user := newLockoutTestUsecase(t, lockout)
+	u.factors = []SecondFactor{&mfaUsecase{mfaRepo: newFakeMFARepo()}}
+
+	if _, err := u.StepUp(user.ID, "correct-password", "123456"); !errors.Is(err, domain.ErrMFANotEnrolled) {
+		t.Fatalf("err = %v, want ErrMFANotEnrolled", err)
+	}
+}
+
+// verificationToken 確認メールのリンクと同じトークンを作る
+func verificationToken(t *testing.T, user *domain.User, email string, ttl time.Duration) string {
+	t.Helper()
+	token, err := utils.GeneratePurposeJWT(utils.PurposeVerifyEmail, ttl, utils.Claims{UserID: user.PublicID, Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyEmail(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute}
+	u, _, _, user := newLockoutTestUsecase(t, lockout)
+	u.cfg.RequireVerifiedEmail = true
+
+	// 確認前はサインインできない
+	if _, err := u.SignIn(user.Email, "correct-password", domain.ClientInfo{}); !errors.Is(err, domain.ErrEmailNotVerified) {
+		t.Fatalf("err = %v, want ErrEmailNotVerified", err)
+	}
+
+	invalid := map[string]string{
+		"expired":       verificationToken(t, user, user.Email, -time.Minute),
+		"other address": verificationToken(t, user, "old@example.com", time.Hour), // 発行後にアドレスが変わった
+		"other purpose": func() string {
+			token, _ := utils.GeneratePurposeJWT(utils.PurposeOrgInvite, time.Hour, utils.Claims{UserID: user.PublicID, Email: user.Email})
+			return token
+		}(),
+		"malformed": "not-a-token",
+	}
+	for name, token := range invalid {
+		if err := u.VerifyEmail(token); !errors.Is(err, domain.ErrInvalidToken) {
+			t.Fatalf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+	if user.EmailVerified() {
+		t.Fatal("verified by an invalid token")
+	}
+
+	if err := u.VerifyEmail(verificationToken(t, user, user.Email, time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := u.SignIn(user.Email, "correct-password", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.VerifyJWT(result.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claims.EmailVerified {
+		t.Fatal("email_verified claim not set after verification")
+	}
+}
+
+// 確認メールは未確認のアカウントにだけ再送し、応答は変えない
+func TestResendVerification(t *testing.T) {
+	unverified := &domain.User{Email: "alice@example.com"}
+	verified := &domain.User{Email: "bob@example.com", EmailVerifiedAt: verifiedAt()}
+	u, _, _ := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpOpen}, unverified, verified)
+	mail := u.mailer.(*fakeMailer)
+
+	for _, email := range []string{unverified.Email, verified.Email, "nobody@example.com"} {
+		if err := u.ResendVerification(email); err != nil {
+			t.Fatalf("%s: %v", email, err)
+		}
+	}
+	mail.waitSent(t, unverified.Email)
+	time.Sleep(10 * time.Millisecond)
+	mail.mu.Lock()
+	defer mail.mu.Unlock()
+	if len(mail.sent) != 1 {
+		t.Fatalf("sent = %v, want only %s", mail.sent, unverified.Email)
+	}
+}
+
+// 利用停止中のアカウントはパスワードが正しくてもサインインできない
+func TestSignInSuspended(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute}
+	u, _, _, user := newLockoutTestUsecase(t, lockout)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	user.DisabledAt, user.DisabledUntil = &past, &future
+	if _, err := u.SignIn(user.Email, "correct-password", domain.ClientInfo{}); !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Fatalf("err = %v, want ErrAccountSuspended", err)
+	}
+
+	// 期限を過ぎていればサインインできる
+	user.DisabledUntil = &past
+	if result, err := u.SignIn(user.Email, "correct-password", domain.ClientInfo{}); err != nil || result.Token == "" {
+		t.Fatalf("after expiry: result = %+v, err = %v", result, err)
+	}
+}
+
+// メールアドレスのほか、確認済みのユーザー名・電話番号でもサインインできる
+func TestSignInByIdentifier(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 10, Window: time.Hour, LockDuration: time.Minute}
+	u, _, _, user := newLockoutTestUsecase(t, lockout)
+	bob := u.userRepo.(*fakeUserRepo).add(&domain.User{Email: "bob@example.com", Password: user.Password})
+	u.identityRepo = &fakeIdentityRepo{identities: []domain.Identity{
+		{ID: 1, UserID: user.ID, Type: domain.IdentityUsername, Key: utils.UsernameKey("alice.smith"), VerifiedAt: verifiedAt()},
+		{ID: 2, UserID: user.ID, Type: domain.IdentityPhone, Key: "+819012345678", VerifiedAt: verifiedAt()},
+		// 確認前の番号ではサインインできない
+		{ID: 3, UserID: bob.ID, Type: domain.IdentityPhone, Key: "+819087654321"},
+	}}
+
+	tests := []struct {
+		identifier string
+		wantUser   *domain.User
+	}{
+		{identifier: "Alice@Example.com", wantUser: user},
+		{identifier: "alice.smith", wantUser: user},
+		{identifier: " Alice.Smith ", wantUser: user},
+		{identifier: "+81 90-1234-5678", wantUser: user},
+		{identifier: "+819087654321"},
+		{identifier: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identifier, func(t *testing.T) {
+			result, err := u.SignIn(tt.identifier, "correct-password", domain.ClientInfo{})
+			if tt.wantUser == nil {
+				if !errors.Is(err, domain.ErrInvalidCredentials) {
+					t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := utils.VerifyJWT(result.Token)
+			if err != nil || claims.UserID != tt.wantUser.PublicID {
+				t.Fatalf("claims = %+v, err = %v", claims, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
+	"user-jwt/pkg/utils"
+)
+
+// EmailChangeUsecase メールアドレス変更のユースケース
+type EmailChangeUsecase interface {
+	Request(userID uint, newEmail string) error // 新アドレスに確認リンク、旧アドレスに取り消しリンクを送る
+	Confirm(token string) error                 // 確認リンクで変更を確定
+	Revert(token string) error                  // 取り消しリンクで変更を取り消す（確定済みの場合は元に戻す）
+}
+
+type emailChangeUsecase struct {
+	userRepo       repository.UserRepository
+	changeRepo     repository.EmailChangeRepository
+	revocationRepo repository.TokenRevocationRepository
+	auditRepo      repository.AuditRepository
+	mailer         mailer.Mailer
+	cfg            config.AuthConfig
+}
+
+// NewEmailChangeUsecase EmailChangeUsecaseのコンストラクタ
+func NewEmailChangeUsecase(
+	userRepo repository.UserRepository,
+	changeRepo repository.EmailChangeRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	auditRepo repository.AuditRepository,
+	mailer mailer.Mailer,
+	cfg config.AuthConfig,
+) EmailChangeUsecase {
+	return &emailChangeUsecase{
+		userRepo:       userRepo,
+		changeRepo:     changeRepo,
+		revocationRepo: revocationRepo,
+		auditRepo:      auditRepo,
+		mailer:         mailer,
+		cfg:            cfg,
+	}
+}
+
+func (u *emailChangeUsecase) Request(userID uint, newEmail string) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+	if utils.NormalizeEmail(user.Email) == utils.NormalizeEmail(newEmail) {
+		return domain.ErrSameEmail
+	}
+
+	// 既に使われているアドレスでも応答は変えず、そのアドレスの持ち主にだけ知らせる
+	taken, err := u.userRepo.EmailTaken(newEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		u.sendMail(newEmail, "Email change attempt",
+			"Someone tried to change another account's email address to this one.\n"+
+				"Because an account with this address already exists, nothing was changed.")
+		return nil
+	}
+
+	confirmToken, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	revertToken, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	change := domain.EmailChange{
+		UserID:      user.ID,
+		OldEmail:    user.Email,
+		NewEmail:    newEmail,
+		ConfirmHash: utils.HashToken(confirmToken),
+		RevertHash:  utils.HashToken(revertToken),
+	}
+	if err := u.changeRepo.Save(change, u.cfg.EmailChangeTTL, u.cfg.EmailRevertTTL); err != nil {
+		return err
+	}
+
+	u.sendMail(newEmail, "Confirm your new email address",
+		fmt.Sprintf("Open the link below to use this address for your account. It expires in %s.\n\n%s",
+			u.cfg.EmailChangeTTL, u.link("/user/email/confirm", confirmToken)))
+	u.sendMail(user.Email, "Your email address is being changed",
+		fmt.Sprintf("A request was made to change your account's email address to %s.\n"+
+			"If this wasn't you, open the link below to cancel the change or switch back. It expires in %s.\n\n%s",
+			newEmail, u.cfg.EmailRevertTTL, u.link("/user/email/revert", revertToken)))
+
+	u.audit(domain.AuditEmailChangeRequest, user.ID, map[string]interface{}{"new_email": newEmail})
+	return nil
+}
+
+func (u *emailChangeUsecase) Confirm(token string) error {
+	change, err := u.changeRepo.FindByConfirmToken(utils.HashToken(token))
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return domain.ErrInvalidToken
+	}
+
+	// 確認待ちの間に他のアカウントが同じアドレスを使い始めた場合
+	taken, err := u.userRepo.EmailTaken(change.NewEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return domain.ErrEmailAlreadyExists
+	}
+
+	// 要求後にアドレスが変わっていれば無効
+	updated, err := u.userRepo.UpdateEmail(change.UserID, change.OldEmail, change.NewEmail, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := u.changeRepo.DeletePending(change.UserID); err != nil {
+		return err
+	}
+	if !updated {
+		return domain.ErrInvalidToken
+	}
+
+	if err := u.revokeTokens(change.UserID); err != nil {
+		return err
+	}
+	u.audit(domain.AuditEmailChanged, change.UserID, map[string]interface{}{"old_email": change.OldEmail})
+	return nil
+}
+
+func (u *emailChangeUsecase) Revert(token string) error {
+	tokenHash := utils.HashToken(token)
+	change, err := u.changeRepo.FindByRevertToken(tokenHash)
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return domain.ErrInvalidToken
+	}
+
+	// 未確定なら取り消し、確定済みなら旧アドレスに戻す
+	if err := u.changeRepo.DeletePending(change.UserID); err != nil {
+		return err
+	}
+	if _, err := u.userRepo.UpdateEmail(change.UserID, change.NewEmail, change.OldEmail, time.Now()); err != nil {
+		if errors.Is(err, domain.ErrEmailAlreadyExists) {
+			return u.revertConflict(change)
+		}
+		return err
+	}
+	if err := u.changeRepo.DeleteRevert(tokenHash); err != nil {
+		return err
+	}
+
+	// 乗っ取りの可能性があるため、変更後に発行されたトークンも含めて失効させる
+	if err := u.revokeTokens(change.UserID); err != nil {
+		return err
+	}
+	u.audit(domain.AuditEmailChangeRevert, change.UserID, map[string]interface{}{"new_email": change.NewEmail})
+	return nil
+}
+
+// revertConflict 旧アドレスが他のアカウントに使われていて戻せない場合
+// アドレスは変更後のままになるため、トークンの失効だけは行い、本人に知らせて管理者の確認を待つ
+// 取り消しリンクは残し、管理者が解決した後に期限内であれば再度使えるようにする
+func (u *emailChangeUsecase) revertConflict(change *domain.EmailChange) error {
+	if err := u.revokeTokens(change.UserID); err != nil {
+		return err
+	}
+	u.sendMail(change.OldEmail, "Your email address could not be switched back",
+		fmt.Sprintf("Your account's email address was changed to %s, but it could not be switched back to this address\n"+
+			"because another account now uses it. All sessions have been signed out and an administrator has been notified.\n"+
+			"Please contact support to recover your account.", change.NewEmail))
+	u.audit(domain.AuditEmailRevertBlocked, change.UserID,
+		map[string]interface{}{"old_email": change.OldEmail, "new_email": change.NewEmail})
+	return domain.ErrEmailRevertConflict
+}
+
+// revokeTokens 古いメールアドレスを含むアクセストークンを失効させる
+func (u *emailChangeUsecase) revokeTokens(userID uint) error {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+	return u.revocationRepo.RevokeUserTokens(user.PublicID, time.Now())
+}
+
+func (u *emailChangeUsecase) link(path, token string) string {
+	return u.cfg.APIBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (u *emailChangeUsecase) sendMail(to, subject, body string) {
+	go func() {
+		if err := u.mailer.Send(to, subject, body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+}
+
+func (u *emailChangeUsecase) audit(eventType string, userID uint, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &userID, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+func newTestEmailChangeUsecase(users ...*domain.User) (*emailChangeUsecase, *fakeEmailChangeRepo, *fakeRevocationRepo, *fakeAuditRepo, *fakeMailer) {
+	changeRepo := &fakeEmailChangeRepo{}
+	revocations := newFakeRevocationRepo()
+	auditRepo := &fakeAuditRepo{}
+	mail := &fakeMailer{}
+	u := &emailChangeUsecase{
+		userRepo:       newFakeUserRepo(users...),
+		changeRepo:     changeRepo,
+		revocationRepo: revocations,
+		auditRepo:      auditRepo,
+		mailer:         mail,
+		cfg:            config.AuthConfig{EmailChangeTTL: time.Hour, EmailRevertTTL: 24 * time.Hour},
+	}
+	return u, changeRepo, revocations, auditRepo, mail
+}
+
+// confirmedChange 確定済みの変更と取り消しリンクのトークンを用意する
+func confirmedChange(t *testing.T, u *emailChangeUsecase, changeRepo *fakeEmailChangeRepo, user *domain.User, newEmail string) string {
+	t.Helper()
+	revertToken := "revert-token"
+	change := domain.EmailChange{UserID: user.ID, OldEmail: user.Email, NewEmail: newEmail, ConfirmHash: utils.HashToken("confirm-token"), RevertHash: utils.HashToken(revertToken)}
+	if err := changeRepo.Save(change, time.Hour, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Confirm("confirm-token"); err != nil {
+		t.Fatal(err)
+	}
+	return revertToken
+}
+
+func TestEmailChangeRevert(t *testing.T) {
+	user := &domain.User{Email: "alice@example.com"}
+	u, changeRepo, revocations, _, _ := newTestEmailChangeUsecase(user)
+	revertToken := confirmedChange(t, u, changeRepo, user, "attacker@example.com")
+
+	if err := u.Revert(revertToken); err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "alice@example.com" {
+		t.Fatalf("email = %s", user.Email)
+	}
+	if _, ok := revocations.revokedBefore[user.PublicID]; !ok {
+		t.Fatal("tokens not revoked")
+	}
+	// 取り消しリンクは1回だけ使える
+	if err := u.Revert(revertToken); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("second revert: err = %v", err)
+	}
+}
+
+// 旧アドレスが他のアカウントに使われていて戻せない場合も、トークンは失効させて管理者に知らせる
+func TestEmailChangeRevertConflict(t *testing.T) {
+	user := &domain.User{Email: "alice@example.com"}
+	u, changeRepo, revocations, auditRepo, mail := newTestEmailChangeUsecase(user)
+	revertToken := confirmedChange(t, u, changeRepo, user, "attacker@example.com")
+	delete(revocations.revokedBefore, user.PublicID)
+	u.userRepo.(*fakeUserRepo).add(&domain.User{Email: "Alice@example.com"})
+
+	if err := u.Revert(revertToken); !errors.Is(err, domain.ErrEmailRevertConflict) {
+		t.Fatalf("err = %v, want ErrEmailRevertConflict", err)
+	}
+	if user.Email != "attacker@example.com" {
+		t.Fatalf("email = %s", user.Email)
+	}
+	if _, ok := revocations.revokedBefore[user.PublicID]; !ok {
+		t.Fatal("tokens not revoked")
+	}
+	if !slices.Contains(auditRepo.types(), domain.AuditEmailRevertBlocked) {
+		t.Fatalf("conflict not flagged: %v", auditRepo.types())
+	}
+	mail.waitSent(t, "alice@example.com")
+	// 管理者が解決した後に使えるよう取り消しリンクは残す
+	if change, _ := changeRepo.FindByRevertToken(utils.HashToken(revertToken)); change == nil {
+		t.Fatal("revert link deleted")
+	}
+}
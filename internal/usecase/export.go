@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+// DataExportUsecase 個人データのエクスポートのユースケース
+type DataExportUsecase interface {
+	Start(userID uint) (domain.DataExport, error)              // エクスポートジョブを開始
+	Status(userID uint, exportID string) (ExportStatus, error) // ジョブの状態を取得（完了時はダウンロードURLを含む）
+	Download(token string) ([]byte, error)                     // 署名付きリンクのトークンでアーカイブを取得
+}
+
+// ExportStatus エクスポートジョブの状態とダウンロード先
+type ExportStatus struct {
+	Job         domain.DataExport
+	DownloadURL string // 完了している場合のみ設定（有効期限付き）
+}
+
+type dataExportUsecase struct {
+	userRepo       repository.UserRepository
+	exportRepo     repository.DataExportRepository
+	auditRepo      repository.AuditRepository
+	mfaRepo        repository.MFARepository
+	webAuthnRepo   repository.WebAuthnRepository
+	identityRepo   repository.IdentityRepository
+	orgRepo        repository.OrganizationRepository
+	revocationRepo repository.TokenRevocationRepository
+	cfg            config.AccountConfig
+	apiBaseURL     string
+}
+
+// NewDataExportUsecase DataExportUsecaseのコンストラクタ
+func NewDataExportUsecase(
+	userRepo repository.UserRepository,
+	exportRepo repository.DataExportRepository,
+	auditRepo repository.AuditRepository,
+	mfaRepo repository.MFARepository,
+	webAuthnRepo repository.WebAuthnRepository,
+	identityRepo repository.IdentityRepository,
+	orgRepo repository.OrganizationRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	cfg config.AccountConfig,
+	apiBaseURL string,
+) DataExportUsecase {
+	return &dataExportUsecase{
+		userRepo:       userRepo,
+		exportRepo:     exportRepo,
+		auditRepo:      auditRepo,
+		mfaRepo:        mfaRepo,
+		webAuthnRepo:   webAuthnRepo,
+		identityRepo:   identityRepo,
+		orgRepo:        orgRepo,
+		revocationRepo: revocationRepo,
+		cfg:            cfg,
+		apiBaseURL:     apiBaseURL,
+	}
+}
+
+func (u *dataExportUsecase) Start(userID uint) (domain.DataExport, error) {
+	id, err := utils.NewUUIDv7()
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+
+	now := time.Now()
+	job := domain.DataExport{
+		ID:        id,
+		UserID:    userID,
+		Status:    domain.ExportPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.cfg.ExportTTL),
+	}
+	if err := u.exportRepo.SaveJob(job, u.cfg.ExportTTL); err != nil {
+		return domain.DataExport{}, err
+	}
+
+	go u.run(job)
+	return job, nil
+}
+
+func (u *dataExportUsecase) Status(userID uint, exportID string) (ExportStatus, error) {
+	job, err := u.exportRepo.FindJob(exportID)
+	if err != nil {
+		return ExportStatus{}, err
+	}
+	// 他のユーザーのジョブは存在しないものとして扱う
+	if job == nil || job.UserID != userID {
+		return ExportStatus{}, domain.ErrExportNotFound
+	}
+	if job.Status != domain.ExportCompleted {
+		return ExportStatus{Job: *job}, nil
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return ExportStatus{}, err
+	}
+	if user == nil {
+		return ExportStatus{}, domain.ErrUserNotFound
+	}
+
+	ttl := u.cfg.ExportLinkTTL
+	if remaining := time.Until(job.ExpiresAt); remaining < ttl {
+		ttl = remaining
+	}
+	claims := utils.Claims{UserID: user.PublicID}
+	claims.ID = job.ID
+	token, err := utils.GeneratePurposeJWT(utils.PurposeExport, ttl, claims)
+	if err != nil {
+		return ExportStatus{}, err
+	}
+
+	return ExportStatus{
+		Job:         *job,
+		DownloadURL: u.apiBaseURL + "/user/exports/download?token=" + url.QueryEscape(token),
+	}, nil
+}
+
+func (u *dataExportUsecase) Download(token string) ([]byte, error) {
+	claims, err := utils.VerifyPurposeJWT(token, utils.PurposeExport)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+
+	job, err := u.exportRepo.FindJob(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.Status != domain.ExportCompleted {
+		return nil, domain.ErrExportNotFound
+	}
+	userID, err := u.userRepo.FindIDByPublicID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == 0 || userID != job.UserID {
+		return nil, domain.ErrInvalidToken
+	}
+
+	archive, err := u.exportRepo.FindArchive(job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if archive == nil {
+		return nil, domain.ErrExportNotFound
+	}
+	return archive, nil
+}
+
+// run アーカイブを作成してジョブの状態を更新する
+func (u *dataExportUsecase) run(job domain.DataExport) {
+	ttl := time.Until(job.ExpiresAt)
+	job.Status = domain.ExportRunning
+	if err := u.exportRepo.SaveJob(job, ttl); err != nil {
+		log.Printf("failed to update export job: %v", err)
+	}
+
+	archive, err := u.buildArchive(job.UserID)
+	if err == nil {
+		err = u.exportRepo.SaveArchive(job.ID, archive, ttl)
+	}
+	if err != nil {
+		log.Printf("data export %s failed: %v", job.ID, err)
+		job.Status = domain.ExportFailed
+	} else {
+		now := time.Now()
+		job.Status = domain.ExportCompleted
+		job.CompletedAt = &now
+	}
+
+	if err := u.exportRepo.SaveJob(job, ttl); err != nil {
+		log.Printf("failed to update export job: %v", err)
+	}
+}
+
+// アーカイブに含めるプロフィール
+type exportProfile struct {
+	ID              string     `json:"id"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DisplayName     string     `json:"display_name"`
+	Locale          string     `json:"locale"`
+	Timezone        string     `json:"timezone"`
+	AvatarURL       string     `json:"avatar_url"`
+	Visibility      string     `json:"visibility"`
+	Role            string     `json:"role"`
+	DeletionDueAt   *time.Time `json:"deletion_due_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// アーカイブに含めるサインイン・セッション
+type exportSignIn struct {
+	SignedInAt time.Time       `json:"signed_in_at"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"` // セッション一覧のみ
+	IP         string          `json:"ip,omitempty"`
+	UserAgent  string          `json:"user_agent,omitempty"`
+	Detail     json.RawMessage `json:"detail,omitempty"`
+}
+
+// アーカイブに含める監査イベント
+type exportAuditEvent struct {
+	Type      string          `json:"type"`
+	IP        string          `json:"ip,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Detail    json.RawMessage `json:"detail,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// アーカイブに含める認証手段
+type exportIdentities struct {
+	Email    string                   `json:"email"`
+	Username *exportIdentity          `json:"username,omitempty"`
+	Phone    *exportIdentity          `json:"phone,omitempty"`
+	External []exportExternalIdentity `json:"external"`
+	Password bool                     `json:"password"`
+	TOTP     *exportTOTP              `json:"totp,omitempty"`
+	Passkeys []exportPasskey          `json:"passkeys"`
+}
+
+type exportIdentity struct {
+	Value      string     `json:"value"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+// 連携した外部プロバイダーのアカウント
+type exportExternalIdentity struct {
+	Provider string    `json:"provider"`
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	Email    string    `json:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+type exportTOTP struct {
+	Enabled   bool       `json:"enabled"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+}
+
+// アーカイブに含める組織への所属
+type exportMembership struct {
+	OrganizationID string    `json:"organization_id"`
+	Name           string    `json:"name"`
+	Role           string    `json:"role"`
+	JoinedAt       time.Time `json:"joined_at"`
+}
+
+type exportPasskey struct {
+	Name       string     `json:"name"`
+	AAGUID     string     `json:"aaguid"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// buildArchive ユーザーのデータをJSONファイルにまとめたZIPを作る
+func (u *dataExportUsecase) buildArchive(userID uint) ([]byte, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	events, err := u.auditRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := u.mfaRepo.FindTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := u.webAuthnRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	userIdentities, err := u.identityRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := u.orgRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	revokedBefore, err := u.revocationRepo.RevokedBefore(user.PublicID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := exportProfile{
+		ID:              user.PublicID,
+		Email:           user.Email,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		DisplayName:     user.DisplayName,
+		Locale:          user.Locale,
+		Timezone:        user.Timezone,
+		AvatarURL:       user.AvatarURL,
+		Visibility:      user.Visibility,
+		Role:            user.Role,
+		DeletionDueAt:   user.DeletionDueAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+
+	// サインインの記録のうち、発行したトークンがまだ有効なものをセッションとする
+	history := []exportSignIn{}
+	sessions := []exportSignIn{}
+	auditEvents := make([]exportAuditEvent, 0, len(events))
+	now := time.Now()
+	for _, event := range events {
+		auditEvents = append(auditEvents, exportAuditEvent{
+			Type:      event.Type,
+			IP:        event.IP,
+			UserAgent: event.UserAgent,
+			Detail:    rawDetail(event.Detail),
+			CreatedAt: event.CreatedAt,
+		})
+		if event.Type != domain.AuditSignIn {
+			continue
+		}
+
+		signIn := exportSignIn{
+			SignedInAt: event.CreatedAt,
+			IP:         event.IP,
+			UserAgent:  event.UserAgent,
+			Detail:     rawDetail(event.Detail),
+		}
+		history = append(history, signIn)
+
+		expiresAt := event.CreatedAt.Add(utils.AccessTokenTTL)
+		if expiresAt.After(now) && !event.CreatedAt.Before(revokedBefore) {
+			signIn.ExpiresAt = &expiresAt
+			sessions = append(sessions, signIn)
+		}
+	}
+
+	identities := exportIdentities{
+		Email:    user.Email,
+		Password: user.Password != "",
+		External: []exportExternalIdentity{},
+		Passkeys: make([]exportPasskey, 0, len(passkeys)),
+	}
+	for _, identity := range userIdentities {
+		exported := &exportIdentity{Value: identity.Value, VerifiedAt: identity.VerifiedAt}
+		switch identity.Type {
+		case domain.IdentityUsername:
+			identities.Username = exported
+		case domain.IdentityPhone:
+			identities.Phone = exported
+		default:
+			if strings.HasPrefix(identity.Type, domain.IdentityOIDCPrefix) {
+				issuer, subject := domain.SplitOIDCIdentityKey(identity.Key)
+				identities.External = append(identities.External, exportExternalIdentity{
+					Provider: strings.TrimPrefix(identity.Type, domain.IdentityOIDCPrefix),
+					Issuer:   issuer,
+					Subject:  subject,
+					Email:    identity.Value,
+					LinkedAt: identity.CreatedAt,
+				})
+			}
+		}
+	}
+	if totp != nil {
+		identities.TOTP = &exportTOTP{Enabled: totp.Enabled(), EnabledAt: totp.ConfirmedAt}
+	}
+	for _, cred := range passkeys {
+		identities.Passkeys = append(identities.Passkeys, exportPasskey{
+			Name:       cred.Name,
+			AAGUID:     cred.AAGUID,
+			CreatedAt:  cred.CreatedAt,
+			LastUsedAt: cred.LastUsedAt,
+		})
+	}
+
+	memberships := make([]exportMembership, 0, len(orgs))
+	for _, org := range orgs {
+		memberships = append(memberships, exportMembership{
+			OrganizationID: org.Organization.PublicID,
+			Name:           org.Organization.Name,
+			Role:           org.Role,
+			JoinedAt:       org.JoinedAt,
+		})
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"sessions.json", sessions},
+		{"sign_in_history.json", history},
+		{"audit_events.json", auditEvents},
+		{"identities.json", identities},
+		{"organizations.json", memberships},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rawDetail 監査イベントの補足情報をJSONとしてそのまま埋め込む
+func rawDetail(detail string) json.RawMessage {
+	if detail == "" || !json.Valid([]byte(detail)) {
+		return nil
+	}
+	return json.RawMessage(detail)
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+)
+
+type fakeWebAuthnRepo struct {
+	repository.WebAuthnRepository
+}
+
+func (r *fakeWebAuthnRepo) ListByUser(userID uint) ([]domain.WebAuthnCredential, error) {
+	return nil, nil
+}
+
+// readArchiveFile アーカイブから1ファイルを読み出して v にデコードする
+func readArchiveFile(t *testing.T, archive []byte, name string, v interface{}) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// エクスポートには所属する組織とロールを含める
+func TestExportIncludesOrganizations(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	bob := &domain.User{Email: "bob@example.com"}
+	orgRepo := &fakeOrgRepo{}
+	acme := orgRepo.addOrg("Acme", map[uint]string{})
+	u := &dataExportUsecase{
+		userRepo:       newFakeUserRepo(alice, bob),
+		auditRepo:      &fakeAuditRepo{},
+		mfaRepo:        newFakeMFARepo(),
+		webAuthnRepo:   &fakeWebAuthnRepo{},
+		identityRepo:   &fakeIdentityRepo{},
+		orgRepo:        orgRepo,
+		revocationRepo: newFakeRevocationRepo(),
+	}
+	orgRepo.addMember(acme.ID, alice.ID, domain.OrgRoleAdmin)
+	orgRepo.addMember(acme.ID, bob.ID, domain.OrgRoleMember)
+	orgRepo.addOrg("Other", map[uint]string{bob.ID: domain.OrgRoleAdmin})
+
+	archive, err := u.buildArchive(alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var memberships []exportMembership
+	readArchiveFile(t, archive, "organizations.json", &memberships)
+	if len(memberships) != 1 {
+		t.Fatalf("memberships = %+v", memberships)
+	}
+	if m := memberships[0]; m.OrganizationID != acme.PublicID || m.Name != "Acme" || m.Role != domain.OrgRoleAdmin || m.JoinedAt.IsZero() {
+		t.Fatalf("membership = %+v", m)
+	}
+}
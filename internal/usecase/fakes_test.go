@@ -0,0 +1,654 @@
+package usecase
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	if err := utils.SetJWTKey([]byte(strings.Repeat("k", 32))); err != nil {
+		panic(err)
+	}
+	if err := utils.SetEncryptionKey([]byte(strings.Repeat("e", 32))); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// テスト用のリポジトリ
+// インターフェースを埋め込み、テストで使うメソッドだけを実装する（それ以外を呼ぶと panic する）
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	mu      sync.Mutex
+	users   []*domain.User
+	held    map[string]bool // 論理削除後の保留期間中のアドレス
+	hold    time.Duration   // 論理削除したユーザーのアドレスを再利用できるまでの期間
+	created []domain.User
+}
+
+func newFakeUserRepo(users ...*domain.User) *fakeUserRepo {
+	r := &fakeUserRepo{held: map[string]bool{}}
+	for _, user := range users {
+		r.add(user)
+	}
+	return r
+}
+
+func (r *fakeUserRepo) add(user *domain.User) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.ID == 0 {
+		user.ID = uint(len(r.users) + 1)
+	}
+	if user.PublicID == "" {
+		user.PublicID, _ = utils.NewUUIDv7()
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	if user.ApprovalStatus == "" {
+		user.ApprovalStatus = domain.ApprovalApproved
+	}
+	user.EmailNormalized = utils.NormalizeEmail(user.Email)
+	r.users = append(r.users, user)
+	return user
+}
+
+func (r *fakeUserRepo) FindByEmail(email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	normalized := utils.NormalizeEmail(email)
+	for _, user := range r.users {
+		if user.EmailNormalized == normalized && !user.Deleted() {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// EmailTaken 本物と同じく、論理削除から保留期間が過ぎていないアドレスも使用中とする
+func (r *fakeUserRepo) EmailTaken(email string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	normalized := utils.NormalizeEmail(email)
+	for _, user := range r.users {
+		if user.EmailNormalized == normalized && (!user.Deleted() || user.DeletedAt.Time.After(time.Now().Add(-r.hold))) {
+			return true, nil
+		}
+	}
+	return r.held[normalized], nil
+}
+
+func (r *fakeUserRepo) Create(user domain.User) (domain.User, error) {
+	if existing, _ := r.FindByEmail(user.Email); existing != nil {
+		return domain.User{}, &domain.ConflictError{Field: domain.ConflictFieldEmail}
+	}
+	created := r.add(&user)
+	r.mu.Lock()
+	r.created = append(r.created, *created)
+	r.mu.Unlock()
+	return *created, nil
+}
+
+func (r *fakeUserRepo) FindByID(userID uint) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == userID && !user.Deleted() {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) FindByPublicID(publicID string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.PublicID == publicID && !user.Deleted() {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) FindDeletedByPublicID(publicID string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.PublicID == publicID && user.Deleted() {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepo) FindIDByPublicID(publicID string) (uint, error) {
+	user, _ := r.FindByPublicID(publicID)
+	if user == nil {
+		return 0, nil
+	}
+	return user.ID, nil
+}
+
+func (r *fakeUserRepo) MarkEmailVerified(userID uint, email string, at time.Time) (bool, error) {
+	user, _ := r.FindByID(userID)
+	if user == nil || user.Email != email {
+		return false, nil
+	}
+	user.EmailVerifiedAt = &at
+	return true, nil
+}
+
+func (r *fakeUserRepo) UpdateEmail(userID uint, from, to string, verifiedAt time.Time) (bool, error) {
+	if existing, _ := r.FindByEmail(to); existing != nil && existing.ID != userID {
+		return false, &domain.ConflictError{Field: domain.ConflictFieldEmail}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.ID == userID && user.Email == from {
+			user.Email, user.EmailNormalized, user.EmailVerifiedAt = to, utils.NormalizeEmail(to), &verifiedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// List 作成日時・公開IDの降順で、カーソルより後のユーザーを返す（条件は Before と Deleted のみ扱う）
+func (r *fakeUserRepo) List(filter domain.UserFilter, limit int) ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []domain.User
+	for _, user := range r.users {
+		if user.Deleted() == filter.Deleted {
+			users = append(users, *user)
+		}
+	}
+	slices.SortFunc(users, func(a, b domain.User) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.PublicID, a.PublicID)
+	})
+	if filter.Before != nil {
+		users = slices.DeleteFunc(users, func(user domain.User) bool {
+			c := user.CreatedAt.Compare(filter.Before.CreatedAt)
+			return c > 0 || (c == 0 && user.PublicID >= filter.Before.PublicID)
+		})
+	}
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) ScheduleDeletion(userID uint, dueAt time.Time) error {
+	user, _ := r.FindByID(userID)
+	user.DeletionDueAt = &dueAt
+	return nil
+}
+
+func (r *fakeUserRepo) Suspend(userID uint, at time.Time, reason string, until *time.Time) error {
+	user, _ := r.FindByID(userID)
+	user.DisabledAt, user.DisabledReason, user.DisabledUntil = &at, reason, until
+	return nil
+}
+
+func (r *fakeUserRepo) Reinstate(userID uint) (bool, error) {
+	user, _ := r.FindByID(userID)
+	if user.DisabledAt == nil {
+		return false, nil
+	}
+	user.DisabledAt, user.DisabledReason, user.DisabledUntil = nil, "", nil
+	return true, nil
+}
+
+func (r *fakeUserRepo) SoftDelete(userID uint) (bool, error) {
+	user, _ := r.FindByID(userID)
+	if user == nil {
+		return false, nil
+	}
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return true, nil
+}
+
+// Restore 同じアドレスの有効なユーザーがいれば、一意インデックスと同じく ConflictError を返す
+func (r *fakeUserRepo) Restore(userID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.users, func(user *domain.User) bool { return user.ID == userID && user.Deleted() })
+	if i < 0 {
+		return false, nil
+	}
+	for _, user := range r.users {
+		if user.EmailNormalized == r.users[i].EmailNormalized && !user.Deleted() {
+			return false, &domain.ConflictError{Field: domain.ConflictFieldEmail}
+		}
+	}
+	r.users[i].DeletedAt = gorm.DeletedAt{}
+	return true, nil
+}
+
+func (r *fakeUserRepo) CancelDeletion(userID uint) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeUserRepo) FindDueForDeletion(now time.Time, afterID uint, limit int) ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []domain.User
+	for _, user := range r.users {
+		if user.ID > afterID && user.DeletionDueAt != nil && !user.DeletionDueAt.After(now) && len(due) < limit {
+			due = append(due, *user)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeUserRepo) OrgRoles(userID uint) (map[string]string, error) {
+	return nil, nil
+}
+
+// fakeRevocationRepo ユーザーごとの失効の基準時刻を保持する
+type fakeRevocationRepo struct {
+	revokedBefore map[string]time.Time
+}
+
+func newFakeRevocationRepo() *fakeRevocationRepo {
+	return &fakeRevocationRepo{revokedBefore: map[string]time.Time{}}
+}
+
+func (r *fakeRevocationRepo) RevokeUserTokens(publicID string, at time.Time) error {
+	r.revokedBefore[publicID] = at.Truncate(time.Millisecond)
+	return nil
+}
+
+func (r *fakeRevocationRepo) RevokedBefore(publicID string) (time.Time, error) {
+	return r.revokedBefore[publicID], nil
+}
+
+// fakeEmailChangeRepo 変更要求をメモリに保持する（確認待ちと取り消しリンクを区別しない）
+type fakeEmailChangeRepo struct {
+	changes []domain.EmailChange
+	pending map[uint]bool
+}
+
+func (r *fakeEmailChangeRepo) Save(change domain.EmailChange, confirmTTL, revertTTL time.Duration) error {
+	r.changes = append(r.changes, change)
+	if r.pending == nil {
+		r.pending = map[uint]bool{}
+	}
+	r.pending[change.UserID] = true
+	return nil
+}
+
+func (r *fakeEmailChangeRepo) FindByConfirmToken(tokenHash string) (*domain.EmailChange, error) {
+	for _, change := range r.changes {
+		if change.ConfirmHash == tokenHash && r.pending[change.UserID] {
+			return &change, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeEmailChangeRepo) FindByRevertToken(tokenHash string) (*domain.EmailChange, error) {
+	for _, change := range r.changes {
+		if change.RevertHash == tokenHash {
+			return &change, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeEmailChangeRepo) DeletePending(userID uint) error {
+	delete(r.pending, userID)
+	return nil
+}
+
+func (r *fakeEmailChangeRepo) DeleteRevert(tokenHash string) error {
+	r.changes = slices.DeleteFunc(r.changes, func(change domain.EmailChange) bool { return change.RevertHash == tokenHash })
+	return nil
+}
+
+// fakeStatusRepo userRepo が設定されていれば、本物と同じく無効化されるまで状態をキャッシュする
+type fakeStatusRepo struct {
+	repository.UserStatusRepository
+	userRepo    *fakeUserRepo
+	cache       map[string]domain.UserStatus
+	invalidated []string
+}
+
+func (r *fakeStatusRepo) Get(publicID string) (*domain.UserStatus, error) {
+	if status, ok := r.cache[publicID]; ok {
+		return &status, nil
+	}
+	status := domain.UserStatus{}
+	if user, _ := r.userRepo.FindByPublicID(publicID); user != nil {
+		status = domain.UserStatus{UserID: user.ID, DisabledAt: user.DisabledAt, DisabledUntil: user.DisabledUntil}
+	}
+	if r.cache == nil {
+		r.cache = map[string]domain.UserStatus{}
+	}
+	r.cache[publicID] = status
+	return &status, nil
+}
+
+func (r *fakeStatusRepo) Invalidate(publicID string) error {
+	delete(r.cache, publicID)
+	r.invalidated = append(r.invalidated, publicID)
+	return nil
+}
+
+type fakeIdentityRepo struct {
+	repository.IdentityRepository
+	identities []domain.Identity
+}
+
+func (r *fakeIdentityRepo) FindVerified(identityType, key string) (*domain.Identity, error) {
+	for i := range r.identities {
+		identity := &r.identities[i]
+		if identity.Type == identityType && identity.Key == key && identity.VerifiedAt != nil {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIdentityRepo) Find(userID uint, identityType string) (*domain.Identity, error) {
+	for _, identity := range r.identities {
+		if identity.UserID == userID && identity.Type == identityType {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+// Save 本物と同じくユーザー・種類ごとに置き換え、確認済みの値の重複は ConflictError にする
+func (r *fakeIdentityRepo) Save(identity domain.Identity) (domain.Identity, error) {
+	if identity.Verified() {
+		if existing, _ := r.FindVerified(identity.Type, identity.Key); existing != nil && existing.UserID != identity.UserID {
+			return domain.Identity{}, &domain.ConflictError{Field: domain.ConflictFieldIdentifier}
+		}
+	}
+	r.Delete(identity.UserID, identity.Type)
+	for _, stored := range r.identities {
+		identity.ID = max(identity.ID, stored.ID)
+	}
+	identity.ID++
+	r.identities = append(r.identities, identity)
+	return identity, nil
+}
+
+func (r *fakeIdentityRepo) MarkVerified(id uint, codeHash string, at time.Time) (bool, error) {
+	i := slices.IndexFunc(r.identities, func(identity domain.Identity) bool {
+		return identity.ID == id && identity.CodeHash == codeHash && identity.CodeExpiresAt != nil && identity.CodeExpiresAt.After(at)
+	})
+	if i < 0 {
+		return false, nil
+	}
+	if existing, _ := r.FindVerified(r.identities[i].Type, r.identities[i].Key); existing != nil {
+		return false, &domain.ConflictError{Field: domain.ConflictFieldIdentifier}
+	}
+	r.identities[i].VerifiedAt, r.identities[i].CodeHash, r.identities[i].CodeExpiresAt = &at, "", nil
+	return true, nil
+}
+
+func (r *fakeIdentityRepo) Delete(userID uint, identityType string) (bool, error) {
+	before := len(r.identities)
+	r.identities = slices.DeleteFunc(r.identities, func(identity domain.Identity) bool {
+		return identity.UserID == userID && identity.Type == identityType
+	})
+	return len(r.identities) < before, nil
+}
+
+func (r *fakeIdentityRepo) ListByUser(userID uint) ([]domain.Identity, error) {
+	var identities []domain.Identity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+type fakeOrgRepo struct {
+	repository.OrganizationRepository
+	orgs        []domain.Organization
+	memberships []domain.Membership
+}
+
+// addOrg 組織を作成し、members（ユーザーID → ロール）を所属させる
+func (r *fakeOrgRepo) addOrg(name string, members map[uint]string) domain.Organization {
+	org := domain.Organization{ID: uint(len(r.orgs) + 1), Name: name, CreatedAt: time.Now()}
+	org.PublicID, _ = utils.NewUUIDv7()
+	r.orgs = append(r.orgs, org)
+	for userID, role := range members {
+		r.addMember(org.ID, userID, role)
+	}
+	return org
+}
+
+func (r *fakeOrgRepo) addMember(orgID, userID uint, role string) {
+	r.memberships = append(r.memberships, domain.Membership{
+		ID: uint(len(r.memberships) + 1), OrganizationID: orgID, UserID: userID, Role: role, CreatedAt: time.Now(),
+	})
+}
+
+func (r *fakeOrgRepo) FindByID(id uint) (*domain.Organization, error) {
+	for _, org := range r.orgs {
+		if org.ID == id {
+			return &org, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOrgRepo) FindMembership(orgID, userID uint) (*domain.Membership, error) {
+	for _, membership := range r.memberships {
+		if membership.OrganizationID == orgID && membership.UserID == userID {
+			return &membership, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOrgRepo) ListByUser(userID uint) ([]domain.UserOrganization, error) {
+	var orgs []domain.UserOrganization
+	for _, membership := range r.memberships {
+		if membership.UserID == userID {
+			org, _ := r.FindByID(membership.OrganizationID)
+			orgs = append(orgs, domain.UserOrganization{Organization: *org, Role: membership.Role, JoinedAt: membership.CreatedAt})
+		}
+	}
+	return orgs, nil
+}
+
+func (r *fakeOrgRepo) ListSoleAdminOrgs(userID uint) ([]domain.Organization, error) {
+	var orgs []domain.Organization
+	for _, org := range r.orgs {
+		var isAdmin, otherAdmin, otherMember bool
+		for _, membership := range r.memberships {
+			switch {
+			case membership.OrganizationID != org.ID:
+			case membership.UserID == userID:
+				isAdmin = membership.Role == domain.OrgRoleAdmin
+			case membership.Role == domain.OrgRoleAdmin:
+				otherAdmin = true
+			default:
+				otherMember = true
+			}
+		}
+		if isAdmin && !otherAdmin && otherMember {
+			orgs = append(orgs, org)
+		}
+	}
+	return orgs, nil
+}
+
+type fakeAuditRepo struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+}
+
+func (r *fakeAuditRepo) Record(event domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeAuditRepo) ListByUser(userID uint) ([]domain.AuditEvent, error) {
+	return nil, nil
+}
+
+func (r *fakeAuditRepo) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	types := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func verifiedAt() *time.Time {
+	now := time.Now()
+	return &now
+}
+
+// fakeAttemptRepo 失敗回数・遅延・ロックをメモリに保持する（時間の経過は expireDelays で表す）
+type fakeAttemptRepo struct {
+	failures map[string]int
+	delays   map[string]time.Duration
+	locks    map[string]time.Duration
+}
+
+func newFakeAttemptRepo() *fakeAttemptRepo {
+	return &fakeAttemptRepo{failures: map[string]int{}, delays: map[string]time.Duration{}, locks: map[string]time.Duration{}}
+}
+
+func (r *fakeAttemptRepo) RecordFailure(key string, window time.Duration) (int, error) {
+	r.failures[key]++
+	return r.failures[key], nil
+}
+
+func (r *fakeAttemptRepo) Reset(key string) error {
+	delete(r.failures, key)
+	delete(r.delays, key)
+	delete(r.locks, key)
+	return nil
+}
+
+func (r *fakeAttemptRepo) Delay(key string, duration time.Duration) error {
+	r.delays[key] = duration
+	return nil
+}
+
+func (r *fakeAttemptRepo) Lock(key string, duration time.Duration) error {
+	r.locks[key] = duration
+	return nil
+}
+
+func (r *fakeAttemptRepo) BlockedFor(key string) (time.Duration, bool, error) {
+	if d, ok := r.locks[key]; ok {
+		return d, true, nil
+	}
+	return r.delays[key], false, nil
+}
+
+// expireDelays 遅延の待ち時間が過ぎた状態にする（ロックは残す）
+func (r *fakeAttemptRepo) expireDelays() {
+	r.delays = map[string]time.Duration{}
+}
+
+// fakeDetector 常に同じ判定を返す
+type fakeDetector struct {
+	verdict domain.StuffingVerdict
+}
+
+func (d *fakeDetector) Check(client domain.ClientInfo) domain.StuffingVerdict { return d.verdict }
+func (d *fakeDetector) Observe(client domain.ClientInfo, failed bool)         {}
+func (d *fakeDetector) Thresholds() domain.StuffingThresholds {
+	return domain.StuffingThresholds{}
+}
+func (d *fakeDetector) UpdateThresholds(actorID uint, thresholds domain.StuffingThresholds) error {
+	return nil
+}
+
+type fakeInvitationRepo struct {
+	invitations map[string]domain.Invitation // トークンのハッシュ → 招待
+}
+
+func (r *fakeInvitationRepo) Save(tokenHash string, invitation domain.Invitation) error {
+	r.invitations[tokenHash] = invitation
+	return nil
+}
+
+func (r *fakeInvitationRepo) Take(tokenHash string) (*domain.Invitation, error) {
+	invitation, ok := r.invitations[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.invitations, tokenHash)
+	return &invitation, nil
+}
+
+func (r *fakeInvitationRepo) DeleteByEmail(email string) error {
+	for hash, invitation := range r.invitations {
+		if utils.NormalizeEmail(invitation.Email) == utils.NormalizeEmail(email) {
+			delete(r.invitations, hash)
+		}
+	}
+	return nil
+}
+
+// fakeScreener 指定したアドレスだけを拒否する
+type fakeScreener struct {
+	reject map[string]string // メールアドレス → 拒否理由
+}
+
+func (s *fakeScreener) Screen(email string, client domain.ClientInfo) error {
+	if reason, ok := s.reject[email]; ok {
+		return &domain.ScreeningError{Reason: reason}
+	}
+	return nil
+}
+
+// fakeMailer 送信したメールの宛先を記録する（送信は非同期のため排他する）
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+// waitSent 宛先へのメールが送信されるまで待つ
+func (m *fakeMailer) waitSent(t *testing.T, to string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		m.mu.Lock()
+		sent := slices.Contains(m.sent, to)
+		m.mu.Unlock()
+		if sent {
+			return
+		}
+	}
+	t.Fatalf("no mail sent to %s", to)
+}
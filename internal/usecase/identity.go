@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/sms"
+	"user-jwt/pkg/utils"
+)
+
+// IdentityUsecase メールアドレス以外のサインイン用識別子（ユーザー名・電話番号）のユースケース
+type IdentityUsecase interface {
+	List(userID uint) ([]domain.Identity, error)                       // メールアドレスを含む識別子の一覧
+	SetUsername(userID uint, username string) (domain.Identity, error) // ユーザー名を設定（本人が決めるため確認済みとする）
+	StartPhone(userID uint, phone string) error                        // 電話番号を登録して確認コードをSMSで送る
+	VerifyPhone(userID uint, code string) error                        // 確認コードで電話番号を確認済みにする
+	Remove(userID uint, identityType string) error                     // ユーザー名または電話番号を削除
+}
+
+type identityUsecase struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	attemptRepo  repository.LoginAttemptRepository
+	auditRepo    repository.AuditRepository
+	sms          sms.Sender
+	lockout      config.LockoutConfig
+	cfg          config.AuthConfig
+}
+
+// NewIdentityUsecase IdentityUsecaseのコンストラクタ
+func NewIdentityUsecase(
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	auditRepo repository.AuditRepository,
+	sms sms.Sender,
+	lockout config.LockoutConfig,
+	cfg config.AuthConfig,
+) IdentityUsecase {
+	return &identityUsecase{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		attemptRepo:  attemptRepo,
+		auditRepo:    auditRepo,
+		sms:          sms,
+		lockout:      lockout,
+		cfg:          cfg,
+	}
+}
+
+func (u *identityUsecase) List(userID uint) ([]domain.Identity, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	identities, err := u.identityRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// メールアドレスは users テーブルの値から組み立てる
+	email := domain.Identity{
+		UserID:     user.ID,
+		Type:       domain.IdentityEmail,
+		Value:      user.Email,
+		Key:        strings.ToLower(user.Email),
+		VerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:  user.CreatedAt,
+		UpdatedAt:  user.UpdatedAt,
+	}
+	return append([]domain.Identity{email}, identities...), nil
+}
+
+func (u *identityUsecase) SetUsername(userID uint, username string) (domain.Identity, error) {
+	username = utils.NormalizeUsername(username)
+	if !utils.ValidUsername(username) {
+		return domain.Identity{}, domain.ErrInvalidIdentifier
+	}
+	if utils.ReservedUsername(username) {
+		return domain.Identity{}, domain.ErrUsernameNotAllowed
+	}
+
+	now := time.Now()
+	identity, err := u.identityRepo.Save(domain.Identity{
+		UserID:     userID,
+		Type:       domain.IdentityUsername,
+		Value:      username,
+		Key:        utils.UsernameKey(username),
+		VerifiedAt: &now,
+	})
+	if err != nil {
+		return domain.Identity{}, err
+	}
+
+	u.audit(domain.AuditIdentityAdded, userID, map[string]interface{}{"type": domain.IdentityUsername, "value": username})
+	return identity, nil
+}
+
+func (u *identityUsecase) StartPhone(userID uint, phone string) error {
+	phone = utils.NormalizePhone(phone)
+	if !utils.ValidPhone(phone) {
+		return domain.ErrInvalidIdentifier
+	}
+
+	// 他のアカウントで確認済みの番号は登録できない
+	existing, err := u.identityRepo.FindVerified(domain.IdentityPhone, phone)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.UserID != userID {
+		return domain.ErrIdentifierTaken
+	}
+
+	code, err := generateNumericCode(6)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(u.cfg.PhoneCodeTTL)
+	if _, err := u.identityRepo.Save(domain.Identity{
+		UserID:        userID,
+		Type:          domain.IdentityPhone,
+		Value:         phone,
+		Key:           phone,
+		CodeHash:      phoneCodeHash(phone, code),
+		CodeExpiresAt: &expiresAt,
+	}); err != nil {
+		return err
+	}
+	if err := u.attemptRepo.Reset(phoneAttemptKey(userID)); err != nil {
+		log.Printf("failed to reset phone verification failures: %v", err)
+	}
+
+	go func() {
+		message := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, u.cfg.PhoneCodeTTL)
+		if err := u.sms.Send(phone, message); err != nil {
+			log.Printf("failed to send sms: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (u *identityUsecase) VerifyPhone(userID uint, code string) error {
+	identity, err := u.identityRepo.Find(userID, domain.IdentityPhone)
+	if err != nil {
+		return err
+	}
+	if identity == nil || identity.Verified() || identity.CodeHash == "" {
+		return domain.ErrInvalidCode
+	}
+
+	// 確認コードの総当たりを防ぐ
+	key := phoneAttemptKey(userID)
+	failures, err := u.attemptRepo.RecordFailure(key, u.lockout.Window)
+	if err != nil {
+		return err
+	}
+	if failures > mfaMaxFailures {
+		return domain.ErrInvalidCode
+	}
+
+	verified, err := u.identityRepo.MarkVerified(identity.ID, phoneCodeHash(identity.Value, strings.TrimSpace(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return domain.ErrInvalidCode
+	}
+	if err := u.attemptRepo.Reset(key); err != nil {
+		log.Printf("failed to reset phone verification failures: %v", err)
+	}
+
+	u.audit(domain.AuditIdentityAdded, userID, map[string]interface{}{"type": domain.IdentityPhone, "value": identity.Value})
+	return nil
+}
+
+func (u *identityUsecase) Remove(userID uint, identityType string) error {
+	removed, err := u.identityRepo.Delete(userID, identityType)
+	if err != nil {
+		return err
+	}
+	if removed {
+		u.audit(domain.AuditIdentityRemoved, userID, map[string]interface{}{"type": identityType})
+	}
+	return nil
+}
+
+func (u *identityUsecase) audit(eventType string, userID uint, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &userID, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+func phoneAttemptKey(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+func phoneCodeHash(phone, code string) string {
+	return utils.HashToken(phone + ":" + code)
+}
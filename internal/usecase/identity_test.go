@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+)
+
+// fakeSMSSender 送信したメッセージを宛先ごとに受け取れるようにする
+type fakeSMSSender struct {
+	sent chan [2]string
+}
+
+func (s *fakeSMSSender) Send(to, message string) error {
+	s.sent <- [2]string{to, message}
+	return nil
+}
+
+var smsCodePattern = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+// waitCode 宛先に送られた確認コードを取り出す
+func (s *fakeSMSSender) waitCode(t *testing.T, to string) string {
+	t.Helper()
+	select {
+	case sent := <-s.sent:
+		if sent[0] != to {
+			t.Fatalf("sms sent to %s, want %s", sent[0], to)
+		}
+		return smsCodePattern.FindString(sent[1])
+	case <-time.After(time.Second):
+		t.Fatalf("no sms sent to %s", to)
+		return ""
+	}
+}
+
+func newTestIdentityUsecase(users ...*domain.User) (*identityUsecase, *fakeIdentityRepo, *fakeSMSSender) {
+	identityRepo := &fakeIdentityRepo{}
+	sender := &fakeSMSSender{sent: make(chan [2]string, 10)}
+	u := &identityUsecase{
+		userRepo:     newFakeUserRepo(users...),
+		identityRepo: identityRepo,
+		attemptRepo:  newFakeAttemptRepo(),
+		auditRepo:    &fakeAuditRepo{},
+		sms:          sender,
+		lockout:      config.LockoutConfig{Window: time.Hour},
+		cfg:          config.AuthConfig{PhoneCodeTTL: 10 * time.Minute},
+	}
+	return u, identityRepo, sender
+}
+
+func TestSetUsername(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	bob := &domain.User{Email: "bob@example.com"}
+	u, _, _ := newTestIdentityUsecase(alice, bob)
+	if _, err := u.SetUsername(bob.ID, "john.doe"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		username     string
+		wantErr      error
+		wantConflict bool
+	}{
+		{name: "too short", username: "ab", wantErr: domain.ErrInvalidIdentifier},
+		{name: "non-ASCII lookalike", username: "аlice", wantErr: domain.ErrInvalidIdentifier},
+		{name: "consecutive separators", username: "alice..smith", wantErr: domain.ErrInvalidIdentifier},
+		{name: "reserved", username: "Admin", wantErr: domain.ErrUsernameNotAllowed},
+		{name: "confusable with reserved", username: "adm1n", wantErr: domain.ErrUsernameNotAllowed},
+		// 表記が違っても紛らわしいユーザー名は他人と重複させない
+		{name: "confusable with another user", username: "j0hn_d0e", wantConflict: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := u.SetUsername(alice.ID, tt.username)
+			var conflict *domain.ConflictError
+			if tt.wantConflict {
+				if !errors.As(err, &conflict) || conflict.Field != domain.ConflictFieldIdentifier {
+					t.Fatalf("err = %v, want identifier conflict", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	identity, err := u.SetUsername(alice.ID, " Alice.Smith ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Value != "alice.smith" || !identity.Verified() {
+		t.Fatalf("identity = %+v", identity)
+	}
+}
+
+func TestVerifyPhone(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, identityRepo, sender := newTestIdentityUsecase(alice)
+
+	for _, phone := range []string{"090-1234-5678", "+0123456789", "+1234567890123456"} {
+		if err := u.StartPhone(alice.ID, phone); !errors.Is(err, domain.ErrInvalidIdentifier) {
+			t.Fatalf("StartPhone(%q): err = %v, want ErrInvalidIdentifier", phone, err)
+		}
+	}
+
+	if err := u.StartPhone(alice.ID, "+81 90-1234-5678"); err != nil {
+		t.Fatal(err)
+	}
+	code := sender.waitCode(t, "+819012345678")
+	if err := u.VerifyPhone(alice.ID, "000000"); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("wrong code: err = %v", err)
+	}
+	if identity, _ := identityRepo.FindVerified(domain.IdentityPhone, "+819012345678"); identity != nil {
+		t.Fatal("verified with a wrong code")
+	}
+	if err := u.VerifyPhone(alice.ID, code); err != nil {
+		t.Fatal(err)
+	}
+	if identity, _ := identityRepo.FindVerified(domain.IdentityPhone, "+819012345678"); identity == nil || identity.UserID != alice.ID {
+		t.Fatalf("identity = %+v", identity)
+	}
+	// 確認済みの番号に同じコードは使えない
+	if err := u.VerifyPhone(alice.ID, code); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("reused code: err = %v", err)
+	}
+}
+
+// 確認コードは総当たりできないよう、失敗が続くと正しいコードも受け付けない
+func TestVerifyPhoneAttemptLimit(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, _, sender := newTestIdentityUsecase(alice)
+	if err := u.StartPhone(alice.ID, "+819012345678"); err != nil {
+		t.Fatal(err)
+	}
+	code := sender.waitCode(t, "+819012345678")
+
+	for range mfaMaxFailures {
+		if err := u.VerifyPhone(alice.ID, "000000"); !errors.Is(err, domain.ErrInvalidCode) {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	if err := u.VerifyPhone(alice.ID, code); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("correct code after too many failures: err = %v", err)
+	}
+
+	// コードを送り直すとやり直せる
+	if err := u.StartPhone(alice.ID, "+819012345678"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.VerifyPhone(alice.ID, sender.waitCode(t, "+819012345678")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 他のアカウントで確認済みの番号は登録できない
+func TestStartPhoneTaken(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	bob := &domain.User{Email: "bob@example.com"}
+	u, identityRepo, _ := newTestIdentityUsecase(alice, bob)
+	identityRepo.identities = []domain.Identity{
+		{ID: 1, UserID: bob.ID, Type: domain.IdentityPhone, Key: "+819012345678", VerifiedAt: verifiedAt()},
+	}
+
+	if err := u.StartPhone(alice.ID, "+81 90 1234 5678"); !errors.Is(err, domain.ErrIdentifierTaken) {
+		t.Fatalf("err = %v, want ErrIdentifierTaken", err)
+	}
+	if identities, _ := identityRepo.ListByUser(alice.ID); len(identities) != 0 {
+		t.Fatalf("identities = %+v", identities)
+	}
+	if !slices.ContainsFunc(identityRepo.identities, func(identity domain.Identity) bool { return identity.UserID == bob.ID }) {
+		t.Fatal("bob's phone removed")
+	}
+}
@@ -0,0 +1,324 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+const (
+	// MFAチャレンジトークンの有効期限
+	mfaTokenTTL = 5 * time.Minute
+	// 発行するリカバリーコードの数
+	recoveryCodeCount = 10
+	// MFAコードの連続失敗でロックするまでの回数
+	mfaMaxFailures = 5
+)
+
+// TOTPEnrollment TOTP登録の開始結果
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// MFAUsecase 多要素認証に関するユースケース
+type MFAUsecase interface {
+	EnrollTOTP(userID uint) (TOTPEnrollment, error)                         // シークレットを生成
+	ConfirmTOTP(userID uint, code string) ([]string, error)                 // 初回コードで有効化し、リカバリーコードを返す
+	DisableTOTP(userID uint, code string) error                             // コードを確認して無効化
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)     // リカバリーコードを再発行
+	Verify(mfaToken, code string, client domain.ClientInfo) (string, error) // MFAチャレンジを完了しJWTを返す
+	CodeFactor
+}
+
+// SecondFactor サインイン時に要求する第二要素
+type SecondFactor interface {
+	Method() string                    // 要素の種類（totp / webauthn）
+	Enabled(userID uint) (bool, error) // ユーザーが利用可能か
+}
+
+// CodeFactor コードの入力で検証できる第二要素（ステップアップ認証で使う）
+type CodeFactor interface {
+	SecondFactor
+	VerifyCode(userID uint, code string) error
+}
+
+type mfaUsecase struct {
+	userRepo    repository.UserRepository
+	mfaRepo     repository.MFARepository
+	attemptRepo repository.LoginAttemptRepository
+	auditRepo   repository.AuditRepository
+	lockout     config.LockoutConfig
+	issuer      string
+}
+
+// NewMFAUsecase MFAUsecaseのコンストラクタ
+func NewMFAUsecase(
+	userRepo repository.UserRepository,
+	mfaRepo repository.MFARepository,
+	attemptRepo repository.LoginAttemptRepository,
+	auditRepo repository.AuditRepository,
+	lockout config.LockoutConfig,
+	issuer string,
+) MFAUsecase {
+	return &mfaUsecase{
+		userRepo:    userRepo,
+		mfaRepo:     mfaRepo,
+		attemptRepo: attemptRepo,
+		auditRepo:   auditRepo,
+		lockout:     lockout,
+		issuer:      issuer,
+	}
+}
+
+func (u *mfaUsecase) Method() string {
+	return "totp"
+}
+
+func (u *mfaUsecase) Enabled(userID uint) (bool, error) {
+	cred, err := u.mfaRepo.FindTOTP(userID)
+	if err != nil {
+		return false, err
+	}
+	return cred.Enabled(), nil
+}
+
+func (u *mfaUsecase) VerifyCode(userID uint, code string) error {
+	return u.verifyEnabled(userID, code)
+}
+
+func (u *mfaUsecase) EnrollTOTP(userID uint) (TOTPEnrollment, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return TOTPEnrollment{}, domain.ErrUserNotFound
+	}
+
+	existing, err := u.mfaRepo.FindTOTP(userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if existing.Enabled() {
+		return TOTPEnrollment{}, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	encrypted, err := utils.Encrypt(secret)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	// 未確認の登録があれば上書きする
+	cred := domain.TOTPCredential{UserID: userID, Secret: encrypted}
+	if existing != nil {
+		cred.ID = existing.ID
+		cred.CreatedAt = existing.CreatedAt
+	}
+	if err := u.mfaRepo.SaveTOTP(cred); err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    utils.TOTPURI(u.issuer, user.Email, secret),
+	}, nil
+}
+
+func (u *mfaUsecase) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	cred, err := u.mfaRepo.FindTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil {
+		return nil, domain.ErrMFANotEnrolled
+	}
+	if cred.Enabled() {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	step, ok, err := u.checkTOTP(cred, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	cred.ConfirmedAt = &now
+	cred.LastStep = step
+	if err := u.mfaRepo.SaveTOTP(*cred); err != nil {
+		return nil, err
+	}
+
+	codes, err := u.issueRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit(domain.AuditMFAEnabled, userID)
+	return codes, nil
+}
+
+func (u *mfaUsecase) DisableTOTP(userID uint, code string) error {
+	if err := u.verifyEnabled(userID, code); err != nil {
+		return err
+	}
+	if err := u.mfaRepo.DeleteTOTP(userID); err != nil {
+		return err
+	}
+
+	u.audit(domain.AuditMFADisabled, userID)
+	return nil
+}
+
+func (u *mfaUsecase) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := u.verifyEnabled(userID, code); err != nil {
+		return nil, err
+	}
+	return u.issueRecoveryCodes(userID)
+}
+
+func (u *mfaUsecase) Verify(mfaToken, code string, client domain.ClientInfo) (string, error) {
+	claims, err := utils.VerifyPurposeJWT(mfaToken, utils.PurposeMFA)
+	if err != nil {
+		return "", domain.ErrInvalidCredentials
+	}
+
+	user, err := u.userRepo.FindByPublicID(claims.UserID)
+	if err != nil || user == nil {
+		return "", domain.ErrInvalidCredentials
+	}
+
+	if err := u.verifyEnabled(user.ID, code); err != nil {
+		return "", err
+	}
+
+	amr := append(claims.AMR, utils.AMROTP, utils.AMRMFA)
+	return issueAccessToken(u.userRepo, u.auditRepo, user, utils.NewAuthContext(amr...), client)
+}
+
+// verifyEnabled 有効なMFAに対してTOTPコードまたはリカバリーコードを検証（連続失敗でロック）
+func (u *mfaUsecase) verifyEnabled(userID uint, code string) error {
+	key := strconv.FormatUint(uint64(userID), 10)
+
+	blockedFor, _, err := u.attemptRepo.BlockedFor(key)
+	if err != nil {
+		log.Printf("failed to check mfa lockout: %v", err)
+	}
+	if blockedFor > 0 {
+		return &domain.LockedError{RetryAfter: blockedFor}
+	}
+
+	cred, err := u.mfaRepo.FindTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if !cred.Enabled() {
+		return domain.ErrMFANotEnrolled
+	}
+
+	ok, err := u.checkCode(cred, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		failures, err := u.attemptRepo.RecordFailure(key, u.lockout.Window)
+		if err == nil && failures >= mfaMaxFailures {
+			if err := u.attemptRepo.Lock(key, u.lockout.LockDuration); err != nil {
+				log.Printf("failed to lock mfa: %v", err)
+			}
+		}
+		return domain.ErrInvalidMFACode
+	}
+
+	if err := u.attemptRepo.Reset(key); err != nil {
+		log.Printf("failed to reset mfa failures: %v", err)
+	}
+	return nil
+}
+
+// checkCode 6桁の数字はTOTP、それ以外はリカバリーコードとして検証
+func (u *mfaUsecase) checkCode(cred *domain.TOTPCredential, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 && strings.Trim(code, "0123456789") == "" {
+		step, ok, err := u.checkTOTP(cred, code)
+		if err != nil || !ok {
+			return false, err
+		}
+		// 同じコードの再利用を防ぐ
+		return u.mfaRepo.UpdateTOTPStep(cred.UserID, cred.LastStep, step)
+	}
+
+	used, err := u.mfaRepo.UseRecoveryCode(cred.UserID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil || !used {
+		return false, err
+	}
+	u.audit(domain.AuditRecoveryCodeUsed, cred.UserID)
+	return true, nil
+}
+
+func (u *mfaUsecase) checkTOTP(cred *domain.TOTPCredential, code string) (int64, bool, error) {
+	secret, err := utils.Decrypt(cred.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now(), cred.LastStep)
+	return step, ok, nil
+}
+
+func (u *mfaUsecase) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := u.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (u *mfaUsecase) audit(eventType string, userID uint) {
+	if err := u.auditRepo.Record(domain.AuditEvent{Type: eventType, UserID: &userID}); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// リカバリーコードに使う文字（紛らわしい文字を除く）
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateRecoveryCode "XXXXX-XXXXX" 形式のリカバリーコードを生成
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
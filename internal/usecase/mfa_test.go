@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+type fakeMFARepo struct {
+	totp     map[uint]domain.TOTPCredential
+	recovery map[string]bool // コードのハッシュ → 使用済みか
+}
+
+func newFakeMFARepo() *fakeMFARepo {
+	return &fakeMFARepo{totp: map[uint]domain.TOTPCredential{}, recovery: map[string]bool{}}
+}
+
+func (r *fakeMFARepo) FindTOTP(userID uint) (*domain.TOTPCredential, error) {
+	cred, ok := r.totp[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &cred, nil
+}
+
+func (r *fakeMFARepo) SaveTOTP(cred domain.TOTPCredential) error {
+	r.totp[cred.UserID] = cred
+	return nil
+}
+
+func (r *fakeMFARepo) UpdateTOTPStep(userID uint, lastStep, step int64) (bool, error) {
+	cred, ok := r.totp[userID]
+	if !ok || cred.LastStep != lastStep {
+		return false, nil
+	}
+	cred.LastStep = step
+	r.totp[userID] = cred
+	return true, nil
+}
+
+func (r *fakeMFARepo) DeleteTOTP(userID uint) error {
+	delete(r.totp, userID)
+	r.recovery = map[string]bool{}
+	return nil
+}
+
+func (r *fakeMFARepo) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	r.recovery = map[string]bool{}
+	for _, hash := range codeHashes {
+		r.recovery[hash] = false
+	}
+	return nil
+}
+
+func (r *fakeMFARepo) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	used, ok := r.recovery[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recovery[codeHash] = true
+	return true, nil
+}
+
+// currentTOTP 認証アプリと同じ方法で現在のコードを計算する（RFC 6238, SHA-1, 6桁, 30秒）
+func currentTOTP(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// newEnrolledMFAUsecase TOTPを有効化済みのユーザーを1人持つ mfaUsecase（登録の確認に使ったコードも返す）
+func newEnrolledMFAUsecase(t *testing.T) (*mfaUsecase, *fakeMFARepo, *domain.User, string, []string) {
+	t.Helper()
+	user := &domain.User{Email: "alice@example.com"}
+	mfaRepo := newFakeMFARepo()
+	u := &mfaUsecase{
+		userRepo:    newFakeUserRepo(user),
+		mfaRepo:     mfaRepo,
+		attemptRepo: newFakeAttemptRepo(),
+		auditRepo:   &fakeAuditRepo{},
+		lockout:     config.LockoutConfig{Window: time.Hour, LockDuration: time.Minute},
+		issuer:      "user-jwt",
+	}
+
+	enrollment, err := u.EnrollTOTP(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 登録の確認前はコードを検証しない
+	if err := u.VerifyCode(user.ID, currentTOTP(t, enrollment.Secret)); !errors.Is(err, domain.ErrMFANotEnrolled) {
+		t.Fatalf("verify before confirmation: err = %v", err)
+	}
+	code := currentTOTP(t, enrollment.Secret)
+	codes, err := u.ConfirmTOTP(user.ID, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, mfaRepo, user, code, codes
+}
+
+// 登録の確認に使ったコードを含め、同じタイムステップのコードは2回使えない
+func TestMFAVerifyCodeReplay(t *testing.T) {
+	u, _, user, code, _ := newEnrolledMFAUsecase(t)
+
+	if err := u.VerifyCode(user.ID, code); !errors.Is(err, domain.ErrInvalidMFACode) {
+		t.Fatalf("code used for confirmation accepted again: err = %v", err)
+	}
+}
+
+// 同じコードで同時に検証しても、使用済みステップの条件付き更新に成功した1件だけが通る
+func TestMFAVerifyCodeConcurrentReplay(t *testing.T) {
+	u, mfaRepo, user, code, _ := newEnrolledMFAUsecase(t)
+
+	// 確認時のステップを1つ戻し、確認に使ったコードをもう一度使える状態にする
+	cred := mfaRepo.totp[user.ID]
+	cred.LastStep--
+	mfaRepo.totp[user.ID] = cred
+	stale := cred
+
+	ok, err := u.checkCode(&stale, code)
+	if err != nil || !ok {
+		t.Fatalf("first use: ok = %v, err = %v", ok, err)
+	}
+	// 同時に読み込んだ古い設定では、ステップの検証は通っても更新で競合する
+	ok, err = u.checkCode(&stale, code)
+	if err != nil || ok {
+		t.Fatalf("concurrent reuse: ok = %v, err = %v", ok, err)
+	}
+}
+
+func TestMFARecoveryCodes(t *testing.T) {
+	u, _, user, _, codes := newEnrolledMFAUsecase(t)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("issued %d recovery codes", len(codes))
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "recovery code", code: codes[0]},
+		{name: "already used", code: codes[0], wantErr: domain.ErrInvalidMFACode},
+		// 区切りや大文字小文字の違いは無視する
+		{name: "lowercase without separator", code: strings.ToLower(strings.ReplaceAll(codes[1], "-", ""))},
+		{name: "unknown code", code: "AAAA-BBBB-CCCC-DDDD", wantErr: domain.ErrInvalidMFACode},
+	}
+	for _, tt := range tests {
+		if err := u.VerifyCode(user.ID, tt.code); !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMFAVerifyCodeLockout(t *testing.T) {
+	u, _, user, _, codes := newEnrolledMFAUsecase(t)
+
+	for i := 0; i < mfaMaxFailures; i++ {
+		if err := u.VerifyCode(user.ID, "000000"); !errors.Is(err, domain.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: err = %v", i, err)
+		}
+	}
+	// ロック中は正しいリカバリーコードでも拒否する
+	var locked *domain.LockedError
+	if err := u.VerifyCode(user.ID, codes[0]); !errors.As(err, &locked) {
+		t.Fatalf("err = %v, want LockedError", err)
+	}
+}
+
+func TestMFAVerifyAuditClientInfo(t *testing.T) {
+	u, _, user, _, codes := newEnrolledMFAUsecase(t)
+	mfaToken, err := utils.GeneratePurposeJWT(utils.PurposeMFA, mfaTokenTTL, utils.Claims{UserID: user.PublicID, AMR: []string{utils.AMRPassword}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := domain.ClientInfo{IP: "198.51.100.7", UserAgent: "test-agent/1.0"}
+
+	if _, err := u.Verify(mfaToken, codes[0], client); err != nil {
+		t.Fatal(err)
+	}
+	audit := u.auditRepo.(*fakeAuditRepo)
+	event := audit.events[len(audit.events)-1]
+	if event.Type != domain.AuditSignIn || event.IP != client.IP || event.UserAgent != client.UserAgent {
+		t.Fatalf("last event = %+v, want sign-in from %+v", event, client)
+	}
+}
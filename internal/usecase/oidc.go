@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/oidc"
+	"user-jwt/pkg/utils"
+)
+
+// OIDCUsecase 上流の OpenID Connect プロバイダー（Google・Microsoft など）によるサインイン
+type OIDCUsecase interface {
+	Providers() []string                                                                   // 設定済みのプロバイダー名
+	Start(provider string) (authURL, state string, err error)                              // 認可リクエストのURLと state を発行
+	Callback(provider, state, code string, client domain.ClientInfo) (SignInResult, error) // 認可コードを交換・検証してサインインを完了
+}
+
+type oidcUsecase struct {
+	providers    map[string]*oidc.Provider
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	loginRepo    repository.OIDCLoginRepository
+	factors      []SecondFactor
+	auditRepo    repository.AuditRepository
+	screener     SignUpScreener
+	cfg          config.OIDCConfig
+	autoSignUp   bool
+}
+
+// NewOIDCUsecase OIDCUsecaseのコンストラクタ
+// autoSignUp が true の場合は連携のない外部アカウントで初回サインイン時にアカウントを作成する
+func NewOIDCUsecase(
+	providers map[string]*oidc.Provider,
+	userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	loginRepo repository.OIDCLoginRepository,
+	factors []SecondFactor,
+	auditRepo repository.AuditRepository,
+	screener SignUpScreener,
+	cfg config.OIDCConfig,
+	autoSignUp bool,
+) OIDCUsecase {
+	return &oidcUsecase{
+		providers:    providers,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		loginRepo:    loginRepo,
+		factors:      factors,
+		auditRepo:    auditRepo,
+		screener:     screener,
+		cfg:          cfg,
+		autoSignUp:   autoSignUp,
+	}
+}
+
+func (u *oidcUsecase) Providers() []string {
+	names := make([]string, 0, len(u.cfg.Providers))
+	for _, providerCfg := range u.cfg.Providers {
+		names = append(names, providerCfg.Name)
+	}
+	return names
+}
+
+func (u *oidcUsecase) Start(provider string) (string, string, error) {
+	p, ok := u.providers[provider]
+	if !ok {
+		return "", "", domain.ErrUnknownProvider
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	login := domain.OIDCLogin{Provider: provider, Nonce: nonce, CodeVerifier: codeVerifier}
+	if err := u.loginRepo.Save(utils.HashToken(state), login, u.cfg.StateTTL); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (u *oidcUsecase) Callback(provider, state, code string, client domain.ClientInfo) (SignInResult, error) {
+	p, ok := u.providers[provider]
+	if !ok {
+		return SignInResult{}, domain.ErrUnknownProvider
+	}
+
+	login, err := u.loginRepo.Take(utils.HashToken(state))
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if login == nil || login.Provider != provider {
+		return SignInResult{}, domain.ErrInvalidToken
+	}
+
+	ctx := context.Background()
+	rawIDToken, err := p.Exchange(ctx, code, login.CodeVerifier)
+	if err != nil {
+		log.Printf("oidc %s: %v", provider, err)
+		return SignInResult{}, domain.ErrExternalSignInFailed
+	}
+	claims, err := p.VerifyIDToken(ctx, rawIDToken, login.Nonce)
+	if err != nil {
+		log.Printf("oidc %s: %v", provider, err)
+		return SignInResult{}, domain.ErrExternalSignInFailed
+	}
+
+	user, err := u.resolveUser(provider, claims, client)
+	if err != nil {
+		return SignInResult{}, err
+	}
+	return completeSignIn(u.userRepo, u.auditRepo, user, u.factors, []string{utils.AMRFederated}, client)
+}
+
+// resolveUser 外部アカウント（検証済みの iss と sub の組）に対応するローカルのユーザーを求める
+// 連携がなければ、メールアドレスが一致するアカウントへの連携または新規作成を行う
+func (u *oidcUsecase) resolveUser(provider string, claims oidc.Claims, client domain.ClientInfo) (*domain.User, error) {
+	identityType := domain.OIDCIdentityType(provider)
+	identityKey := domain.OIDCIdentityKey(claims.Issuer, claims.Subject)
+	identity, err := u.identityRepo.FindVerified(identityType, identityKey)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := u.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, domain.ErrExternalAccountNotLinked
+		}
+		return user, nil
+	}
+
+	if claims.Email == "" {
+		return nil, domain.ErrExternalEmailRequired
+	}
+	user, err := u.userRepo.FindByEmail(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// 双方でメールアドレスの所有が確認できている場合に限り連携する
+		// （他人のアドレスで先に作られたアカウントや、未確認のアドレスを名乗る外部アカウントによる乗っ取りを防ぐ）
+		if !claims.EmailVerified || !user.EmailVerified() {
+			return nil, domain.ErrEmailAlreadyExists
+		}
+	} else {
+		if user, err = u.createUser(claims, client); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if _, err := u.identityRepo.Save(domain.Identity{
+		UserID:     user.ID,
+		Type:       identityType,
+		Value:      claims.Email,
+		Key:        identityKey,
+		VerifiedAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+	u.audit(domain.AuditIdentityAdded, user.ID, map[string]interface{}{"type": identityType, "value": claims.Email})
+	return user, nil
+}
+
+// createUser 外部アカウントの情報でパスワードなしのアカウントを作成
+// プロバイダーが確認済みのアドレスに限り、通常の登録と同じ不正利用対策を通す
+func (u *oidcUsecase) createUser(claims oidc.Claims, client domain.ClientInfo) (*domain.User, error) {
+	if !u.autoSignUp {
+		return nil, domain.ErrExternalAccountNotLinked
+	}
+	if !claims.EmailVerified {
+		return nil, domain.ErrExternalEmailUnverified
+	}
+	// 論理削除されたアカウントのアドレスは保留期間中は新規登録に使えない
+	taken, err := u.userRepo.EmailTaken(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, domain.ErrEmailAlreadyExists
+	}
+	if err := screenSignUp(u.screener, u.auditRepo, claims.Email, client); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := domain.User{Email: claims.Email, Role: domain.RoleUser, EmailVerifiedAt: &now}
+	created, err := u.userRepo.Create(user)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (u *oidcUsecase) audit(eventType string, userID uint, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &userID, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/oidc"
+	"user-jwt/pkg/utils"
+)
+
+type fakeOIDCLoginRepo struct {
+	logins map[string]domain.OIDCLogin
+}
+
+func (r *fakeOIDCLoginRepo) Save(stateHash string, login domain.OIDCLogin, ttl time.Duration) error {
+	r.logins[stateHash] = login
+	return nil
+}
+
+func (r *fakeOIDCLoginRepo) Take(stateHash string) (*domain.OIDCLogin, error) {
+	login, ok := r.logins[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.logins, stateHash)
+	return &login, nil
+}
+
+func TestOIDCResolveUser(t *testing.T) {
+	const provider, issuer = "google", "https://accounts.google.com"
+	identityType := domain.OIDCIdentityType(provider)
+
+	tests := []struct {
+		name       string
+		existing   *domain.User      // メールアドレスが一致する既存ユーザー
+		identities []domain.Identity // 既存の連携
+		held       bool              // 論理削除後の保留期間中のアドレス
+		claims     oidc.Claims
+		autoSignUp bool
+		rejected   bool // 不正利用対策で登録を拒否する
+		wantErr    error
+		wantLinked bool // 既存ユーザーに連携する
+		wantCreate bool // 新規にユーザーを作成する
+	}{
+		{
+			name:       "linked identity",
+			existing:   &domain.User{ID: 7, Email: "alice@example.com"},
+			identities: []domain.Identity{{UserID: 7, Type: identityType, Key: domain.OIDCIdentityKey(issuer, "sub-1"), VerifiedAt: verifiedAt()}},
+			// 連携済みなら IDトークンのメールアドレスが変わっていても同じユーザー
+			claims: oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "renamed@example.com"},
+		},
+		{
+			name:       "linked user no longer exists",
+			identities: []domain.Identity{{UserID: 99, Type: identityType, Key: domain.OIDCIdentityKey(issuer, "sub-1"), VerifiedAt: verifiedAt()}},
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com", EmailVerified: true},
+			wantErr:    domain.ErrExternalAccountNotLinked,
+		},
+		{
+			name:       "identity of another provider is not used",
+			existing:   &domain.User{ID: 7, Email: "alice@example.com", EmailVerifiedAt: verifiedAt()},
+			identities: []domain.Identity{{UserID: 7, Type: domain.OIDCIdentityType("microsoft"), Key: domain.OIDCIdentityKey(issuer, "sub-1"), VerifiedAt: verifiedAt()}},
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com", EmailVerified: true},
+			wantLinked: true,
+		},
+		{
+			// sub は発行者の中でしか一意でないため、別のテナントの同じ sub は別の外部アカウント
+			name:       "same subject from another issuer",
+			existing:   &domain.User{ID: 7, Email: "alice@example.com"},
+			identities: []domain.Identity{{UserID: 7, Type: identityType, Key: domain.OIDCIdentityKey("https://login.example.com/other-tenant", "sub-1"), VerifiedAt: verifiedAt()}},
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com", EmailVerified: true},
+			wantErr:    domain.ErrEmailAlreadyExists,
+		},
+		{
+			name:     "missing email",
+			claims:   oidc.Claims{Issuer: issuer, Subject: "sub-1"},
+			wantErr:  domain.ErrExternalEmailRequired,
+			existing: &domain.User{Email: "alice@example.com", EmailVerifiedAt: verifiedAt()},
+		},
+		{
+			name:       "both sides verified",
+			existing:   &domain.User{Email: "alice@example.com", EmailVerifiedAt: verifiedAt()},
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "Alice@Example.com", EmailVerified: true},
+			wantLinked: true,
+		},
+		{
+			name:     "provider email not verified",
+			existing: &domain.User{Email: "alice@example.com", EmailVerifiedAt: verifiedAt()},
+			claims:   oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com", EmailVerified: false},
+			wantErr:  domain.ErrEmailAlreadyExists,
+		},
+		{
+			name:     "local email not verified",
+			existing: &domain.User{Email: "alice@example.com"},
+			claims:   oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com", EmailVerified: true},
+			wantErr:  domain.ErrEmailAlreadyExists,
+		},
+		{
+			name:     "neither side verified",
+			existing: &domain.User{Email: "alice@example.com"},
+			claims:   oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "alice@example.com"},
+			wantErr:  domain.ErrEmailAlreadyExists,
+		},
+		{
+			name:    "new user without auto sign-up",
+			claims:  oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "bob@example.com", EmailVerified: true},
+			wantErr: domain.ErrExternalAccountNotLinked,
+		},
+		{
+			name:       "new user with auto sign-up",
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "bob@example.com", EmailVerified: true},
+			autoSignUp: true,
+			wantCreate: true,
+		},
+		{
+			name:       "new user with unverified email",
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "bob@example.com"},
+			autoSignUp: true,
+			wantErr:    domain.ErrExternalEmailUnverified,
+		},
+		{
+			name:       "new user rejected by screening",
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "bob@example.com", EmailVerified: true},
+			autoSignUp: true,
+			rejected:   true,
+			wantErr:    domain.ErrSignUpRejected,
+		},
+		{
+			name:       "address of a recently deleted account",
+			claims:     oidc.Claims{Issuer: issuer, Subject: "sub-1", Email: "bob@example.com", EmailVerified: true},
+			held:       true,
+			autoSignUp: true,
+			wantErr:    domain.ErrEmailAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := newFakeUserRepo()
+			if tt.existing != nil {
+				userRepo.add(tt.existing)
+			}
+			if tt.held {
+				userRepo.held[utils.NormalizeEmail(tt.claims.Email)] = true
+			}
+			identityRepo := &fakeIdentityRepo{identities: tt.identities}
+			screener := &fakeScreener{reject: map[string]string{}}
+			if tt.rejected {
+				screener.reject[tt.claims.Email] = domain.ScreeningDisposableDomain
+			}
+			u := &oidcUsecase{userRepo: userRepo, identityRepo: identityRepo, auditRepo: &fakeAuditRepo{}, screener: screener, autoSignUp: tt.autoSignUp}
+
+			user, err := u.resolveUser(provider, tt.claims, domain.ClientInfo{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(identityRepo.identities) != len(tt.identities) || len(userRepo.created) != 0 {
+					t.Fatal("rejected sign-in must not link or create accounts")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			switch {
+			case tt.wantLinked:
+				if user.ID != tt.existing.ID {
+					t.Fatalf("linked to user %d, want %d", user.ID, tt.existing.ID)
+				}
+			case tt.wantCreate:
+				if len(userRepo.created) != 1 || user.Email != tt.claims.Email {
+					t.Fatalf("created %v", userRepo.created)
+				}
+				if !user.EmailVerified() || user.Password != "" {
+					t.Fatalf("unexpected new user: %+v", user)
+				}
+			default:
+				if user.ID != tt.identities[0].UserID {
+					t.Fatalf("resolved user %d, want %d", user.ID, tt.identities[0].UserID)
+				}
+				if len(identityRepo.identities) != len(tt.identities) {
+					t.Fatal("already linked identity must not be saved again")
+				}
+				return
+			}
+
+			saved, _ := identityRepo.FindVerified(identityType, domain.OIDCIdentityKey(issuer, tt.claims.Subject))
+			if saved == nil || saved.UserID != user.ID {
+				t.Fatalf("identity not linked: %+v", identityRepo.identities)
+			}
+		})
+	}
+}
+
+// state は1回しか使えず、別のプロバイダーのコールバックにも使えない
+func TestOIDCCallbackState(t *testing.T) {
+	// 認可コードの交換より前に拒否されるため、プロバイダーには接続しない
+	providers := map[string]*oidc.Provider{
+		"google":    oidc.NewProvider(oidc.Config{Name: "google", Issuer: "http://127.0.0.1:1"}, nil),
+		"microsoft": oidc.NewProvider(oidc.Config{Name: "microsoft", Issuer: "http://127.0.0.1:1"}, nil),
+	}
+	loginRepo := &fakeOIDCLoginRepo{logins: map[string]domain.OIDCLogin{
+		utils.HashToken("state-1"): {Provider: "google", Nonce: "n", CodeVerifier: "v"},
+	}}
+	u := NewOIDCUsecase(providers, newFakeUserRepo(), &fakeIdentityRepo{}, loginRepo, nil, &fakeAuditRepo{},
+		&fakeScreener{}, config.OIDCConfig{}, false)
+
+	if _, err := u.Callback("microsoft", "state-1", "code", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("state of another provider: err = %v", err)
+	}
+	// 検証に失敗しても state は消費済み
+	if _, err := u.Callback("google", "state-1", "code", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("reused state: err = %v", err)
+	}
+	if _, err := u.Callback("google", "unknown", "code", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("unknown state: err = %v", err)
+	}
+	if _, err := u.Callback("github", "state-1", "code", domain.ClientInfo{}); !errors.Is(err, domain.ErrUnknownProvider) {
+		t.Fatalf("unknown provider: err = %v", err)
+	}
+}
@@ -0,0 +1,346 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
+	"user-jwt/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OrganizationUsecase 組織・メンバー・招待のユースケース
+// 所属が変わったユーザーのトークンは失効させ、トークンの orgs クレームが古いまま使われないようにする
+type OrganizationUsecase interface {
+	Create(userID uint, name string) (domain.Organization, error)                                                // 組織を作成し、作成者を管理者にする
+	ListForUser(userID uint) ([]domain.UserOrganization, error)                                                  // 所属する組織の一覧
+	ListMembers(userID uint, orgPublicID string) ([]domain.OrgMember, error)                                     // メンバーの一覧（メンバーのみ）
+	RemoveMember(actorID uint, orgPublicID, memberPublicID string) error                                         // メンバーを外す（管理者、または本人の脱退）
+	Invite(actorID uint, orgPublicID, email, role string) (domain.OrgInvitation, error)                          // メールアドレスを招待し、署名付きのリンクを送る（管理者のみ）
+	ListInvitations(actorID uint, orgPublicID string) ([]domain.OrgInvitation, error)                            // 未使用の招待の一覧（管理者のみ）
+	RevokeInvitation(actorID uint, orgPublicID, invitationPublicID string) error                                 // 招待を取り消す（管理者のみ）
+	Accept(userID uint, token string) (domain.Organization, error)                                               // サインイン中のアカウントで招待を受諾する
+	AcceptWithSignUp(token, password string, client domain.ClientInfo) (domain.User, domain.Organization, error) // アカウントを作成して招待を受諾する
+}
+
+type organizationUsecase struct {
+	orgRepo        repository.OrganizationRepository
+	invitationRepo repository.OrgInvitationRepository
+	userRepo       repository.UserRepository
+	revocationRepo repository.TokenRevocationRepository
+	auditRepo      repository.AuditRepository
+	screener       SignUpScreener
+	mailer         mailer.Mailer
+	signUp         config.SignUpConfig
+	cfg            config.OrgConfig
+}
+
+// NewOrganizationUsecase OrganizationUsecaseのコンストラクタ
+func NewOrganizationUsecase(
+	orgRepo repository.OrganizationRepository,
+	invitationRepo repository.OrgInvitationRepository,
+	userRepo repository.UserRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	auditRepo repository.AuditRepository,
+	screener SignUpScreener,
+	mailer mailer.Mailer,
+	signUp config.SignUpConfig,
+	cfg config.OrgConfig,
+) OrganizationUsecase {
+	return &organizationUsecase{
+		orgRepo:        orgRepo,
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		revocationRepo: revocationRepo,
+		auditRepo:      auditRepo,
+		screener:       screener,
+		mailer:         mailer,
+		signUp:         signUp,
+		cfg:            cfg,
+	}
+}
+
+func (u *organizationUsecase) Create(userID uint, name string) (domain.Organization, error) {
+	org, err := u.orgRepo.Create(domain.Organization{Name: name}, userID)
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	// 作成者の既存のトークンには管理者のロールが含まれないだけなので失効させない（次回のサインインから反映）
+	u.audit(domain.AuditOrgCreated, &userID, &userID, "", map[string]interface{}{"organization_id": org.PublicID})
+	return org, nil
+}
+
+func (u *organizationUsecase) ListForUser(userID uint) ([]domain.UserOrganization, error) {
+	return u.orgRepo.ListByUser(userID)
+}
+
+func (u *organizationUsecase) ListMembers(userID uint, orgPublicID string) ([]domain.OrgMember, error) {
+	org, _, err := u.authorize(userID, orgPublicID, false)
+	if err != nil {
+		return nil, err
+	}
+	return u.orgRepo.ListMembers(org.ID)
+}
+
+func (u *organizationUsecase) RemoveMember(actorID uint, orgPublicID, memberPublicID string) error {
+	member, err := u.userRepo.FindByPublicID(memberPublicID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return domain.ErrMemberNotFound
+	}
+
+	// 本人による脱退は管理者でなくてもできる
+	org, _, err := u.authorize(actorID, orgPublicID, member.ID != actorID)
+	if err != nil {
+		return err
+	}
+	if err := u.orgRepo.RemoveMember(org.ID, member.ID); err != nil {
+		return err
+	}
+	// 外したメンバーのトークンに残っている組織のロールを使えなくする
+	if err := u.revocationRepo.RevokeUserTokens(member.PublicID, time.Now()); err != nil {
+		return err
+	}
+
+	u.audit(domain.AuditOrgMemberRemoved, &member.ID, &actorID, member.Email, map[string]interface{}{"organization_id": org.PublicID})
+	return nil
+}
+
+func (u *organizationUsecase) Invite(actorID uint, orgPublicID, email, role string) (domain.OrgInvitation, error) {
+	org, _, err := u.authorize(actorID, orgPublicID, true)
+	if err != nil {
+		return domain.OrgInvitation{}, err
+	}
+
+	// 既にメンバーのアドレスには送らない
+	existing, err := u.userRepo.FindByEmail(email)
+	if err != nil {
+		return domain.OrgInvitation{}, err
+	}
+	if existing != nil {
+		membership, err := u.orgRepo.FindMembership(org.ID, existing.ID)
+		if err != nil {
+			return domain.OrgInvitation{}, err
+		}
+		if membership != nil {
+			return domain.OrgInvitation{}, domain.ErrAlreadyMember
+		}
+	}
+
+	invitation, err := u.invitationRepo.Create(domain.OrgInvitation{
+		OrganizationID: org.ID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      &actorID,
+		ExpiresAt:      time.Now().Add(u.cfg.InvitationTTL),
+	})
+	if err != nil {
+		return domain.OrgInvitation{}, err
+	}
+
+	// リンクには招待の公開IDと宛先を署名して含める（取り消し・使用済みはDBで判定する）
+	token, err := utils.GeneratePurposeJWT(utils.PurposeOrgInvite, u.cfg.InvitationTTL, utils.Claims{
+		Email:            invitation.Email,
+		RegisteredClaims: jwt.RegisteredClaims{ID: invitation.PublicID},
+	})
+	if err != nil {
+		return domain.OrgInvitation{}, err
+	}
+	u.sendMail(invitation.Email, fmt.Sprintf("You're invited to join %s", org.Name),
+		fmt.Sprintf("You have been invited to join %s as %s. Use the link below to accept the invitation.\n"+
+			"It expires in %s.\n\n%s", org.Name, role, u.cfg.InvitationTTL, u.cfg.InvitationURL+"?token="+url.QueryEscape(token)))
+
+	u.audit(domain.AuditOrgInvited, nil, &actorID, invitation.Email,
+		map[string]interface{}{"organization_id": org.PublicID, "invitation_id": invitation.PublicID, "role": role})
+	return invitation, nil
+}
+
+func (u *organizationUsecase) ListInvitations(actorID uint, orgPublicID string) ([]domain.OrgInvitation, error) {
+	org, _, err := u.authorize(actorID, orgPublicID, true)
+	if err != nil {
+		return nil, err
+	}
+	return u.invitationRepo.ListPending(org.ID, time.Now())
+}
+
+func (u *organizationUsecase) RevokeInvitation(actorID uint, orgPublicID, invitationPublicID string) error {
+	org, _, err := u.authorize(actorID, orgPublicID, true)
+	if err != nil {
+		return err
+	}
+	revoked, err := u.invitationRepo.Revoke(org.ID, invitationPublicID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return domain.ErrInvalidInvitation
+	}
+
+	u.audit(domain.AuditOrgInviteRevoked, nil, &actorID, "",
+		map[string]interface{}{"organization_id": org.PublicID, "invitation_id": invitationPublicID})
+	return nil
+}
+
+func (u *organizationUsecase) Accept(userID uint, token string) (domain.Organization, error) {
+	invitation, org, err := u.findInvitation(token)
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return domain.Organization{}, err
+	}
+	if user == nil {
+		return domain.Organization{}, domain.ErrUserNotFound
+	}
+	// リンクを転送されても、宛先のアドレスのアカウントでしか受諾できない
+	if user.EmailNormalized != invitation.EmailNormalized {
+		return domain.Organization{}, domain.ErrInvitationEmailMismatch
+	}
+
+	if _, err := u.invitationRepo.Accept(*invitation, *user, time.Now()); err != nil {
+		return domain.Organization{}, err
+	}
+
+	// 既存のトークンには新しいロールが含まれないため、サインインし直して取得させる
+	if err := u.revocationRepo.RevokeUserTokens(user.PublicID, time.Now()); err != nil {
+		log.Printf("failed to revoke tokens after joining organization: %v", err)
+	}
+	u.audit(domain.AuditOrgMemberAdded, &user.ID, invitation.InvitedBy, user.Email,
+		map[string]interface{}{"organization_id": org.PublicID, "invitation_id": invitation.PublicID, "role": invitation.Role})
+	return *org, nil
+}
+
+func (u *organizationUsecase) AcceptWithSignUp(token, password string, client domain.ClientInfo) (domain.User, domain.Organization, error) {
+	// パスワードハッシュ化（重複時も同じ処理時間になるよう先に行う）
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return domain.User{}, domain.Organization{}, err
+	}
+
+	invitation, org, err := u.findInvitation(token)
+	if err != nil {
+		return domain.User{}, domain.Organization{}, err
+	}
+	// 招待はサインアップの招待を兼ねるが、ドメインの制限・不正利用対策と承認制は通常の登録と同じく適用する
+	if !u.signUp.DomainAllowed(invitation.Email) {
+		return domain.User{}, domain.Organization{}, domain.ErrEmailDomainNotAllowed
+	}
+	if err := screenSignUp(u.screener, u.auditRepo, invitation.Email, client); err != nil {
+		return domain.User{}, domain.Organization{}, err
+	}
+	taken, err := u.userRepo.EmailTaken(invitation.Email)
+	if err != nil {
+		return domain.User{}, domain.Organization{}, err
+	}
+	if taken {
+		return domain.User{}, domain.Organization{}, domain.ErrEmailAlreadyExists
+	}
+
+	// 招待メールのリンクを開けたことでアドレスの所有は確認できている
+	now := time.Now()
+	user := domain.User{
+		Email:           invitation.Email,
+		Password:        hashedPassword,
+		Role:            domain.RoleUser,
+		ApprovalStatus:  domain.ApprovalApproved,
+		EmailVerifiedAt: &now,
+	}
+	if u.signUp.Mode == config.SignUpApproval {
+		user.ApprovalStatus = domain.ApprovalPending
+	}
+	created, err := u.invitationRepo.Accept(*invitation, user, now)
+	if err != nil {
+		return domain.User{}, domain.Organization{}, err
+	}
+
+	event := domain.AuditEvent{
+		Type:      domain.AuditOrgMemberAdded,
+		UserID:    &created.ID,
+		ActorID:   invitation.InvitedBy,
+		Email:     created.Email,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Detail: auditDetail(map[string]interface{}{
+			"organization_id": org.PublicID, "invitation_id": invitation.PublicID, "role": invitation.Role, "sign_up": true,
+		}),
+	}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return created, *org, nil
+}
+
+// authorize 組織を探し、ユーザーがメンバー（requireAdmin の場合は管理者）であることを確認する
+// メンバーでない場合も組織の存在を明かさないよう ErrOrganizationNotFound を返す
+func (u *organizationUsecase) authorize(userID uint, orgPublicID string, requireAdmin bool) (*domain.Organization, *domain.Membership, error) {
+	if !utils.IsUUID(orgPublicID) {
+		return nil, nil, domain.ErrOrganizationNotFound
+	}
+	org, err := u.orgRepo.FindByPublicID(orgPublicID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if org == nil {
+		return nil, nil, domain.ErrOrganizationNotFound
+	}
+	membership, err := u.orgRepo.FindMembership(org.ID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if membership == nil {
+		return nil, nil, domain.ErrOrganizationNotFound
+	}
+	if requireAdmin && membership.Role != domain.OrgRoleAdmin {
+		return nil, nil, domain.ErrOrgAdminRequired
+	}
+	return org, membership, nil
+}
+
+// findInvitation 招待リンクのトークンを検証し、受諾できる招待と組織を返す
+func (u *organizationUsecase) findInvitation(token string) (*domain.OrgInvitation, *domain.Organization, error) {
+	claims, err := utils.VerifyPurposeJWT(token, utils.PurposeOrgInvite)
+	if err != nil || claims.ID == "" {
+		return nil, nil, domain.ErrInvalidInvitation
+	}
+	invitation, err := u.invitationRepo.FindByPublicID(claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if invitation == nil || !invitation.Pending(time.Now()) {
+		return nil, nil, domain.ErrInvalidInvitation
+	}
+
+	org, err := u.orgRepo.FindByID(invitation.OrganizationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if org == nil {
+		return nil, nil, domain.ErrInvalidInvitation
+	}
+	return invitation, org, nil
+}
+
+func (u *organizationUsecase) audit(eventType string, userID, actorID *uint, email string, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: userID, ActorID: actorID, Email: email, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// sendMail メールを非同期で送信
+func (u *organizationUsecase) sendMail(to, subject, body string) {
+	go func() {
+		if err := u.mailer.Send(to, subject, body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeOrgInvitationRepo struct {
+	repository.OrgInvitationRepository
+	userRepo    *fakeUserRepo
+	orgRepo     *fakeOrgRepo
+	invitations []domain.OrgInvitation
+}
+
+func (r *fakeOrgInvitationRepo) FindByPublicID(publicID string) (*domain.OrgInvitation, error) {
+	for _, invitation := range r.invitations {
+		if invitation.PublicID == publicID {
+			return &invitation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOrgInvitationRepo) Accept(invitation domain.OrgInvitation, user domain.User, at time.Time) (domain.User, error) {
+	i := slices.IndexFunc(r.invitations, func(stored domain.OrgInvitation) bool { return stored.ID == invitation.ID })
+	if i < 0 || !r.invitations[i].Pending(at) {
+		return domain.User{}, domain.ErrInvalidInvitation
+	}
+	if user.ID == 0 {
+		created, err := r.userRepo.Create(user)
+		if err != nil {
+			return domain.User{}, err
+		}
+		user = created
+	}
+	if membership, _ := r.orgRepo.FindMembership(invitation.OrganizationID, user.ID); membership != nil {
+		return domain.User{}, &domain.ConflictError{Field: domain.ConflictFieldEmail}
+	}
+	r.invitations[i].AcceptedAt = &at
+	r.invitations[i].AcceptedBy = &user.ID
+	r.orgRepo.addMember(invitation.OrganizationID, user.ID, invitation.Role)
+	return user, nil
+}
+
+type orgTestEnv struct {
+	*organizationUsecase
+	userRepo       *fakeUserRepo
+	orgRepo        *fakeOrgRepo
+	invitationRepo *fakeOrgInvitationRepo
+	revocationRepo *fakeRevocationRepo
+	auditRepo      *fakeAuditRepo
+	screener       *fakeScreener
+	org            domain.Organization
+	admin          *domain.User
+}
+
+// newTestOrganizationUsecase 管理者1人の組織を持つ organizationUsecase
+func newTestOrganizationUsecase(users ...*domain.User) *orgTestEnv {
+	admin := &domain.User{Email: "admin@example.com"}
+	userRepo := newFakeUserRepo(append([]*domain.User{admin}, users...)...)
+	orgRepo := &fakeOrgRepo{}
+	env := &orgTestEnv{
+		userRepo:       userRepo,
+		orgRepo:        orgRepo,
+		invitationRepo: &fakeOrgInvitationRepo{userRepo: userRepo, orgRepo: orgRepo},
+		revocationRepo: newFakeRevocationRepo(),
+		auditRepo:      &fakeAuditRepo{},
+		screener:       &fakeScreener{reject: map[string]string{}},
+		org:            orgRepo.addOrg("Acme", map[uint]string{admin.ID: domain.OrgRoleAdmin}),
+		admin:          admin,
+	}
+	env.organizationUsecase = &organizationUsecase{
+		orgRepo:        orgRepo,
+		invitationRepo: env.invitationRepo,
+		userRepo:       userRepo,
+		revocationRepo: env.revocationRepo,
+		auditRepo:      env.auditRepo,
+		screener:       env.screener,
+		mailer:         &fakeMailer{},
+		cfg:            config.OrgConfig{InvitationTTL: time.Hour},
+	}
+	return env
+}
+
+// invite 宛先への招待を保存し、招待リンクのトークンを返す
+func (e *orgTestEnv) invite(t *testing.T, email string) string {
+	t.Helper()
+	publicID, _ := utils.NewUUIDv7()
+	e.invitationRepo.invitations = append(e.invitationRepo.invitations, domain.OrgInvitation{
+		ID:              uint(len(e.invitationRepo.invitations) + 1),
+		PublicID:        publicID,
+		OrganizationID:  e.org.ID,
+		Email:           email,
+		EmailNormalized: utils.NormalizeEmail(email),
+		Role:            domain.OrgRoleMember,
+		InvitedBy:       &e.admin.ID,
+		ExpiresAt:       time.Now().Add(time.Hour),
+	})
+	token, err := utils.GeneratePurposeJWT(utils.PurposeOrgInvite, time.Hour, utils.Claims{
+		Email:            email,
+		RegisteredClaims: jwt.RegisteredClaims{ID: publicID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// 受諾するとメンバーになり、ロールを含まない既存のトークンは失効する。招待は一度しか使えない
+func TestAcceptInvitation(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	env := newTestOrganizationUsecase(alice)
+	token := env.invite(t, "Alice@Example.com")
+
+	org, err := env.Accept(alice.ID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.PublicID != env.org.PublicID {
+		t.Fatalf("org = %+v, want %+v", org, env.org)
+	}
+	if membership, _ := env.orgRepo.FindMembership(env.org.ID, alice.ID); membership == nil || membership.Role != domain.OrgRoleMember {
+		t.Fatalf("membership = %+v", membership)
+	}
+	if _, ok := env.revocationRepo.revokedBefore[alice.PublicID]; !ok {
+		t.Fatal("tokens not revoked after joining")
+	}
+	if !slices.Contains(env.auditRepo.types(), domain.AuditOrgMemberAdded) {
+		t.Fatalf("join not audited: %v", env.auditRepo.types())
+	}
+
+	if _, err := env.Accept(alice.ID, token); !errors.Is(err, domain.ErrInvalidInvitation) {
+		t.Fatalf("second accept: err = %v, want ErrInvalidInvitation", err)
+	}
+}
+
+// 転送された招待リンクは宛先以外のアカウントでは受諾できない
+func TestAcceptInvitationEmailMismatch(t *testing.T) {
+	bob := &domain.User{Email: "bob@example.com"}
+	env := newTestOrganizationUsecase(bob)
+	token := env.invite(t, "alice@example.com")
+
+	if _, err := env.Accept(bob.ID, token); !errors.Is(err, domain.ErrInvitationEmailMismatch) {
+		t.Fatalf("err = %v, want ErrInvitationEmailMismatch", err)
+	}
+	if membership, _ := env.orgRepo.FindMembership(env.org.ID, bob.ID); membership != nil {
+		t.Fatalf("bob joined: %+v", membership)
+	}
+	if _, ok := env.revocationRepo.revokedBefore[bob.PublicID]; ok {
+		t.Fatal("tokens revoked without joining")
+	}
+}
+
+func TestAcceptInvalidInvitation(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	env := newTestOrganizationUsecase(alice)
+	token := env.invite(t, alice.Email)
+
+	tests := map[string]func(){
+		"revoked": func() { env.invitationRepo.invitations[0].RevokedAt = verifiedAt() },
+		"expired": func() { env.invitationRepo.invitations[0].ExpiresAt = time.Now().Add(-time.Minute) },
+	}
+	for name, prepare := range tests {
+		t.Run(name, func(t *testing.T) {
+			saved := env.invitationRepo.invitations[0]
+			defer func() { env.invitationRepo.invitations[0] = saved }()
+			prepare()
+			if _, err := env.Accept(alice.ID, token); !errors.Is(err, domain.ErrInvalidInvitation) {
+				t.Fatalf("err = %v, want ErrInvalidInvitation", err)
+			}
+		})
+	}
+	if _, err := env.Accept(alice.ID, "not-a-token"); !errors.Is(err, domain.ErrInvalidInvitation) {
+		t.Fatalf("malformed token: err = %v, want ErrInvalidInvitation", err)
+	}
+}
+
+// 招待からのサインアップも通常の登録と同じ不正利用対策を通す
+func TestAcceptWithSignUpScreening(t *testing.T) {
+	env := newTestOrganizationUsecase()
+	client := domain.ClientInfo{IP: "192.0.2.1"}
+	rejectedToken := env.invite(t, "mallory@example.com")
+	env.screener.reject["mallory@example.com"] = domain.ScreeningDisposableDomain
+
+	if _, _, err := env.AcceptWithSignUp(rejectedToken, "password123", client); !errors.Is(err, domain.ErrSignUpRejected) {
+		t.Fatalf("err = %v, want ErrSignUpRejected", err)
+	}
+	if len(env.userRepo.created) != 0 {
+		t.Fatalf("user created despite rejection: %+v", env.userRepo.created)
+	}
+	if !slices.Contains(env.auditRepo.types(), domain.AuditSignUpRejected) {
+		t.Fatalf("rejection not audited: %v", env.auditRepo.types())
+	}
+
+	user, org, err := env.AcceptWithSignUp(env.invite(t, "carol@example.com"), "password123", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.PublicID != env.org.PublicID || user.EmailVerifiedAt == nil || !user.Approved() {
+		t.Fatalf("user = %+v, org = %+v", user, org)
+	}
+	if membership, _ := env.orgRepo.FindMembership(env.org.ID, user.ID); membership == nil {
+		t.Fatal("new user not added to organization")
+	}
+}
+
+// 既存のアカウントはサインインしてから受諾する
+func TestAcceptWithSignUpExistingAccount(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	env := newTestOrganizationUsecase(alice)
+
+	if _, _, err := env.AcceptWithSignUp(env.invite(t, alice.Email), "password123", domain.ClientInfo{}); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Fatalf("err = %v, want ErrEmailAlreadyExists", err)
+	}
+	if membership, _ := env.orgRepo.FindMembership(env.org.ID, alice.ID); membership != nil {
+		t.Fatalf("alice joined without signing in: %+v", membership)
+	}
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
+	"user-jwt/pkg/utils"
+)
+
+// PasswordlessUsecase メールのマジックリンク・ワンタイムコードによるサインイン
+type PasswordlessUsecase interface {
+	Start(email, method string) error                                              // リンクまたはコードを送信
+	VerifyCode(email, code string, client domain.ClientInfo) (SignInResult, error) // ワンタイムコードで検証
+	VerifyToken(token string, client domain.ClientInfo) (SignInResult, error)      // マジックリンクのトークンで検証
+}
+
+type passwordlessUsecase struct {
+	userRepo         repository.UserRepository
+	passwordlessRepo repository.PasswordlessRepository
+	factors          []SecondFactor
+	auditRepo        repository.AuditRepository
+	screener         SignUpScreener
+	mailer           mailer.Mailer
+	cfg              config.PasswordlessConfig
+}
+
+// NewPasswordlessUsecase PasswordlessUsecaseのコンストラクタ
+func NewPasswordlessUsecase(
+	userRepo repository.UserRepository,
+	passwordlessRepo repository.PasswordlessRepository,
+	factors []SecondFactor,
+	auditRepo repository.AuditRepository,
+	screener SignUpScreener,
+	mailer mailer.Mailer,
+	cfg config.PasswordlessConfig,
+) PasswordlessUsecase {
+	return &passwordlessUsecase{
+		userRepo:         userRepo,
+		passwordlessRepo: passwordlessRepo,
+		factors:          factors,
+		auditRepo:        auditRepo,
+		screener:         screener,
+		mailer:           mailer,
+		cfg:              cfg,
+	}
+}
+
+func (u *passwordlessUsecase) Start(email, method string) error {
+	// 未登録のメールアドレスには（自動作成しない限り）何も送らないが、応答は変えない
+	if !u.cfg.AutoSignUp {
+		user, err := u.userRepo.FindByEmail(email)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return nil
+		}
+	}
+
+	challenge := domain.PasswordlessChallenge{Email: email}
+	var subject, body string
+	switch method {
+	case domain.PasswordlessMethodLink:
+		token, err := utils.RandomToken(32)
+		if err != nil {
+			return err
+		}
+		challenge.TokenHash = utils.HashToken(token)
+		subject = "Your sign-in link"
+		body = fmt.Sprintf("Use the link below to sign in. It expires in %s.\n\n%s",
+			u.cfg.TTL, u.cfg.LinkURL+"?token="+url.QueryEscape(token))
+	default:
+		code, err := generateNumericCode(6)
+		if err != nil {
+			return err
+		}
+		challenge.CodeHash = passwordlessCodeHash(email, code)
+		subject = "Your sign-in code"
+		body = fmt.Sprintf("Your sign-in code is %s. It expires in %s.", code, u.cfg.TTL)
+	}
+
+	if err := u.passwordlessRepo.Save(challenge, u.cfg.TTL); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := u.mailer.Send(email, subject, body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (u *passwordlessUsecase) VerifyCode(email, code string, client domain.ClientInfo) (SignInResult, error) {
+	challenge, err := u.passwordlessRepo.Find(email)
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if challenge == nil || challenge.CodeHash == "" {
+		return SignInResult{}, domain.ErrInvalidCode
+	}
+
+	// 照合の前に数えて、同時に送られた推測もすべて上限に含める
+	attempts, err := u.passwordlessRepo.IncrementAttempts(email, u.cfg.AttemptWindow)
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if attempts > u.cfg.MaxAttempts {
+		// 試行回数を超えたコードは無効にする（期間内はコードを再送しても検証できない）
+		if err := u.passwordlessRepo.Delete(email); err != nil {
+			log.Printf("failed to delete passwordless challenge: %v", err)
+		}
+		return SignInResult{}, domain.ErrInvalidCode
+	}
+
+	expected := passwordlessCodeHash(email, strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(challenge.CodeHash)) != 1 {
+		return SignInResult{}, domain.ErrInvalidCode
+	}
+
+	return u.complete(*challenge, client)
+}
+
+func (u *passwordlessUsecase) VerifyToken(token string, client domain.ClientInfo) (SignInResult, error) {
+	challenge, err := u.passwordlessRepo.FindByToken(utils.HashToken(token))
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if challenge == nil {
+		return SignInResult{}, domain.ErrInvalidCode
+	}
+	return u.complete(*challenge, client)
+}
+
+// complete チャレンジを消費してサインインを完了する（必要に応じてアカウントを作成）
+func (u *passwordlessUsecase) complete(challenge domain.PasswordlessChallenge, client domain.ClientInfo) (SignInResult, error) {
+	email := challenge.Email
+	user, err := u.userRepo.FindByEmail(email)
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if user == nil {
+		if !u.cfg.AutoSignUp {
+			return SignInResult{}, domain.ErrInvalidCode
+		}
+		// 論理削除されたアカウントのアドレスは保留期間中は新規登録に使えない
+		taken, err := u.userRepo.EmailTaken(email)
+		if err != nil {
+			return SignInResult{}, err
+		}
+		if taken {
+			return SignInResult{}, domain.ErrInvalidCode
+		}
+		// 通常の登録と同じく不正利用対策の判定を行う（拒否してもチャレンジは残し、Proof of Work を付けて再送できる）
+		if err := screenSignUp(u.screener, u.auditRepo, email, client); err != nil {
+			return SignInResult{}, err
+		}
+	}
+
+	// 同じコード・リンクによる同時の検証は1つだけが成功する
+	consumed, err := u.passwordlessRepo.Consume(challenge)
+	if err != nil {
+		return SignInResult{}, err
+	}
+	if !consumed {
+		return SignInResult{}, domain.ErrInvalidCode
+	}
+	if err := u.passwordlessRepo.ResetAttempts(email); err != nil {
+		log.Printf("failed to reset passwordless attempts: %v", err)
+	}
+
+	if user == nil {
+		// パスワードなしのアカウントを作成（パスワードでのサインインは不可）
+		created, err := u.userRepo.Create(domain.User{Email: email, Role: domain.RoleUser})
+		if err != nil {
+			return SignInResult{}, err
+		}
+		user = &created
+	}
+
+	// メールで届いたコード・リンクを使えたのでアドレスは確認済みとみなす
+	if !user.EmailVerified() {
+		now := time.Now()
+		if _, err := u.userRepo.MarkEmailVerified(user.ID, user.Email, now); err != nil {
+			return SignInResult{}, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	return completeSignIn(u.userRepo, u.auditRepo, user, u.factors, []string{utils.AMROTP}, client)
+}
+
+func passwordlessCodeHash(email, code string) string {
+	return utils.HashToken(strings.ToLower(email) + ":" + code)
+}
+
+// generateNumericCode 指定桁数の数字コードを生成
+func generateNumericCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+// fakePasswordlessRepo チャレンジと試行回数をメモリに保持する
+type fakePasswordlessRepo struct {
+	challenges map[string]domain.PasswordlessChallenge
+	attempts   map[string]int
+}
+
+func newFakePasswordlessRepo() *fakePasswordlessRepo {
+	return &fakePasswordlessRepo{challenges: map[string]domain.PasswordlessChallenge{}, attempts: map[string]int{}}
+}
+
+func (r *fakePasswordlessRepo) Save(challenge domain.PasswordlessChallenge, ttl time.Duration) error {
+	r.challenges[challenge.Email] = challenge
+	return nil
+}
+
+func (r *fakePasswordlessRepo) Find(email string) (*domain.PasswordlessChallenge, error) {
+	challenge, ok := r.challenges[email]
+	if !ok {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
+func (r *fakePasswordlessRepo) FindByToken(tokenHash string) (*domain.PasswordlessChallenge, error) {
+	for _, challenge := range r.challenges {
+		if challenge.TokenHash == tokenHash {
+			return &challenge, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePasswordlessRepo) Consume(challenge domain.PasswordlessChallenge) (bool, error) {
+	if stored, ok := r.challenges[challenge.Email]; !ok || stored != challenge {
+		return false, nil
+	}
+	delete(r.challenges, challenge.Email)
+	return true, nil
+}
+
+func (r *fakePasswordlessRepo) Delete(email string) error {
+	delete(r.challenges, email)
+	return nil
+}
+
+func (r *fakePasswordlessRepo) IncrementAttempts(email string, window time.Duration) (int, error) {
+	r.attempts[email]++
+	return r.attempts[email], nil
+}
+
+func (r *fakePasswordlessRepo) ResetAttempts(email string) error {
+	delete(r.attempts, email)
+	return nil
+}
+
+func newTestPasswordlessUsecase(cfg config.PasswordlessConfig, screener SignUpScreener, users ...*domain.User) (*passwordlessUsecase, *fakePasswordlessRepo, *fakeUserRepo, *fakeAuditRepo) {
+	repo := newFakePasswordlessRepo()
+	userRepo := newFakeUserRepo(users...)
+	auditRepo := &fakeAuditRepo{}
+	u := &passwordlessUsecase{
+		userRepo:         userRepo,
+		passwordlessRepo: repo,
+		auditRepo:        auditRepo,
+		screener:         screener,
+		mailer:           &fakeMailer{},
+		cfg:              cfg,
+	}
+	return u, repo, userRepo, auditRepo
+}
+
+// saveCode 指定したコードのチャレンジを発行した状態にする
+func saveCode(repo *fakePasswordlessRepo, email, code string) {
+	repo.challenges[email] = domain.PasswordlessChallenge{Email: email, CodeHash: passwordlessCodeHash(email, code)}
+}
+
+func TestPasswordlessCodeAttemptLimit(t *testing.T) {
+	user := &domain.User{Email: "alice@example.com", EmailVerifiedAt: verifiedAt()}
+	cfg := config.PasswordlessConfig{MaxAttempts: 3, AttemptWindow: time.Hour}
+	u, repo, _, _ := newTestPasswordlessUsecase(cfg, &fakeScreener{}, user)
+
+	saveCode(repo, user.Email, "123456")
+	for i := 0; i < cfg.MaxAttempts; i++ {
+		if _, err := u.VerifyCode(user.Email, "000000", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCode) {
+			t.Fatalf("attempt %d: err = %v", i, err)
+		}
+	}
+	// 上限を超えると正しいコードでも拒否し、チャレンジを無効にする
+	if _, err := u.VerifyCode(user.Email, "123456", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("err = %v, want ErrInvalidCode", err)
+	}
+	if _, ok := repo.challenges[user.Email]; ok {
+		t.Fatal("challenge not deleted after too many attempts")
+	}
+
+	// コードを再送しても試行回数は数え直さない
+	saveCode(repo, user.Email, "654321")
+	if _, err := u.VerifyCode(user.Email, "654321", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("err after resend = %v, want ErrInvalidCode", err)
+	}
+
+	// 期間が過ぎれば新しいコードで検証できる
+	delete(repo.attempts, user.Email)
+	saveCode(repo, user.Email, "654321")
+	result, err := u.VerifyCode(user.Email, "654321", domain.ClientInfo{})
+	if err != nil || result.Token == "" {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	if _, ok := repo.attempts[user.Email]; ok {
+		t.Fatal("attempts not reset after successful verification")
+	}
+}
+
+func TestPasswordlessCodeSingleUse(t *testing.T) {
+	user := &domain.User{Email: "alice@example.com", EmailVerifiedAt: verifiedAt()}
+	u, repo, _, _ := newTestPasswordlessUsecase(config.PasswordlessConfig{MaxAttempts: 5, AttemptWindow: time.Hour}, &fakeScreener{}, user)
+
+	saveCode(repo, user.Email, "123456")
+	if _, err := u.VerifyCode(user.Email, "123456", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.VerifyCode(user.Email, "123456", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("replayed code: err = %v, want ErrInvalidCode", err)
+	}
+
+	// 検証の途中で別のコードに置き換えられた場合は消費できない
+	saveCode(repo, user.Email, "111111")
+	stale := repo.challenges[user.Email]
+	saveCode(repo, user.Email, "222222")
+	if _, err := u.complete(stale, domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidCode) {
+		t.Fatalf("stale challenge: err = %v, want ErrInvalidCode", err)
+	}
+}
+
+func TestPasswordlessAutoSignUpScreening(t *testing.T) {
+	cfg := config.PasswordlessConfig{MaxAttempts: 5, AttemptWindow: time.Hour, AutoSignUp: true}
+	screener := &fakeScreener{reject: map[string]string{"bot@example.com": domain.ScreeningPoWRequired}}
+	u, repo, userRepo, auditRepo := newTestPasswordlessUsecase(cfg, screener)
+
+	saveCode(repo, "bot@example.com", "123456")
+	_, err := u.VerifyCode("bot@example.com", "123456", domain.ClientInfo{IP: "192.0.2.1"})
+	if !errors.Is(err, domain.ErrSignUpRejected) {
+		t.Fatalf("err = %v, want ErrSignUpRejected", err)
+	}
+	if len(userRepo.created) != 0 {
+		t.Fatalf("created = %v", userRepo.created)
+	}
+	if !slices.Contains(auditRepo.types(), domain.AuditSignUpRejected) {
+		t.Fatalf("rejection not audited: %v", auditRepo.types())
+	}
+	// Proof of Work を付けて送り直せるようチャレンジは残す
+	if _, ok := repo.challenges["bot@example.com"]; !ok {
+		t.Fatal("challenge consumed by a rejected sign-up")
+	}
+
+	token := "magic-link-token"
+	repo.challenges["new@example.com"] = domain.PasswordlessChallenge{Email: "new@example.com", TokenHash: utils.HashToken(token)}
+	result, err := u.VerifyToken(token, domain.ClientInfo{})
+	if err != nil || result.Token == "" {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	if len(userRepo.created) != 1 || userRepo.created[0].Email != "new@example.com" {
+		t.Fatalf("created = %v", userRepo.created)
+	}
+}
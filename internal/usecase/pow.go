@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/screening"
+	"user-jwt/pkg/utils"
+)
+
+// 応答に含められるnonceの最大長
+const maxPoWNonceLength = 64
+
+// PoWChallenge クライアントに解かせるProof of Workのチャレンジ
+// SHA-256(Challenge + ":" + nonce) の先頭 Difficulty ビットが0になる nonce を探し、"Challenge:nonce" を応答とする
+type PoWChallenge struct {
+	Challenge  string
+	Difficulty int
+	ExpiresIn  time.Duration
+}
+
+// ProofOfWorkUsecase 負荷が高いときにクライアントに計算コストを課すチャレンジ
+type ProofOfWorkUsecase interface {
+	Issue() (PoWChallenge, error) // 1回限りのチャレンジを発行
+	ChallengeVerifier             // "challenge:nonce" 形式の応答を検証（検証したチャレンジは使えなくなる）
+}
+
+type proofOfWorkUsecase struct {
+	powRepo repository.ProofOfWorkRepository
+	cfg     config.ScreeningConfig
+}
+
+// NewProofOfWorkUsecase ProofOfWorkUsecaseのコンストラクタ
+func NewProofOfWorkUsecase(powRepo repository.ProofOfWorkRepository, cfg config.ScreeningConfig) ProofOfWorkUsecase {
+	return &proofOfWorkUsecase{powRepo: powRepo, cfg: cfg}
+}
+
+func (u *proofOfWorkUsecase) Issue() (PoWChallenge, error) {
+	challenge, err := utils.RandomToken(16)
+	if err != nil {
+		return PoWChallenge{}, err
+	}
+	if err := u.powRepo.Save(challenge, u.cfg.PoWDifficulty, u.cfg.PoWTTL); err != nil {
+		return PoWChallenge{}, err
+	}
+	return PoWChallenge{Challenge: challenge, Difficulty: u.cfg.PoWDifficulty, ExpiresIn: u.cfg.PoWTTL}, nil
+}
+
+func (u *proofOfWorkUsecase) Verify(response string) bool {
+	i := strings.LastIndexByte(response, ':')
+	if i <= 0 || len(response)-i-1 > maxPoWNonceLength {
+		return false
+	}
+	challenge, nonce := response[:i], response[i+1:]
+
+	difficulty, err := u.powRepo.Take(challenge)
+	if err != nil {
+		log.Printf("failed to load proof-of-work challenge: %v", err)
+		return false
+	}
+	if difficulty == 0 {
+		return false
+	}
+	return screening.CheckProofOfWork(challenge, nonce, difficulty)
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/screening"
+)
+
+type fakePoWRepo struct {
+	challenges map[string]int // チャレンジ → 難易度
+}
+
+func (r *fakePoWRepo) Save(challenge string, difficulty int, ttl time.Duration) error {
+	r.challenges[challenge] = difficulty
+	return nil
+}
+
+func (r *fakePoWRepo) Take(challenge string) (int, error) {
+	difficulty := r.challenges[challenge]
+	delete(r.challenges, challenge)
+	return difficulty, nil
+}
+
+// solvePoW クライアントと同じ方法でチャレンジを解き、"challenge:nonce" 形式の応答を返す
+func solvePoW(t *testing.T, challenge PoWChallenge) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		if screening.CheckProofOfWork(challenge.Challenge, nonce, challenge.Difficulty) {
+			return challenge.Challenge + ":" + nonce
+		}
+	}
+	t.Fatal("no nonce found")
+	return ""
+}
+
+func newTestPoWUsecase() (*proofOfWorkUsecase, *fakePoWRepo) {
+	repo := &fakePoWRepo{challenges: map[string]int{}}
+	return &proofOfWorkUsecase{powRepo: repo, cfg: config.ScreeningConfig{PoWDifficulty: 8, PoWTTL: time.Minute}}, repo
+}
+
+func TestProofOfWorkVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(t *testing.T, challenge PoWChallenge, solved string) string
+		want     bool
+	}{
+		{name: "solved", response: func(t *testing.T, c PoWChallenge, solved string) string { return solved }, want: true},
+		{name: "wrong nonce", response: func(t *testing.T, c PoWChallenge, solved string) string {
+			// 難易度を満たさない nonce を探す
+			for i := 0; ; i++ {
+				if nonce := "x" + strconv.Itoa(i); !screening.CheckProofOfWork(c.Challenge, nonce, c.Difficulty) {
+					return c.Challenge + ":" + nonce
+				}
+			}
+		}},
+		{name: "unknown challenge", response: func(t *testing.T, c PoWChallenge, solved string) string {
+			return "unknown" + solved[len(c.Challenge):]
+		}},
+		{name: "missing separator", response: func(t *testing.T, c PoWChallenge, solved string) string { return c.Challenge }},
+		{name: "empty challenge", response: func(t *testing.T, c PoWChallenge, solved string) string { return ":" + solved }},
+		{name: "nonce too long", response: func(t *testing.T, c PoWChallenge, solved string) string {
+			return c.Challenge + ":" + strings.Repeat("0", maxPoWNonceLength+1)
+		}},
+		{name: "empty", response: func(t *testing.T, c PoWChallenge, solved string) string { return "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newTestPoWUsecase()
+			challenge, err := u.Issue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if challenge.Difficulty != 8 || challenge.ExpiresIn != time.Minute {
+				t.Fatalf("issued %+v", challenge)
+			}
+			if got := u.Verify(tt.response(t, challenge, solvePoW(t, challenge))); got != tt.want {
+				t.Fatalf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 検証したチャレンジは正否にかかわらず使えなくなる
+func TestProofOfWorkSingleUse(t *testing.T) {
+	u, repo := newTestPoWUsecase()
+	challenge, err := u.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := solvePoW(t, challenge)
+
+	if !u.Verify(response) {
+		t.Fatal("first use rejected")
+	}
+	if u.Verify(response) {
+		t.Fatal("solved challenge accepted twice")
+	}
+
+	// 期限切れ（保存されていない）チャレンジも拒否する
+	challenge, _ = u.Issue()
+	response = solvePoW(t, challenge)
+	delete(repo.challenges, challenge.Challenge)
+	if u.Verify(response) {
+		t.Fatal("expired challenge accepted")
+	}
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/mailer"
+	"user-jwt/pkg/utils"
+)
+
+// IssuedInvitation 発行した招待（トークンは発行時にしか取得できない）
+type IssuedInvitation struct {
+	Token string
+	domain.Invitation
+}
+
+// RegistrationUsecase 管理者によるサインアップの招待・承認のユースケース
+type RegistrationUsecase interface {
+	CreateInvitation(actorID uint, email string) (IssuedInvitation, error) // 1回限りの招待を発行（宛先を指定した場合はメールでも送る）
+	Approve(actorID uint, publicID string) error                           // 承認待ちのアカウントを承認して本人に通知
+	Reject(actorID uint, publicID, reason string) error                    // 承認待ちのアカウントを却下して本人に通知（アカウントは論理削除）
+}
+
+type registrationUsecase struct {
+	userRepo       repository.UserRepository
+	invitationRepo repository.InvitationRepository
+	auditRepo      repository.AuditRepository
+	mailer         mailer.Mailer
+	cfg            config.SignUpConfig
+}
+
+// NewRegistrationUsecase RegistrationUsecaseのコンストラクタ
+func NewRegistrationUsecase(
+	userRepo repository.UserRepository,
+	invitationRepo repository.InvitationRepository,
+	auditRepo repository.AuditRepository,
+	mailer mailer.Mailer,
+	cfg config.SignUpConfig,
+) RegistrationUsecase {
+	return &registrationUsecase{
+		userRepo:       userRepo,
+		invitationRepo: invitationRepo,
+		auditRepo:      auditRepo,
+		mailer:         mailer,
+		cfg:            cfg,
+	}
+}
+
+func (u *registrationUsecase) CreateInvitation(actorID uint, email string) (IssuedInvitation, error) {
+	if u.cfg.Mode != config.SignUpInvite {
+		return IssuedInvitation{}, domain.ErrFeatureDisabled
+	}
+
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return IssuedInvitation{}, err
+	}
+	invitation := domain.Invitation{
+		Email:     email,
+		CreatedBy: actorID,
+		ExpiresAt: time.Now().Add(u.cfg.InvitationTTL),
+	}
+	if err := u.invitationRepo.Save(utils.HashToken(token), invitation); err != nil {
+		return IssuedInvitation{}, err
+	}
+
+	if email != "" {
+		u.sendMail(email, "You're invited to create an account",
+			fmt.Sprintf("You have been invited to create an account. Enter the invitation code below when signing up.\n"+
+				"It can be used once and expires in %s.\n\n%s", u.cfg.InvitationTTL, token))
+	}
+
+	detail := map[string]interface{}{"expires_at": invitation.ExpiresAt}
+	if email != "" {
+		detail["email"] = email
+	}
+	event := domain.AuditEvent{Type: domain.AuditInvitationCreated, ActorID: &actorID, Email: email, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return IssuedInvitation{Token: token, Invitation: invitation}, nil
+}
+
+func (u *registrationUsecase) Approve(actorID uint, publicID string) error {
+	user, err := u.findPending(publicID)
+	if err != nil {
+		return err
+	}
+
+	approved, err := u.userRepo.UpdateApproval(user.ID, domain.ApprovalPending, domain.ApprovalApproved)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return domain.ErrUserNotFound
+	}
+
+	u.sendMail(user.Email, "Your account has been approved",
+		"Your account has been approved. You can now sign in.")
+	u.audit(domain.AuditUserApproved, actorID, user, nil)
+	return nil
+}
+
+func (u *registrationUsecase) Reject(actorID uint, publicID, reason string) error {
+	user, err := u.findPending(publicID)
+	if err != nil {
+		return err
+	}
+
+	rejected, err := u.userRepo.UpdateApproval(user.ID, domain.ApprovalPending, domain.ApprovalRejected)
+	if err != nil {
+		return err
+	}
+	if !rejected {
+		return domain.ErrUserNotFound
+	}
+	// 却下したアカウントは残さず、保留期間の後に同じアドレスで申し込み直せるようにする
+	if _, err := u.userRepo.SoftDelete(user.ID); err != nil {
+		return err
+	}
+
+	body := "Unfortunately, your account request was not approved."
+	if reason != "" {
+		body += "\n\nReason: " + reason
+	}
+	u.sendMail(user.Email, "Your account request was not approved", body)
+
+	var detail map[string]interface{}
+	if reason != "" {
+		detail = map[string]interface{}{"reason": reason}
+	}
+	u.audit(domain.AuditUserRejected, actorID, user, detail)
+	return nil
+}
+
+// findPending 承認待ちのユーザーを探す（承認待ちでなければ ErrUserNotFound）
+func (u *registrationUsecase) findPending(publicID string) (*domain.User, error) {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.ApprovalStatus != domain.ApprovalPending {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u *registrationUsecase) audit(eventType string, actorID uint, user *domain.User, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &user.ID, ActorID: &actorID, Email: user.Email}
+	if detail != nil {
+		event.Detail = auditDetail(detail)
+	}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// sendMail メールを非同期で送信
+func (u *registrationUsecase) sendMail(to, subject, body string) {
+	go func() {
+		if err := u.mailer.Send(to, subject, body); err != nil {
+			log.Printf("failed to send mail: %v", err)
+		}
+	}()
+}
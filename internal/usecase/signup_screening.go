@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/ratelimit"
+	"user-jwt/pkg/screening"
+	"user-jwt/pkg/utils"
+)
+
+// SignUpScreener サインアップの不正利用（使い捨てアドレス・ボットによる大量登録）を判定する
+type SignUpScreener interface {
+	Screen(email string, client domain.ClientInfo) error // 拒否する場合は *domain.ScreeningError を返す
+}
+
+type signUpScreener struct {
+	disposable *screening.DomainList
+	mx         screening.MXChecker // nilの場合はMXレコードを確認しない
+	limiter    ratelimit.Limiter
+	pow        ChallengeVerifier
+	cfg        config.ScreeningConfig
+}
+
+// NewSignUpScreener SignUpScreenerのコンストラクタ
+func NewSignUpScreener(
+	disposable *screening.DomainList,
+	mx screening.MXChecker,
+	limiter ratelimit.Limiter,
+	pow ChallengeVerifier,
+	cfg config.ScreeningConfig,
+) SignUpScreener {
+	return &signUpScreener{
+		disposable: disposable,
+		mx:         mx,
+		limiter:    limiter,
+		pow:        pow,
+		cfg:        cfg,
+	}
+}
+
+// Screen 負荷時のProof of Work、使い捨てドメイン、MXレコード、IPごとの頻度の順に確認する
+func (s *signUpScreener) Screen(email string, client domain.ClientInfo) error {
+	if reason := s.checkLoad(client); reason != "" {
+		return &domain.ScreeningError{Reason: reason}
+	}
+
+	normalized := utils.NormalizeEmail(email)
+	emailDomain := normalized[strings.LastIndex(normalized, "@")+1:]
+	if s.disposable.Contains(emailDomain) {
+		return &domain.ScreeningError{Reason: domain.ScreeningDisposableDomain}
+	}
+	if s.mx != nil {
+		hasMX, err := s.mx.HasMX(emailDomain)
+		if err != nil {
+			// 確認できない場合は登録を妨げない
+			log.Printf("failed to check mx record: %v", err)
+		} else if !hasMX {
+			return &domain.ScreeningError{Reason: domain.ScreeningNoMXRecord}
+		}
+	}
+
+	// 他の理由で拒否した試行は数えず、登録に進むものだけを数える
+	if client.IP != "" && !s.allow("signup-velocity:ip:"+client.IP, s.cfg.VelocityIP) {
+		return &domain.ScreeningError{Reason: domain.ScreeningIPVelocity}
+	}
+	return nil
+}
+
+// screenSignUp 新規アカウントの作成前に不正利用対策の判定を行い、拒否した場合は理由を監査ログに残す
+func screenSignUp(screener SignUpScreener, auditRepo repository.AuditRepository, email string, client domain.ClientInfo) error {
+	err := screener.Screen(email, client)
+	var rejected *domain.ScreeningError
+	if errors.As(err, &rejected) {
+		event := domain.AuditEvent{
+			Type:      domain.AuditSignUpRejected,
+			Email:     email,
+			IP:        client.IP,
+			UserAgent: client.UserAgent,
+			Detail:    auditDetail(map[string]interface{}{"reason": rejected.Reason}),
+		}
+		if err := auditRepo.Record(event); err != nil {
+			log.Printf("failed to record audit event: %v", err)
+		}
+	}
+	return err
+}
+
+// checkLoad サインアップ全体の件数が多いときはProof of Workの応答を求める
+func (s *signUpScreener) checkLoad(client domain.ClientInfo) string {
+	if s.allow("signup-load", s.cfg.PoWThreshold) {
+		return ""
+	}
+	if client.ChallengeResponse == "" {
+		return domain.ScreeningPoWRequired
+	}
+	if !s.pow.Verify(client.ChallengeResponse) {
+		return domain.ScreeningPoWInvalid
+	}
+	return ""
+}
+
+func (s *signUpScreener) allow(key string, rule ratelimit.Rule) bool {
+	res, err := s.limiter.Allow(key, rule)
+	if err != nil {
+		// 判定できない場合は登録を妨げない
+		log.Printf("failed to check sign-up velocity: %v", err)
+		return true
+	}
+	return res.Allowed
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/ratelimit"
+	"user-jwt/pkg/screening"
+)
+
+func TestSignUpScreener(t *testing.T) {
+	disposable, err := screening.NewDisposableDomainList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.ScreeningConfig{
+		VelocityIP:    ratelimit.Rule{Limit: 2, Period: time.Hour},
+		PoWThreshold:  ratelimit.Rule{Limit: 1, Period: time.Hour},
+		PoWDifficulty: 8,
+		PoWTTL:        time.Minute,
+	}
+
+	type attempt struct {
+		email      string
+		ip         string
+		solvePoW   bool   // 発行したチャレンジを解いて送る
+		response   string // solvePoW でない場合に送る応答
+		wantReason string // 空なら許可
+	}
+	tests := []struct {
+		name     string
+		attempts []attempt
+	}{
+		{
+			name: "proof of work under load",
+			attempts: []attempt{
+				{email: "a@example.com", ip: "192.0.2.1"},
+				// 全体の件数が上限を超えると応答を求める
+				{email: "b@example.com", ip: "192.0.2.2", wantReason: domain.ScreeningPoWRequired},
+				{email: "b@example.com", ip: "192.0.2.2", response: "bogus:1", wantReason: domain.ScreeningPoWInvalid},
+				{email: "b@example.com", ip: "192.0.2.2", solvePoW: true},
+			},
+		},
+		{
+			name: "disposable domain",
+			attempts: []attempt{
+				{email: "a@10MinuteMail.com", ip: "192.0.2.1", wantReason: domain.ScreeningDisposableDomain},
+				{email: "a@inbox.10minutemail.com", ip: "192.0.2.1", solvePoW: true, wantReason: domain.ScreeningDisposableDomain},
+			},
+		},
+		{
+			name: "no mx record",
+			attempts: []attempt{
+				{email: "a@nomail.example", ip: "192.0.2.1", wantReason: domain.ScreeningNoMXRecord},
+			},
+		},
+		{
+			name: "ip velocity",
+			attempts: []attempt{
+				{email: "a@example.com", ip: "192.0.2.1"},
+				{email: "b@example.com", ip: "192.0.2.1", solvePoW: true},
+				{email: "c@example.com", ip: "192.0.2.1", solvePoW: true, wantReason: domain.ScreeningIPVelocity},
+				{email: "c@example.com", ip: "192.0.2.9", solvePoW: true},
+			},
+		},
+		{
+			// 他の理由で拒否した試行は頻度に数えない
+			name: "rejected attempts are not counted",
+			attempts: []attempt{
+				{email: "a@10minutemail.com", ip: "192.0.2.1", wantReason: domain.ScreeningDisposableDomain},
+				{email: "b@10minutemail.com", ip: "192.0.2.1", solvePoW: true, wantReason: domain.ScreeningDisposableDomain},
+				{email: "c@example.com", ip: "192.0.2.1", solvePoW: true},
+				{email: "d@example.com", ip: "192.0.2.1", solvePoW: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pow, _ := newTestPoWUsecase()
+			s := NewSignUpScreener(disposable, stubMX{"nomail.example": false}, ratelimit.NewMemoryLimiter(), pow, cfg)
+
+			for i, a := range tt.attempts {
+				client := domain.ClientInfo{IP: a.ip, ChallengeResponse: a.response}
+				if a.solvePoW {
+					challenge, err := pow.Issue()
+					if err != nil {
+						t.Fatal(err)
+					}
+					client.ChallengeResponse = solvePoW(t, challenge)
+				}
+
+				err := s.Screen(a.email, client)
+				var rejected *domain.ScreeningError
+				switch {
+				case a.wantReason == "" && err != nil:
+					t.Fatalf("attempt %d: unexpected error: %v", i, err)
+				case a.wantReason != "" && (!errors.As(err, &rejected) || rejected.Reason != a.wantReason):
+					t.Fatalf("attempt %d: err = %v, want %s", i, err, a.wantReason)
+				}
+			}
+		})
+	}
+}
+
+// stubMX 記載のないドメインはMXレコードがあるものとみなす
+type stubMX map[string]bool
+
+func (m stubMX) HasMX(domain string) (bool, error) {
+	hasMX, ok := m[domain]
+	return !ok || hasMX, nil
+}
+
+// 不正利用対策で拒否したサインアップは理由を監査ログに残し、ユーザーを作成しない
+func TestSignUpScreeningRejected(t *testing.T) {
+	u, userRepo, _ := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpOpen})
+	u.screener = &fakeScreener{reject: map[string]string{"a@10minutemail.com": domain.ScreeningDisposableDomain}}
+	audit := u.auditRepo.(*fakeAuditRepo)
+
+	_, err := u.SignUp("a@10minutemail.com", "password123", "", domain.ClientInfo{IP: "192.0.2.1"})
+	if !errors.Is(err, domain.ErrSignUpRejected) {
+		t.Fatalf("err = %v, want ErrSignUpRejected", err)
+	}
+	if len(userRepo.created) != 0 {
+		t.Fatal("rejected sign-up created a user")
+	}
+	if len(audit.events) != 1 || audit.events[0].Type != domain.AuditSignUpRejected ||
+		!strings.Contains(audit.events[0].Detail, domain.ScreeningDisposableDomain) {
+		t.Fatalf("audit events = %+v", audit.events)
+	}
+}
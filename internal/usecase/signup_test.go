@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+)
+
+func newSignUpTestUsecase(signUp config.SignUpConfig, users ...*domain.User) (*authUsecase, *fakeUserRepo, *fakeInvitationRepo) {
+	userRepo := newFakeUserRepo(users...)
+	invitationRepo := &fakeInvitationRepo{invitations: map[string]domain.Invitation{}}
+	u := &authUsecase{
+		userRepo:       userRepo,
+		identityRepo:   &fakeIdentityRepo{},
+		invitationRepo: invitationRepo,
+		attemptRepo:    newFakeAttemptRepo(),
+		auditRepo:      &fakeAuditRepo{},
+		detector:       &fakeDetector{verdict: domain.StuffingAllow},
+		screener:       &fakeScreener{},
+		mailer:         &fakeMailer{},
+		signUp:         signUp,
+	}
+	return u, userRepo, invitationRepo
+}
+
+func TestSignUpModes(t *testing.T) {
+	open := config.SignUpConfig{Mode: config.SignUpOpen}
+	invite := config.SignUpConfig{Mode: config.SignUpInvite}
+	domainOnly := config.SignUpConfig{Mode: config.SignUpDomain, AllowedDomains: []string{"example.com", "xn--bcher-kva.example"}}
+	approval := config.SignUpConfig{Mode: config.SignUpApproval}
+
+	tests := []struct {
+		name           string
+		signUp         config.SignUpConfig
+		invitations    map[string]domain.Invitation // 招待トークン → 招待
+		email          string
+		token          string
+		wantErr        error
+		wantStatus     string // 作成されたユーザーの承認状態
+		wantInvitation bool   // 招待が残っている
+	}{
+		{name: "open", signUp: open, email: "alice@other.example", wantStatus: domain.ApprovalApproved},
+		{name: "open ignores invitation", signUp: open, email: "alice@example.com", token: "unknown", wantStatus: domain.ApprovalApproved},
+
+		{name: "invite without token", signUp: invite, email: "alice@example.com", wantErr: domain.ErrInvalidInvitation},
+		{name: "invite with unknown token", signUp: invite, email: "alice@example.com", token: "unknown", wantErr: domain.ErrInvalidInvitation},
+		{
+			name: "invite for any address", signUp: invite,
+			invitations: map[string]domain.Invitation{"token-1": {}},
+			email:       "alice@example.com", token: "token-1", wantStatus: domain.ApprovalApproved,
+		},
+		{
+			name: "invite for the same address", signUp: invite,
+			invitations: map[string]domain.Invitation{"token-1": {Email: "alice@example.com"}},
+			email:       "Alice@Example.com", token: "token-1", wantStatus: domain.ApprovalApproved,
+		},
+		{
+			// 他のアドレスで使おうとしても招待は消費しない
+			name: "invite for another address", signUp: invite,
+			invitations: map[string]domain.Invitation{"token-1": {Email: "bob@example.com"}},
+			email:       "alice@example.com", token: "token-1", wantErr: domain.ErrInvalidInvitation, wantInvitation: true,
+		},
+		{
+			// 登録済みのアドレスでは招待を消費しない
+			name: "invite for a registered address", signUp: invite,
+			invitations: map[string]domain.Invitation{"token-1": {}},
+			email:       "taken@example.com", token: "token-1", wantErr: domain.ErrEmailAlreadyExists, wantInvitation: true,
+		},
+
+		{name: "allowed domain", signUp: domainOnly, email: "alice@Example.COM", wantStatus: domain.ApprovalApproved},
+		{name: "allowed internationalized domain", signUp: domainOnly, email: "alice@Bücher.example", wantStatus: domain.ApprovalApproved},
+		{name: "other domain", signUp: domainOnly, email: "alice@other.example", wantErr: domain.ErrEmailDomainNotAllowed},
+		{name: "subdomain of allowed domain", signUp: domainOnly, email: "alice@mail.example.com", wantErr: domain.ErrEmailDomainNotAllowed},
+		{name: "allowed domain as subdomain", signUp: domainOnly, email: "alice@example.com.evil.example", wantErr: domain.ErrEmailDomainNotAllowed},
+
+		{name: "approval", signUp: approval, email: "alice@example.com", wantStatus: domain.ApprovalPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, userRepo, invitationRepo := newSignUpTestUsecase(tt.signUp, &domain.User{Email: "taken@example.com"})
+			for token, invitation := range tt.invitations {
+				invitation.ExpiresAt = time.Now().Add(time.Hour)
+				invitationRepo.invitations[utils.HashToken(token)] = invitation
+			}
+
+			user, err := u.SignUp(tt.email, "password123", tt.token, domain.ClientInfo{})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(userRepo.created) != 0 {
+					t.Fatal("rejected sign-up created a user")
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if user.ApprovalStatus != tt.wantStatus || user.Role != domain.RoleUser {
+					t.Fatalf("created %+v", user)
+				}
+			}
+			if got := len(invitationRepo.invitations) > 0; got != tt.wantInvitation {
+				t.Fatalf("invitation remaining = %v, want %v", got, tt.wantInvitation)
+			}
+		})
+	}
+}
+
+// 招待は1回しか使えない
+func TestSignUpInvitationSingleUse(t *testing.T) {
+	u, _, invitationRepo := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpInvite})
+	invitationRepo.invitations[utils.HashToken("token-1")] = domain.Invitation{ExpiresAt: time.Now().Add(time.Hour)}
+
+	if _, err := u.SignUp("alice@example.com", "password123", "token-1", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.SignUp("bob@example.com", "password123", "token-1", domain.ClientInfo{}); !errors.Is(err, domain.ErrInvalidInvitation) {
+		t.Fatalf("err = %v, want ErrInvalidInvitation", err)
+	}
+}
+
+// 承認待ちのアカウントはパスワードが正しくてもサインインできない
+func TestSignUpApprovalBlocksSignIn(t *testing.T) {
+	u, userRepo, _ := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpApproval})
+
+	if _, err := u.SignUp("alice@example.com", "password123", "", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.SignIn("alice@example.com", "password123", domain.ClientInfo{}); !errors.Is(err, domain.ErrApprovalPending) {
+		t.Fatalf("err = %v, want ErrApprovalPending", err)
+	}
+
+	user, _ := userRepo.FindByEmail("alice@example.com")
+	user.ApprovalStatus = domain.ApprovalApproved
+	if result, err := u.SignIn("alice@example.com", "password123", domain.ClientInfo{}); err != nil || result.Token == "" {
+		t.Fatalf("sign-in after approval: result = %+v, err = %v", result, err)
+	}
+}
+
+// 列挙対策が有効な場合、既存アカウントへの登録は所有者にだけメールで知らせる
+func TestSignUpExistingEmailNotifiesOwner(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, userRepo, _ := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpOpen}, alice)
+	u.cfg.EnumerationProtection = true
+	mail := u.mailer.(*fakeMailer)
+
+	if _, err := u.SignUp("Alice@Example.com", "password123", "", domain.ClientInfo{}); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Fatalf("err = %v, want ErrEmailAlreadyExists", err)
+	}
+	mail.waitSent(t, "Alice@Example.com")
+	if len(userRepo.created) != 0 {
+		t.Fatalf("created %+v", userRepo.created)
+	}
+
+	// 新規登録でも確認メールが届くため、メールの有無では区別できない
+	if _, err := u.SignUp("bob@example.com", "password123", "", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	mail.waitSent(t, "bob@example.com")
+}
+
+// 論理削除されたアカウントのアドレスは保留期間が過ぎるまで新規登録に使えない
+func TestSignUpDeletedEmailHold(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com"}
+	u, userRepo, _ := newSignUpTestUsecase(config.SignUpConfig{Mode: config.SignUpOpen}, alice)
+	userRepo.hold = 24 * time.Hour
+	if _, err := userRepo.SoftDelete(alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := u.SignUp("alice@example.com", "password123", "", domain.ClientInfo{}); !errors.Is(err, domain.ErrEmailAlreadyExists) {
+		t.Fatalf("within hold: err = %v, want ErrEmailAlreadyExists", err)
+	}
+
+	alice.DeletedAt.Time = time.Now().Add(-25 * time.Hour)
+	user, err := u.SignUp("alice@example.com", "password123", "", domain.ClientInfo{})
+	if err != nil {
+		t.Fatalf("after hold: %v", err)
+	}
+	if user.ID == alice.ID || user.PublicID == alice.PublicID {
+		t.Fatalf("deleted account reused: %+v", user)
+	}
+}
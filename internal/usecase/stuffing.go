@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+)
+
+// しきい値を保存する設定名
+const stuffingThresholdsSetting = "stuffing_thresholds"
+
+// 保存済みしきい値を再読み込みする間隔
+const stuffingThresholdsTTL = 10 * time.Second
+
+// StuffingDetector 複数アカウントにまたがるクレデンシャルスタッフィングの検知
+type StuffingDetector interface {
+	Check(client domain.ClientInfo) domain.StuffingVerdict // サインイン前の判定
+	Observe(client domain.ClientInfo, failed bool)         // サインイン結果の記録
+	Thresholds() domain.StuffingThresholds
+	UpdateThresholds(actorID uint, thresholds domain.StuffingThresholds) error
+}
+
+// ChallengeVerifier チャレンジモード時にクライアントの応答を検証する
+// 未設定（nil）の場合、チャレンジモードはサインインを許可してログに記録するだけになる
+type ChallengeVerifier interface {
+	Verify(response string) bool
+}
+
+type stuffingDetector struct {
+	statsRepo   repository.SignInStatsRepository
+	settingRepo repository.SettingRepository
+	auditRepo   repository.AuditRepository
+	defaults    domain.StuffingThresholds
+
+	mu       sync.Mutex
+	cached   domain.StuffingThresholds
+	cachedAt time.Time
+}
+
+// NewStuffingDetector StuffingDetectorのコンストラクタ
+func NewStuffingDetector(
+	statsRepo repository.SignInStatsRepository,
+	settingRepo repository.SettingRepository,
+	auditRepo repository.AuditRepository,
+	defaults domain.StuffingThresholds,
+) StuffingDetector {
+	return &stuffingDetector{
+		statsRepo:   statsRepo,
+		settingRepo: settingRepo,
+		auditRepo:   auditRepo,
+		defaults:    defaults,
+	}
+}
+
+// 集計軸
+type stuffingDimension struct {
+	name  string
+	value string
+	rule  domain.StuffingRule
+}
+
+func (d *stuffingDetector) dimensions(client domain.ClientInfo, t domain.StuffingThresholds) []stuffingDimension {
+	dims := []stuffingDimension{{name: "global", value: "all", rule: t.Global}}
+	if client.IP != "" {
+		dims = append(dims,
+			stuffingDimension{name: "ip", value: client.IP, rule: t.IP},
+			stuffingDimension{name: "subnet", value: subnetOf(client.IP), rule: t.Subnet},
+		)
+	}
+	if client.UserAgent != "" {
+		sum := sha256.Sum256([]byte(client.UserAgent))
+		dims = append(dims, stuffingDimension{name: "ua", value: hex.EncodeToString(sum[:8]), rule: t.UserAgent})
+	}
+	return dims
+}
+
+func (d *stuffingDetector) Check(client domain.ClientInfo) domain.StuffingVerdict {
+	t := d.Thresholds()
+	window := time.Duration(t.WindowSeconds) * time.Second
+
+	verdict := domain.StuffingAllow
+	for _, dim := range d.dimensions(client, t) {
+		attempts, failures, err := d.statsRepo.Counts(dim.name+":"+dim.value, window)
+		if err != nil {
+			log.Printf("failed to read sign-in stats: %v", err)
+			continue
+		}
+		if attempts < dim.rule.MinAttempts {
+			continue
+		}
+
+		ratio := float64(failures) / float64(attempts)
+		v := domain.StuffingAllow
+		switch {
+		case dim.rule.BlockRatio > 0 && ratio >= dim.rule.BlockRatio:
+			v = domain.StuffingBlock
+		case dim.rule.ChallengeRatio > 0 && ratio >= dim.rule.ChallengeRatio:
+			v = domain.StuffingChallenge
+		}
+		if v == domain.StuffingAllow {
+			continue
+		}
+
+		d.recordDetection(dim, v, attempts, failures, ratio, window, client)
+		if v > verdict {
+			verdict = v
+		}
+	}
+	return verdict
+}
+
+func (d *stuffingDetector) Observe(client domain.ClientInfo, failed bool) {
+	t := d.Thresholds()
+	window := time.Duration(t.WindowSeconds) * time.Second
+
+	dims := d.dimensions(client, t)
+	keys := make([]string, 0, len(dims))
+	for _, dim := range dims {
+		keys = append(keys, dim.name+":"+dim.value)
+	}
+	if err := d.statsRepo.Record(keys, failed, window); err != nil {
+		log.Printf("failed to record sign-in stats: %v", err)
+	}
+}
+
+// recordDetection 検知内容を監査ログに記録（同じ軸・判定は期間内に1回のみ）
+func (d *stuffingDetector) recordDetection(dim stuffingDimension, v domain.StuffingVerdict, attempts, failures int, ratio float64, window time.Duration, client domain.ClientInfo) {
+	first, err := d.statsRepo.MarkOnce(dim.name+":"+dim.value+":"+v.String(), window)
+	if err != nil || !first {
+		return
+	}
+
+	event := domain.AuditEvent{
+		Type:      domain.AuditStuffingDetect,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Detail: auditDetail(map[string]interface{}{
+			"dimension": dim.name,
+			"value":     dim.value,
+			"action":    v.String(),
+			"attempts":  attempts,
+			"failures":  failures,
+			"ratio":     ratio,
+		}),
+	}
+	if err := d.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+func (d *stuffingDetector) Thresholds() domain.StuffingThresholds {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if time.Since(d.cachedAt) < stuffingThresholdsTTL {
+		return d.cached
+	}
+
+	t := d.defaults
+	if _, err := d.settingRepo.Get(stuffingThresholdsSetting, &t); err != nil {
+		log.Printf("failed to load stuffing thresholds: %v", err)
+		t = d.defaults
+	}
+	d.cached = t
+	d.cachedAt = time.Now()
+	return t
+}
+
+func (d *stuffingDetector) UpdateThresholds(actorID uint, thresholds domain.StuffingThresholds) error {
+	if err := d.settingRepo.Set(stuffingThresholdsSetting, thresholds); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.cached = thresholds
+	d.cachedAt = time.Now()
+	d.mu.Unlock()
+
+	event := domain.AuditEvent{
+		Type:    domain.AuditStuffingThresholds,
+		ActorID: &actorID,
+		Detail:  auditDetail(map[string]interface{}{"thresholds": thresholds}),
+	}
+	if err := d.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+// subnetOf IPv4は/24、IPv6は/64のネットワークアドレスを返す
+func subnetOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
@@ -0,0 +1,245 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/config"
+)
+
+// fakeSignInStatsRepo 期間を考慮せずに回数を数える
+type fakeSignInStatsRepo struct {
+	attempts map[string]int
+	failures map[string]int
+	marked   map[string]bool
+}
+
+func newFakeSignInStatsRepo() *fakeSignInStatsRepo {
+	return &fakeSignInStatsRepo{attempts: map[string]int{}, failures: map[string]int{}, marked: map[string]bool{}}
+}
+
+func (r *fakeSignInStatsRepo) Record(keys []string, failed bool, window time.Duration) error {
+	for _, key := range keys {
+		r.attempts[key]++
+		if failed {
+			r.failures[key]++
+		}
+	}
+	return nil
+}
+
+func (r *fakeSignInStatsRepo) Counts(key string, window time.Duration) (int, int, error) {
+	return r.attempts[key], r.failures[key], nil
+}
+
+func (r *fakeSignInStatsRepo) MarkOnce(key string, ttl time.Duration) (bool, error) {
+	if r.marked[key] {
+		return false, nil
+	}
+	r.marked[key] = true
+	return true, nil
+}
+
+// fakeSettingRepo 保存時と同じくJSONを経由して設定を読み書きする
+type fakeSettingRepo struct {
+	settings map[string][]byte
+}
+
+func (r *fakeSettingRepo) Get(name string, v interface{}) (bool, error) {
+	data, ok := r.settings[name]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+func (r *fakeSettingRepo) Set(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.settings[name] = data
+	return nil
+}
+
+type fakeChallengeVerifier struct {
+	valid string
+}
+
+func (v *fakeChallengeVerifier) Verify(response string) bool {
+	return response != "" && response == v.valid
+}
+
+// IP 単位のしきい値だけを有効にした検知器
+func newTestStuffingDetector() (*stuffingDetector, *fakeSignInStatsRepo, *fakeAuditRepo) {
+	statsRepo := newFakeSignInStatsRepo()
+	auditRepo := &fakeAuditRepo{}
+	defaults := domain.StuffingThresholds{
+		WindowSeconds: 600,
+		IP:            domain.StuffingRule{MinAttempts: 10, ChallengeRatio: 0.5, BlockRatio: 0.8},
+		Subnet:        domain.StuffingRule{MinAttempts: 1000},
+		UserAgent:     domain.StuffingRule{MinAttempts: 1000},
+		Global:        domain.StuffingRule{MinAttempts: 1000},
+	}
+	d := NewStuffingDetector(statsRepo, &fakeSettingRepo{settings: map[string][]byte{}}, auditRepo, defaults).(*stuffingDetector)
+	return d, statsRepo, auditRepo
+}
+
+// observe 同じ接続元からの成功・失敗を記録する
+func observe(d StuffingDetector, client domain.ClientInfo, successes, failures int) {
+	for range successes {
+		d.Observe(client, false)
+	}
+	for range failures {
+		d.Observe(client, true)
+	}
+}
+
+func TestStuffingDetectorThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		successes int
+		failures  int
+		want      domain.StuffingVerdict
+	}{
+		{name: "too few attempts", failures: 9, want: domain.StuffingAllow},
+		{name: "below challenge ratio", successes: 6, failures: 4, want: domain.StuffingAllow},
+		{name: "at challenge ratio", successes: 5, failures: 5, want: domain.StuffingChallenge},
+		{name: "below block ratio", successes: 3, failures: 7, want: domain.StuffingChallenge},
+		{name: "at block ratio", successes: 2, failures: 8, want: domain.StuffingBlock},
+		{name: "all failures", failures: 50, want: domain.StuffingBlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _, audit := newTestStuffingDetector()
+			client := domain.ClientInfo{IP: "192.0.2.1"}
+			observe(d, client, tt.successes, tt.failures)
+
+			if got := d.Check(client); got != tt.want {
+				t.Fatalf("verdict = %v, want %v", got, tt.want)
+			}
+			// 別の接続元は影響を受けない
+			if got := d.Check(domain.ClientInfo{IP: "198.51.100.1"}); got != domain.StuffingAllow {
+				t.Fatalf("other IP verdict = %v", got)
+			}
+			audited := slices.Contains(audit.types(), domain.AuditStuffingDetect)
+			if audited != (tt.want != domain.StuffingAllow) {
+				t.Fatalf("detection audited = %v for verdict %v", audited, tt.want)
+			}
+		})
+	}
+}
+
+// 検知は軸・判定ごとに期間内で1回だけ監査ログに記録する
+func TestStuffingDetectorAuditsOnce(t *testing.T) {
+	d, _, audit := newTestStuffingDetector()
+	client := domain.ClientInfo{IP: "192.0.2.1"}
+	observe(d, client, 0, 10)
+
+	for range 3 {
+		d.Check(client)
+	}
+	if got := len(audit.events); got != 1 {
+		t.Fatalf("audit events = %d, want 1", got)
+	}
+	var detail map[string]interface{}
+	if err := json.Unmarshal([]byte(audit.events[0].Detail), &detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail["dimension"] != "ip" || detail["action"] != "block" {
+		t.Fatalf("detail = %v", detail)
+	}
+}
+
+// IPを変えながらの試行もサブネット単位で検知する
+func TestStuffingDetectorSubnet(t *testing.T) {
+	d, _, _ := newTestStuffingDetector()
+	d.defaults.Subnet = domain.StuffingRule{MinAttempts: 20, ChallengeRatio: 0.5, BlockRatio: 0.9}
+	for i := range 20 {
+		observe(d, domain.ClientInfo{IP: fmt.Sprintf("192.0.2.%d", i+1)}, 0, 1)
+	}
+
+	if got := d.Check(domain.ClientInfo{IP: "192.0.2.200"}); got != domain.StuffingBlock {
+		t.Fatalf("same /24 verdict = %v, want block", got)
+	}
+	if got := d.Check(domain.ClientInfo{IP: "192.0.3.1"}); got != domain.StuffingAllow {
+		t.Fatalf("other /24 verdict = %v, want allow", got)
+	}
+}
+
+// 管理者が変更したしきい値はすぐに反映され、保存・監査される
+func TestStuffingDetectorUpdateThresholds(t *testing.T) {
+	d, _, audit := newTestStuffingDetector()
+	client := domain.ClientInfo{IP: "192.0.2.1"}
+	observe(d, client, 5, 5)
+	if got := d.Check(client); got != domain.StuffingChallenge {
+		t.Fatalf("verdict = %v", got)
+	}
+
+	updated := d.Thresholds()
+	updated.IP.BlockRatio = 0.5
+	if err := d.UpdateThresholds(1, updated); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Check(client); got != domain.StuffingBlock {
+		t.Fatalf("verdict after update = %v, want block", got)
+	}
+	if !slices.Contains(audit.types(), domain.AuditStuffingThresholds) {
+		t.Fatalf("threshold change not audited: %v", audit.types())
+	}
+
+	// 他のインスタンスも保存された値を読み込む
+	other := NewStuffingDetector(d.statsRepo, d.settingRepo, &fakeAuditRepo{}, d.defaults)
+	if got := other.Thresholds(); got != updated {
+		t.Fatalf("stored thresholds = %+v, want %+v", got, updated)
+	}
+}
+
+func TestSignInStuffingVerdict(t *testing.T) {
+	lockout := config.LockoutConfig{Threshold: 5, Window: time.Hour, LockDuration: time.Minute, BaseDelay: time.Second, MaxDelay: time.Minute}
+	tests := []struct {
+		name      string
+		verdict   domain.StuffingVerdict
+		verifier  bool
+		response  string
+		wantErr   error
+		wantToken bool
+	}{
+		{name: "allow", verdict: domain.StuffingAllow, wantToken: true},
+		{name: "block", verdict: domain.StuffingBlock, wantErr: domain.ErrSignInBlocked},
+		{name: "challenge without response", verdict: domain.StuffingChallenge, verifier: true, wantErr: domain.ErrChallengeRequired},
+		{name: "challenge with wrong response", verdict: domain.StuffingChallenge, verifier: true, response: "wrong", wantErr: domain.ErrChallengeRequired},
+		{name: "challenge solved", verdict: domain.StuffingChallenge, verifier: true, response: "solved", wantToken: true},
+		// 検証する仕組みがなければ解けない要求でブロックしない
+		{name: "challenge without verifier", verdict: domain.StuffingChallenge, wantToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, attempts, _, user := newLockoutTestUsecase(t, lockout)
+			u.detector = &fakeDetector{verdict: tt.verdict}
+			if tt.verifier {
+				u.challenge = &fakeChallengeVerifier{valid: "solved"}
+			}
+
+			result, err := u.SignIn(user.Email, "correct-password", domain.ClientInfo{ChallengeResponse: tt.response})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				// パスワードを照合する前に拒否し、アカウントの失敗回数にも数えない
+				if attempts.failures[user.Email] != 0 {
+					t.Fatalf("failures = %v", attempts.failures)
+				}
+				return
+			}
+			if err != nil || (result.Token != "") != tt.wantToken {
+				t.Fatalf("result = %+v, err = %v", result, err)
+			}
+		})
+	}
+}
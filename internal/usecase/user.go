@@ -1,7 +1,6 @@
 package usecase
 
 import (
-	"errors"
 	"user-jwt/internal/domain"
 	"user-jwt/internal/repository"
 )
@@ -9,6 +8,14 @@ import (
 // UserUsecase ユーザーに関するユースケース
 type UserUsecase interface {
 	GetUserByID(userID uint) (*domain.User, error)
+	ViewUser(viewer domain.Viewer, publicID string) (UserView, error)                          // 公開範囲に従ってユーザー情報を参照
+	UpdateProfile(userID uint, version int, update domain.ProfileUpdate) (*domain.User, error) // versionが現在の値と一致する場合のみ更新
+}
+
+// UserView 参照者に見せてよい範囲のユーザー情報
+type UserView struct {
+	User *domain.User
+	Full bool // falseの場合は公開プロフィールのみ返す
 }
 
 type userUsecase struct {
@@ -23,8 +30,58 @@ func NewUserUsecase(userRepo repository.UserRepository) UserUsecase {
 // GetUserByID ユーザーIDでユーザー情報を取得
 func (u *userUsecase) GetUserByID(userID uint) (*domain.User, error) {
 	user, err := u.userRepo.FindByID(userID)
-	if err != nil || user == nil {
-		return nil, errors.New("user not found")
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
 	}
 	return user, nil
 }
+
+// ViewUser 本人と管理者には全項目、他のユーザーには公開プロフィールのみを返す
+// 参照できない場合はアカウントの存在を明かさないよう ErrUserNotFound を返す
+func (u *userUsecase) ViewUser(viewer domain.Viewer, publicID string) (UserView, error) {
+	user, err := u.userRepo.FindByPublicID(publicID)
+	if err != nil {
+		return UserView{}, err
+	}
+	if user == nil {
+		return UserView{}, domain.ErrUserNotFound
+	}
+
+	if viewer.UserID == user.ID || viewer.IsAdmin() {
+		return UserView{User: user, Full: true}, nil
+	}
+	if user.Visibility == domain.VisibilityPrivate {
+		return UserView{}, domain.ErrUserNotFound
+	}
+	return UserView{User: user}, nil
+}
+
+// UpdateProfile プロフィールを更新して更新後のユーザーを返す
+func (u *userUsecase) UpdateProfile(userID uint, version int, update domain.ProfileUpdate) (*domain.User, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	if user.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+	if update.Empty() {
+		return user, nil
+	}
+
+	// 読み込みから更新までの間に別の更新が入った場合も検出する
+	updated, err := u.userRepo.UpdateProfile(userID, version, update)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, domain.ErrVersionMismatch
+	}
+	return u.userRepo.FindByID(userID)
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"user-jwt/internal/domain"
+)
+
+func TestViewUser(t *testing.T) {
+	alice := &domain.User{Email: "alice@example.com", Visibility: domain.VisibilityPublic}
+	bob := &domain.User{Email: "bob@example.com", Visibility: domain.VisibilityPrivate}
+	admin := &domain.User{Email: "admin@example.com", Role: domain.RoleAdmin}
+	u := NewUserUsecase(newFakeUserRepo(alice, bob, admin))
+
+	asUser := func(user *domain.User) domain.Viewer { return domain.Viewer{UserID: user.ID, Role: user.Role} }
+	tests := []struct {
+		name     string
+		viewer   domain.Viewer
+		target   string
+		wantErr  error
+		wantFull bool
+	}{
+		{name: "self", viewer: asUser(alice), target: alice.PublicID, wantFull: true},
+		{name: "self private", viewer: asUser(bob), target: bob.PublicID, wantFull: true},
+		{name: "other public", viewer: asUser(bob), target: alice.PublicID},
+		// 非公開のアカウントは存在しないものとして扱う
+		{name: "other private", viewer: asUser(alice), target: bob.PublicID, wantErr: domain.ErrUserNotFound},
+		{name: "admin public", viewer: asUser(admin), target: alice.PublicID, wantFull: true},
+		{name: "admin private", viewer: asUser(admin), target: bob.PublicID, wantFull: true},
+		{name: "unknown", viewer: asUser(alice), target: "0190a6f2-7b3c-7d4e-8f00-00000000ffff", wantErr: domain.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view, err := u.ViewUser(tt.viewer, tt.target)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if view.User.PublicID != tt.target || view.Full != tt.wantFull {
+				t.Fatalf("view = {%s full=%v}, want {%s full=%v}", view.User.PublicID, view.Full, tt.target, tt.wantFull)
+			}
+		})
+	}
+}
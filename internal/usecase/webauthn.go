@@ -0,0 +1,378 @@
+package usecase
+
+import (
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/internal/repository"
+	"user-jwt/pkg/config"
+	"user-jwt/pkg/utils"
+	"user-jwt/pkg/webauthn"
+)
+
+// WebAuthnUsecase パスキーの登録・認証に関するユースケース
+type WebAuthnUsecase interface {
+	BeginRegistration(userID uint) (string, webauthn.CreationOptions, error)
+	FinishRegistration(userID uint, sessionID, name string, resp webauthn.AttestationResponse) (domain.WebAuthnCredential, error)
+	BeginLogin() (string, webauthn.RequestOptions, error)                                                            // パスキーのみでのサインイン
+	FinishLogin(sessionID string, resp webauthn.AssertionResponse, client domain.ClientInfo) (string, error)         // JWTを返す
+	BeginMFA(mfaToken string) (string, webauthn.RequestOptions, error)                                               // 第二要素としての認証
+	FinishMFA(mfaToken, sessionID string, resp webauthn.AssertionResponse, client domain.ClientInfo) (string, error) // JWTを返す
+	ListCredentials(userID uint) ([]domain.WebAuthnCredential, error)
+	DeleteCredential(userID, credentialID uint) error
+	SecondFactor
+}
+
+type webAuthnUsecase struct {
+	userRepo    repository.UserRepository
+	credRepo    repository.WebAuthnRepository
+	sessionRepo repository.WebAuthnSessionRepository
+	auditRepo   repository.AuditRepository
+	cfg         config.WebAuthnConfig
+}
+
+// NewWebAuthnUsecase WebAuthnUsecaseのコンストラクタ
+func NewWebAuthnUsecase(
+	userRepo repository.UserRepository,
+	credRepo repository.WebAuthnRepository,
+	sessionRepo repository.WebAuthnSessionRepository,
+	auditRepo repository.AuditRepository,
+	cfg config.WebAuthnConfig,
+) WebAuthnUsecase {
+	return &webAuthnUsecase{
+		userRepo:    userRepo,
+		credRepo:    credRepo,
+		sessionRepo: sessionRepo,
+		auditRepo:   auditRepo,
+		cfg:         cfg,
+	}
+}
+
+func (u *webAuthnUsecase) BeginRegistration(userID uint) (string, webauthn.CreationOptions, error) {
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return "", webauthn.CreationOptions{}, domain.ErrUserNotFound
+	}
+
+	creds, err := u.credRepo.ListByUser(userID)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		id, err := webauthn.Decode(cred.CredentialID)
+		if err != nil {
+			continue
+		}
+		exclude = append(exclude, webauthn.NewCredentialDescriptor(id, splitTransports(cred.Transports)))
+	}
+
+	// パスキーのみでのサインインにはユーザー検証が必須
+	requireUV := u.cfg.PasskeyLogin || u.cfg.UserVerification == webauthn.VerificationRequired
+	sessionID, challenge, err := u.startSession(domain.CeremonyRegistration, userID, requireUV)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, err
+	}
+
+	residentKey := "discouraged"
+	if u.cfg.PasskeyLogin {
+		residentKey = "required"
+	}
+	opts := u.cfg.NewCreationOptions(challenge, webauthn.User{
+		ID:          userHandle(user),
+		Name:        user.Email,
+		DisplayName: user.Email,
+	}, exclude, residentKey)
+	if requireUV {
+		opts.AuthenticatorSelection.UserVerification = webauthn.VerificationRequired
+	}
+	return sessionID, opts, nil
+}
+
+func (u *webAuthnUsecase) FinishRegistration(userID uint, sessionID, name string, resp webauthn.AttestationResponse) (domain.WebAuthnCredential, error) {
+	session, err := u.takeSession(sessionID, domain.CeremonyRegistration)
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+	if session.UserID != userID {
+		return domain.WebAuthnCredential{}, domain.ErrWebAuthnFailed
+	}
+
+	verified, err := u.cfg.VerifyRegistration(session.Challenge, resp, session.RequireUV)
+	if err != nil {
+		log.Printf("passkey registration rejected: %v", err)
+		return domain.WebAuthnCredential{}, domain.ErrWebAuthnFailed
+	}
+
+	credentialID := webauthn.Encode(verified.ID)
+	existing, err := u.credRepo.FindByCredentialID(credentialID)
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+	if existing != nil {
+		return domain.WebAuthnCredential{}, domain.ErrWebAuthnFailed
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+	cred, err := u.credRepo.Create(domain.WebAuthnCredential{
+		UserID:            userID,
+		CredentialID:      credentialID,
+		PublicKey:         verified.PublicKey,
+		SignCount:         verified.SignCount,
+		AAGUID:            hex.EncodeToString(verified.AAGUID),
+		AttestationFormat: verified.AttestationFormat,
+		Transports:        strings.Join(verified.Transports, ","),
+		Name:              name,
+	})
+	if err != nil {
+		return domain.WebAuthnCredential{}, err
+	}
+
+	u.audit(domain.AuditPasskeyRegistered, userID, map[string]interface{}{"credential_id": cred.ID, "fmt": cred.AttestationFormat})
+	return cred, nil
+}
+
+func (u *webAuthnUsecase) BeginLogin() (string, webauthn.RequestOptions, error) {
+	if !u.cfg.PasskeyLogin {
+		return "", webauthn.RequestOptions{}, domain.ErrFeatureDisabled
+	}
+
+	sessionID, challenge, err := u.startSession(domain.CeremonyLogin, 0, true)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+	// 発見可能なクレデンシャルを使うため allowCredentials は空にする
+	return sessionID, u.cfg.NewRequestOptions(challenge, nil, webauthn.VerificationRequired), nil
+}
+
+func (u *webAuthnUsecase) FinishLogin(sessionID string, resp webauthn.AssertionResponse, client domain.ClientInfo) (string, error) {
+	if !u.cfg.PasskeyLogin {
+		return "", domain.ErrFeatureDisabled
+	}
+
+	session, err := u.takeSession(sessionID, domain.CeremonyLogin)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := u.verifyAssertion(session, resp, 0)
+	if err != nil {
+		return "", err
+	}
+	// ユーザー検証付きのパスキーはそれ自体が多要素認証
+	return issueAccessToken(u.userRepo, u.auditRepo, user, utils.NewAuthContext(utils.AMRHardwareKey, utils.AMRMFA), client)
+}
+
+func (u *webAuthnUsecase) BeginMFA(mfaToken string) (string, webauthn.RequestOptions, error) {
+	if !u.cfg.SecondFactor {
+		return "", webauthn.RequestOptions{}, domain.ErrFeatureDisabled
+	}
+
+	_, userID, err := u.mfaTokenUser(mfaToken)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+
+	creds, err := u.credRepo.ListByUser(userID)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+	if len(creds) == 0 {
+		return "", webauthn.RequestOptions{}, domain.ErrCredentialNotFound
+	}
+	allow := make([]webauthn.CredentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		id, err := webauthn.Decode(cred.CredentialID)
+		if err != nil {
+			continue
+		}
+		allow = append(allow, webauthn.NewCredentialDescriptor(id, splitTransports(cred.Transports)))
+	}
+
+	requireUV := u.cfg.UserVerification == webauthn.VerificationRequired
+	sessionID, challenge, err := u.startSession(domain.CeremonyMFA, userID, requireUV)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, err
+	}
+	return sessionID, u.cfg.NewRequestOptions(challenge, allow, u.cfg.UserVerification), nil
+}
+
+func (u *webAuthnUsecase) FinishMFA(mfaToken, sessionID string, resp webauthn.AssertionResponse, client domain.ClientInfo) (string, error) {
+	if !u.cfg.SecondFactor {
+		return "", domain.ErrFeatureDisabled
+	}
+
+	claims, userID, err := u.mfaTokenUser(mfaToken)
+	if err != nil {
+		return "", err
+	}
+
+	session, err := u.takeSession(sessionID, domain.CeremonyMFA)
+	if err != nil {
+		return "", err
+	}
+	if session.UserID != userID {
+		return "", domain.ErrWebAuthnFailed
+	}
+
+	user, err := u.verifyAssertion(session, resp, userID)
+	if err != nil {
+		return "", err
+	}
+	amr := append(claims.AMR, utils.AMRHardwareKey, utils.AMRMFA)
+	return issueAccessToken(u.userRepo, u.auditRepo, user, utils.NewAuthContext(amr...), client)
+}
+
+// mfaTokenUser MFAトークンを検証して対象ユーザーの内部IDを返す
+func (u *webAuthnUsecase) mfaTokenUser(mfaToken string) (*utils.Claims, uint, error) {
+	claims, err := utils.VerifyPurposeJWT(mfaToken, utils.PurposeMFA)
+	if err != nil {
+		return nil, 0, domain.ErrInvalidCredentials
+	}
+	userID, err := u.userRepo.FindIDByPublicID(claims.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if userID == 0 {
+		return nil, 0, domain.ErrInvalidCredentials
+	}
+	return claims, userID, nil
+}
+
+func (u *webAuthnUsecase) Method() string {
+	return "webauthn"
+}
+
+func (u *webAuthnUsecase) Enabled(userID uint) (bool, error) {
+	if !u.cfg.SecondFactor {
+		return false, nil
+	}
+	creds, err := u.credRepo.ListByUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return len(creds) > 0, nil
+}
+
+func (u *webAuthnUsecase) ListCredentials(userID uint) ([]domain.WebAuthnCredential, error) {
+	return u.credRepo.ListByUser(userID)
+}
+
+func (u *webAuthnUsecase) DeleteCredential(userID, credentialID uint) error {
+	deleted, err := u.credRepo.Delete(userID, credentialID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return domain.ErrCredentialNotFound
+	}
+
+	u.audit(domain.AuditPasskeyRemoved, userID, map[string]interface{}{"credential_id": credentialID})
+	return nil
+}
+
+// verifyAssertion アサーションを検証し、署名カウンターを更新してユーザーを返す
+// expectedUserID が0以外の場合はそのユーザーのクレデンシャルに限定する
+func (u *webAuthnUsecase) verifyAssertion(session *domain.WebAuthnSession, resp webauthn.AssertionResponse, expectedUserID uint) (*domain.User, error) {
+	rawID, err := webauthn.Decode(resp.ID)
+	if err != nil {
+		return nil, domain.ErrWebAuthnFailed
+	}
+	cred, err := u.credRepo.FindByCredentialID(webauthn.Encode(rawID))
+	if err != nil {
+		return nil, err
+	}
+	if cred == nil || (expectedUserID != 0 && cred.UserID != expectedUserID) {
+		return nil, domain.ErrWebAuthnFailed
+	}
+
+	user, err := u.userRepo.FindByID(cred.UserID)
+	if err != nil || user == nil {
+		return nil, domain.ErrWebAuthnFailed
+	}
+
+	// userHandle が返された場合は登録時のユーザーと一致すること
+	if resp.Response.UserHandle != "" {
+		handle, err := webauthn.Decode(resp.Response.UserHandle)
+		if err != nil || string(handle) != string(userHandle(user)) {
+			return nil, domain.ErrWebAuthnFailed
+		}
+	}
+
+	newCount, _, err := u.cfg.VerifyAssertion(session.Challenge, resp, cred.PublicKey, cred.SignCount, session.RequireUV)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegression) {
+			u.audit(domain.AuditPasskeyCloned, cred.UserID, map[string]interface{}{"credential_id": cred.ID, "stored_count": cred.SignCount})
+		}
+		log.Printf("passkey assertion rejected: %v", err)
+		return nil, domain.ErrWebAuthnFailed
+	}
+
+	updated, err := u.credRepo.UpdateSignCount(cred.ID, cred.SignCount, newCount, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, domain.ErrWebAuthnFailed
+	}
+	return user, nil
+}
+
+func (u *webAuthnUsecase) startSession(ceremony string, userID uint, requireUV bool) (string, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := utils.RandomToken(24)
+	if err != nil {
+		return "", nil, err
+	}
+
+	session := domain.WebAuthnSession{
+		Ceremony:  ceremony,
+		Challenge: challenge,
+		UserID:    userID,
+		RequireUV: requireUV,
+	}
+	if err := u.sessionRepo.Save(sessionID, session, u.cfg.Timeout); err != nil {
+		return "", nil, err
+	}
+	return sessionID, challenge, nil
+}
+
+// takeSession セッションを取り出す（1回限り）
+func (u *webAuthnUsecase) takeSession(sessionID, ceremony string) (*domain.WebAuthnSession, error) {
+	session, err := u.sessionRepo.Take(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.Ceremony != ceremony {
+		return nil, domain.ErrWebAuthnFailed
+	}
+	return session, nil
+}
+
+func (u *webAuthnUsecase) audit(eventType string, userID uint, detail map[string]interface{}) {
+	event := domain.AuditEvent{Type: eventType, UserID: &userID, Detail: auditDetail(detail)}
+	if err := u.auditRepo.Record(event); err != nil {
+		log.Printf("failed to record audit event: %v", err)
+	}
+}
+
+// userHandle 認証器に保存するユーザーハンドル（個人情報や連番IDを含めない）
+func userHandle(user *domain.User) []byte {
+	return []byte(user.PublicID)
+}
+
+func splitTransports(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// AccountConfig アカウント削除・データエクスポート・利用停止の設定
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration // 削除要求から完全に消去するまでの猶予期間
+	ErasureInterval     time.Duration // 消去ジョブの実行間隔
+	ExportTTL           time.Duration // データエクスポートのアーカイブを保持する期間
+	ExportLinkTTL       time.Duration // ダウンロードリンクの有効期限
+	StatusCacheTTL      time.Duration // 認証時に確認する利用停止状態のキャッシュ期間
+	DeletedEmailHold    time.Duration // 論理削除したアカウントのメールアドレスを新規登録に使えない期間
+}
+
+// LoadAccountConfig 環境変数からアカウント削除・データエクスポート・利用停止の設定を読み込む
+func LoadAccountConfig() AccountConfig {
+	return AccountConfig{
+		DeletionGracePeriod: getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		ErasureInterval:     getEnvDuration("ACCOUNT_ERASURE_INTERVAL", time.Hour),
+		ExportTTL:           getEnvDuration("DATA_EXPORT_TTL", 24*time.Hour),
+		ExportLinkTTL:       getEnvDuration("DATA_EXPORT_LINK_TTL", 15*time.Minute),
+		StatusCacheTTL:      getEnvDuration("USER_STATUS_CACHE_TTL", 5*time.Minute),
+		DeletedEmailHold:    getEnvDuration("ACCOUNT_DELETED_EMAIL_HOLD", 90*24*time.Hour),
+	}
+}
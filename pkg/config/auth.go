@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// AuthConfig 認証まわりの動作設定
+type AuthConfig struct {
+	// EnumerationProtection trueの場合、サインアップの応答からアカウントの存在を判別できないようにする
+	EnumerationProtection bool
+	// MFAIssuer 認証アプリに表示される発行者名
+	MFAIssuer string
+	// StepUpMaxAge 重要な操作に必要な認証からの経過時間の上限
+	StepUpMaxAge time.Duration
+	// RequireVerifiedEmail trueの場合、メールアドレス未確認のユーザーはサインインできない
+	RequireVerifiedEmail bool
+	// EmailVerificationTTL メールアドレス確認リンクの有効期限
+	EmailVerificationTTL time.Duration
+	// EmailChangeTTL メールアドレス変更の確認リンクの有効期限
+	EmailChangeTTL time.Duration
+	// EmailRevertTTL 旧アドレスに送る取り消しリンクの有効期限（変更の確定後も使える）
+	EmailRevertTTL time.Duration
+	// APIBaseURL メールに記載するAPIのURL
+	APIBaseURL string
+	// PhoneCodeTTL 電話番号の確認コードの有効期限
+	PhoneCodeTTL time.Duration
+}
+
+// LoadAuthConfig 環境変数から認証設定を読み込む
+func LoadAuthConfig() AuthConfig {
+	return AuthConfig{
+		EnumerationProtection: os.Getenv("AUTH_ENUMERATION_PROTECTION") == "true",
+		MFAIssuer:             getEnvString("MFA_ISSUER", "user-jwt"),
+		StepUpMaxAge:          getEnvDuration("STEP_UP_MAX_AGE", 10*time.Minute),
+		RequireVerifiedEmail:  os.Getenv("AUTH_REQUIRE_VERIFIED_EMAIL") == "true",
+		EmailVerificationTTL:  getEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		EmailChangeTTL:        getEnvDuration("EMAIL_CHANGE_TTL", time.Hour),
+		EmailRevertTTL:        getEnvDuration("EMAIL_REVERT_TTL", 7*24*time.Hour),
+		APIBaseURL:            getEnvString("API_BASE_URL", "http://localhost:8080"),
+		PhoneCodeTTL:          getEnvDuration("PHONE_CODE_TTL", 10*time.Minute),
+	}
+}
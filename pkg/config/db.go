@@ -27,10 +27,32 @@ func ConnectDB() {
 		log.Fatal("Failed to connect to the database:", err)
 	}
 
+	// 公開IDの追加と既存ユーザーへの付与（NOT NULL列の追加より先に行う）
+	if err := migrateUserPublicIDs(database); err != nil {
+		log.Fatal("Failed to backfill user public IDs:", err)
+	}
+	// 正規化したメールアドレスの付与（一意インデックスの作成より先に行う）
+	if err := migrateUserEmailNormalized(database); err != nil {
+		log.Fatal("Failed to backfill normalized emails:", err)
+	}
+
 	// 自動マイグレーション
-	if err := database.AutoMigrate(&domain.User{}); err != nil {
+	if err := database.AutoMigrate(
+		&domain.User{},
+		&domain.AuditEvent{},
+		&domain.TOTPCredential{},
+		&domain.RecoveryCode{},
+		&domain.WebAuthnCredential{},
+		&domain.Identity{},
+		&domain.Organization{},
+		&domain.Membership{},
+		&domain.OrgInvitation{},
+	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	if err := migrateUserIndexes(database); err != nil {
+		log.Fatal("Failed to create user indexes:", err)
+	}
 
 	DB = database
 	log.Println("Database connection established.")
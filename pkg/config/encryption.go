@@ -0,0 +1,26 @@
+package config
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+
+	"user-jwt/pkg/utils"
+)
+
+// LoadEncryptionKey 保存データ暗号化用のキーを読み込む（DATA_ENCRYPTION_KEY: base64の32バイト）
+func LoadEncryptionKey() {
+	v := os.Getenv("DATA_ENCRYPTION_KEY")
+	if v == "" {
+		log.Println("DATA_ENCRYPTION_KEY is not set; features that encrypt data at rest are unavailable.")
+		return
+	}
+
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		log.Fatal("Invalid DATA_ENCRYPTION_KEY:", err)
+	}
+	if err := utils.SetEncryptionKey(key); err != nil {
+		log.Fatal("Invalid DATA_ENCRYPTION_KEY:", err)
+	}
+}
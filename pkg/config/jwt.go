@@ -0,0 +1,26 @@
+package config
+
+import (
+	"encoding/base64"
+	"log"
+	"os"
+
+	"user-jwt/pkg/utils"
+)
+
+// LoadJWTKey トークンの署名キーを読み込む（JWT_SECRET: base64の32バイト以上）
+// アクセストークンと用途限定トークン（MFA・メール確認・エクスポート）の署名に使うため、未設定なら起動しない
+func LoadJWTKey() {
+	v := os.Getenv("JWT_SECRET")
+	if v == "" {
+		log.Fatal("JWT_SECRET is not set; generate one with `openssl rand -base64 32`.")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		log.Fatal("Invalid JWT_SECRET:", err)
+	}
+	if err := utils.SetJWTKey(key); err != nil {
+		log.Fatal("Invalid JWT_SECRET:", err)
+	}
+}
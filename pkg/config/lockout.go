@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// LockoutConfig サインイン失敗時の遅延・ロックアウト設定
+type LockoutConfig struct {
+	Threshold    int           // ロックするまでの連続失敗回数
+	Window       time.Duration // 失敗回数を数える期間
+	LockDuration time.Duration // ロック時間
+	BaseDelay    time.Duration // 指数バックオフの初期遅延
+	MaxDelay     time.Duration // 指数バックオフの上限
+}
+
+// LoadLockoutConfig 環境変数からロックアウト設定を読み込む
+func LoadLockoutConfig() LockoutConfig {
+	return LockoutConfig{
+		Threshold:    getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		Window:       getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LockDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		BaseDelay:    getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		MaxDelay:     getEnvDuration("LOGIN_BACKOFF_MAX", time.Minute),
+	}
+}
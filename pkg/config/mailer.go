@@ -0,0 +1,23 @@
+package config
+
+import (
+	"log"
+	"os"
+
+	"user-jwt/pkg/mailer"
+)
+
+// NewMailer 環境変数に応じてMailerを生成（SMTP_HOST未設定時はログ出力のみ）
+func NewMailer() mailer.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Println("SMTP_HOST is not set; emails are written to the log.")
+		return mailer.NewLogMailer()
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	return mailer.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+}
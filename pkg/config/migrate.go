@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"user-jwt/internal/domain"
+	"user-jwt/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// 既存ユーザーに値を付与する1回あたりの件数
+const userBackfillBatch = 500
+
+// migrateUserPublicIDs 公開IDの列がない既存のusersテーブルに列を追加し、全ユーザーに付与する
+// 付与が終わってからNOT NULL制約を設定するので、途中で失敗しても再起動で続きから再開できる
+func migrateUserPublicIDs(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&domain.User{}) {
+		return nil
+	}
+	if !migrator.HasColumn(&domain.User{}, "PublicID") {
+		if err := db.Exec("ALTER TABLE users ADD COLUMN public_id char(36)").Error; err != nil {
+			return err
+		}
+	}
+
+	total := 0
+	for {
+		var users []struct {
+			ID        uint
+			CreatedAt time.Time
+		}
+		// deleted_at 列の追加前にも動くよう論理削除の条件を付けない
+		if err := db.Unscoped().Model(&domain.User{}).Select("id", "created_at").
+			Where("public_id IS NULL").Order("id").Limit(userBackfillBatch).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, user := range users {
+				publicID, err := utils.NewUUIDv7At(user.CreatedAt)
+				if err != nil {
+					return err
+				}
+				if err := tx.Unscoped().Model(&domain.User{}).Where("id = ?", user.ID).
+					UpdateColumn("public_id", publicID).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		total += len(users)
+	}
+	if total > 0 {
+		log.Printf("Assigned public IDs to %d existing users.", total)
+	}
+
+	return db.Exec("ALTER TABLE users ALTER COLUMN public_id SET NOT NULL").Error
+}
+
+// migrateUserEmailNormalized 既存ユーザーに正規化したメールアドレスを付与する
+// 一意インデックスの作成（AutoMigrate）より先に行う。正規化すると他の有効なアカウントと重複するユーザーが
+// いる場合は、サインインできないアカウントを残さないよう重複の一覧を返して起動を止める
+// （README の手順で解消して再起動すると、付与済みのユーザーはそのままで続きから再開する）
+func migrateUserEmailNormalized(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&domain.User{}) {
+		return nil
+	}
+	if !migrator.HasColumn(&domain.User{}, "EmailNormalized") {
+		if err := db.Exec("ALTER TABLE users ADD COLUMN email_normalized text").Error; err != nil {
+			return err
+		}
+	}
+
+	// 論理削除の列がまだない場合はすべてのユーザーを有効として扱う
+	self, other := "TRUE", "TRUE"
+	if migrator.HasColumn(&domain.User{}, "DeletedAt") {
+		self, other = "users.deleted_at IS NULL", "other.deleted_at IS NULL"
+	}
+	assign := "UPDATE users SET email_normalized = ? WHERE id = ? AND (NOT (" + self + ") OR " +
+		"NOT EXISTS (SELECT 1 FROM users other WHERE other.email_normalized = ? AND " + other + "))"
+
+	var lastID uint
+	total := 0
+	var duplicates []string
+	for {
+		var users []struct {
+			ID    uint
+			Email string
+		}
+		if err := db.Unscoped().Model(&domain.User{}).Select("id", "email").
+			Where("email_normalized IS NULL AND id > ?", lastID).Order("id").Limit(userBackfillBatch).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		for _, user := range users {
+			normalized := utils.NormalizeEmail(user.Email)
+			result := db.Exec(assign, normalized, user.ID, normalized)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				var existingID uint
+				if err := db.Model(&domain.User{}).Select("id").Where("email_normalized = ?", normalized).
+					Limit(1).Scan(&existingID).Error; err != nil {
+					return err
+				}
+				duplicates = append(duplicates, fmt.Sprintf("user %d duplicates user %d", user.ID, existingID))
+				continue
+			}
+			total++
+		}
+		lastID = users[len(users)-1].ID
+	}
+	if total > 0 {
+		log.Printf("Assigned normalized emails to %d existing users.", total)
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%d users have emails that duplicate another account after normalization; "+
+			"change the email of or delete one account in each pair and restart: %s",
+			len(duplicates), strings.Join(duplicates, ", "))
+	}
+	return nil
+}
+
+// migrateUserIndexes GORMのタグでは表現できないusersテーブルのインデックスを作成する
+func migrateUserIndexes(db *gorm.DB) error {
+	for _, stmt := range []string{
+		// 管理者向け検索でのメールアドレスの前方一致（lower(email) LIKE 'prefix%'）用
+		"CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users (lower(email) text_pattern_ops)",
+		// ユーザー一覧のカーソルページネーション（ORDER BY created_at DESC, public_id DESC）用
+		"CREATE INDEX IF NOT EXISTS idx_users_created_at_public_id ON users (created_at DESC, public_id DESC)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
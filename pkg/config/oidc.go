@@ -0,0 +1,69 @@
+package config
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"user-jwt/pkg/oidc"
+)
+
+// 既知のプロバイダーの issuer（OIDC_<NAME>_ISSUER で上書きできる）
+var defaultOIDCIssuers = map[string]string{
+	"google":    "https://accounts.google.com",
+	"microsoft": "https://login.microsoftonline.com/common/v2.0",
+}
+
+// OIDCConfig 上流の OpenID Connect プロバイダーによるサインインの設定
+type OIDCConfig struct {
+	Providers   []oidc.Config
+	StateTTL    time.Duration // 認可リクエストから callback までの有効期限
+	HTTPTimeout time.Duration // プロバイダーへのリクエストのタイムアウト
+	// CookieSecure state を結び付ける Cookie に Secure 属性を付ける（API が HTTPS で公開されている場合）
+	CookieSecure bool
+}
+
+// LoadOIDCConfig 環境変数から OIDC の設定を読み込む
+// OIDC_PROVIDERS に列挙した名前ごとに OIDC_<NAME>_CLIENT_ID などを読む
+func LoadOIDCConfig() OIDCConfig {
+	baseURL := strings.TrimSuffix(getEnvString("API_BASE_URL", "http://localhost:8080"), "/")
+
+	var providers []oidc.Config
+	for _, name := range splitList(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		clientID := os.Getenv(prefix + "CLIENT_ID")
+		issuer := getEnvString(prefix+"ISSUER", defaultOIDCIssuers[name])
+		if clientID == "" || issuer == "" {
+			log.Printf("OIDC provider %q is missing %sCLIENT_ID or %sISSUER; skipping", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, oidc.Config{
+			Name:         name,
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getEnvString(prefix+"REDIRECT_URL", baseURL+"/auth/oidc/"+name+"/callback"),
+			Scopes:       splitList(getEnvString(prefix+"SCOPES", "openid,email,profile")),
+		})
+	}
+
+	return OIDCConfig{
+		Providers:    providers,
+		StateTTL:     getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		HTTPTimeout:  getEnvDuration("OIDC_HTTP_TIMEOUT", 10*time.Second),
+		CookieSecure: strings.HasPrefix(baseURL, "https://"),
+	}
+}
+
+// NewOIDCProviders 設定したプロバイダーのクライアントを名前ごとに生成
+func NewOIDCProviders(cfg OIDCConfig) map[string]*oidc.Provider {
+	client := &http.Client{Timeout: cfg.HTTPTimeout}
+	providers := make(map[string]*oidc.Provider, len(cfg.Providers))
+	for _, providerCfg := range cfg.Providers {
+		providers[providerCfg.Name] = oidc.NewProvider(providerCfg, client)
+	}
+	return providers
+}
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// OrgConfig 組織への招待の設定
+type OrgConfig struct {
+	InvitationTTL time.Duration // 招待リンクの有効期限
+	InvitationURL string        // 招待メールのリンク先（フロントエンドの受諾画面。token クエリを付けて送る）
+}
+
+// LoadOrgConfig 環境変数から組織への招待の設定を読み込む
+func LoadOrgConfig() OrgConfig {
+	return OrgConfig{
+		InvitationTTL: getEnvDuration("ORG_INVITATION_TTL", 7*24*time.Hour),
+		InvitationURL: getEnvString("ORG_INVITATION_URL", "http://localhost:3000/org-invitations/accept"),
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// PasswordlessConfig パスワードレスサインインの設定
+type PasswordlessConfig struct {
+	TTL           time.Duration // コード・リンクの有効期限
+	MaxAttempts   int           // コード検証の最大試行回数
+	AttemptWindow time.Duration // 試行回数を数える期間（コードを再送しても数え直さない）
+	LinkURL       string        // マジックリンクの遷移先（token をクエリに付与）
+	AutoSignUp    bool          // 未登録のメールアドレスで初回サインイン時にアカウントを作成
+}
+
+// LoadPasswordlessConfig 環境変数からパスワードレスサインインの設定を読み込む
+func LoadPasswordlessConfig() PasswordlessConfig {
+	return PasswordlessConfig{
+		TTL:           getEnvDuration("PASSWORDLESS_TTL", 10*time.Minute),
+		MaxAttempts:   getEnvInt("PASSWORDLESS_MAX_ATTEMPTS", 5),
+		AttemptWindow: getEnvDuration("PASSWORDLESS_ATTEMPT_WINDOW", time.Hour),
+		LinkURL:       getEnvString("PASSWORDLESS_LINK_URL", "http://localhost:3000/passwordless/callback"),
+		AutoSignUp:    os.Getenv("PASSWORDLESS_AUTO_SIGNUP") == "true",
+	}
+}
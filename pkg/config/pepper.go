@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"user-jwt/pkg/utils"
+)
+
+// LoadPeppers シークレットファイルからパスワード用ペッパーを読み込む
+//
+// ファイルは1行に1つ "<バージョン>:<base64シークレット>" の形式で記述する。
+// PASSWORD_PEPPER_VERSION が未指定の場合は最大のバージョンを現在のペッパーとする。
+func LoadPeppers() {
+	path := os.Getenv("PASSWORD_PEPPER_FILE")
+	if path == "" {
+		log.Println("PASSWORD_PEPPER_FILE is not set; passwords are hashed without a pepper.")
+		return
+	}
+
+	keys, latest, err := readPepperFile(path)
+	if err != nil {
+		log.Fatal("Failed to load password peppers:", err)
+	}
+
+	current := latest
+	if v := os.Getenv("PASSWORD_PEPPER_VERSION"); v != "" {
+		current, err = strconv.Atoi(v)
+		if err != nil {
+			log.Fatal("Invalid PASSWORD_PEPPER_VERSION:", err)
+		}
+	}
+
+	if err := utils.SetPeppers(keys, current); err != nil {
+		log.Fatal("Failed to set password peppers:", err)
+	}
+	log.Printf("Password peppers loaded (current version: %d).", current)
+}
+
+func readPepperFile(path string) (map[int][]byte, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	keys := map[int][]byte{}
+	latest := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		versionStr, secretStr, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid pepper line: %q", line)
+		}
+		version, err := strconv.Atoi(strings.TrimSpace(versionStr))
+		if err != nil || version <= 0 {
+			return nil, 0, fmt.Errorf("invalid pepper version: %q", versionStr)
+		}
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secretStr))
+		if err != nil || len(secret) < 16 {
+			return nil, 0, fmt.Errorf("pepper version %d must be base64 of at least 16 bytes", version)
+		}
+
+		keys[version] = secret
+		if version > latest {
+			latest = version
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return keys, latest, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPepperFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantVersion []int
+		wantLatest  int
+		wantErr     bool
+	}{
+		{
+			name: "versions with comments",
+			content: "# rotated 2024-01\n" +
+				"1:c2VjcmV0LXBlcHBlci12ZXJzaW9uLW9uZQ==\n\n" +
+				"3:c2VjcmV0LXBlcHBlci12ZXJzaW9uLXRocmVl\n" +
+				"2:c2VjcmV0LXBlcHBlci12ZXJzaW9uLXR3bw==\n",
+			wantVersion: []int{1, 2, 3},
+			wantLatest:  3,
+		},
+		{name: "empty file", content: "", wantLatest: 0},
+		{name: "missing separator", content: "c2VjcmV0LXBlcHBlci12ZXJzaW9uLW9uZQ==\n", wantErr: true},
+		{name: "version zero", content: "0:c2VjcmV0LXBlcHBlci12ZXJzaW9uLW9uZQ==\n", wantErr: true},
+		{name: "non-numeric version", content: "v1:c2VjcmV0LXBlcHBlci12ZXJzaW9uLW9uZQ==\n", wantErr: true},
+		{name: "invalid base64", content: "1:not base64!\n", wantErr: true},
+		{name: "secret too short", content: "1:c2hvcnQ=\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peppers")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			keys, latest, err := readPepperFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if latest != tt.wantLatest || len(keys) != len(tt.wantVersion) {
+				t.Fatalf("got %d keys, latest %d", len(keys), latest)
+			}
+			for _, v := range tt.wantVersion {
+				if len(keys[v]) < 16 {
+					t.Fatalf("version %d not loaded", v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"user-jwt/pkg/ratelimit"
+)
+
+// RateLimitConfig 認証エンドポイントごとのレート制限設定
+type RateLimitConfig struct {
+	SignInIP               ratelimit.Rule
+	SignInEmail            ratelimit.Rule
+	SignUpIP               ratelimit.Rule
+	SignOutUser            ratelimit.Rule
+	ChallengeIP            ratelimit.Rule
+	MFAVerifyIP            ratelimit.Rule
+	WebAuthnIP             ratelimit.Rule // WebAuthnのログイン・MFA（begin/finish）
+	PasswordlessStartIP    ratelimit.Rule
+	PasswordlessStartEmail ratelimit.Rule
+	PasswordlessVerifyIP   ratelimit.Rule
+	OIDCIP                 ratelimit.Rule
+	VerifyEmailIP          ratelimit.Rule
+	VerifyEmailResendEmail ratelimit.Rule
+	StepUpUser             ratelimit.Rule
+	EmailChangeUser        ratelimit.Rule
+	EmailLinkIP            ratelimit.Rule // メールアドレス変更の確認・取り消しリンク
+	PhoneUser              ratelimit.Rule
+	ExportUser             ratelimit.Rule
+	ExportDownloadIP       ratelimit.Rule
+	OrgInvitationUser      ratelimit.Rule
+}
+
+// LoadRateLimitConfig 環境変数からレート制限設定を読み込む（例: RATE_LIMIT_SIGN_IN_IP="20/1m"）
+func LoadRateLimitConfig() RateLimitConfig {
+	perMinute := func(n int) ratelimit.Rule { return ratelimit.Rule{Limit: n, Period: time.Minute} }
+	perHour := func(n int) ratelimit.Rule { return ratelimit.Rule{Limit: n, Period: time.Hour} }
+	return RateLimitConfig{
+		SignInIP:               getEnvRule("RATE_LIMIT_SIGN_IN_IP", perMinute(20)),
+		SignInEmail:            getEnvRule("RATE_LIMIT_SIGN_IN_EMAIL", perMinute(5)),
+		SignUpIP:               getEnvRule("RATE_LIMIT_SIGN_UP_IP", perHour(5)),
+		SignOutUser:            getEnvRule("RATE_LIMIT_SIGN_OUT_USER", perMinute(10)),
+		ChallengeIP:            getEnvRule("RATE_LIMIT_CHALLENGE_IP", perMinute(20)),
+		MFAVerifyIP:            getEnvRule("RATE_LIMIT_MFA_VERIFY_IP", perMinute(20)),
+		WebAuthnIP:             getEnvRule("RATE_LIMIT_WEBAUTHN_IP", perMinute(20)),
+		PasswordlessStartIP:    getEnvRule("RATE_LIMIT_PASSWORDLESS_START_IP", perMinute(20)),
+		PasswordlessStartEmail: getEnvRule("RATE_LIMIT_PASSWORDLESS_START_EMAIL", perMinute(5)),
+		PasswordlessVerifyIP:   getEnvRule("RATE_LIMIT_PASSWORDLESS_VERIFY_IP", perMinute(20)),
+		OIDCIP:                 getEnvRule("RATE_LIMIT_OIDC_IP", perMinute(20)),
+		VerifyEmailIP:          getEnvRule("RATE_LIMIT_VERIFY_EMAIL_IP", perMinute(20)),
+		VerifyEmailResendEmail: getEnvRule("RATE_LIMIT_VERIFY_EMAIL_RESEND_EMAIL", perMinute(5)),
+		StepUpUser:             getEnvRule("RATE_LIMIT_STEP_UP_USER", perMinute(5)),
+		EmailChangeUser:        getEnvRule("RATE_LIMIT_EMAIL_CHANGE_USER", perMinute(5)),
+		EmailLinkIP:            getEnvRule("RATE_LIMIT_EMAIL_LINK_IP", perMinute(20)),
+		PhoneUser:              getEnvRule("RATE_LIMIT_PHONE_USER", perMinute(5)),
+		ExportUser:             getEnvRule("RATE_LIMIT_EXPORT_USER", perHour(3)),
+		ExportDownloadIP:       getEnvRule("RATE_LIMIT_EXPORT_DOWNLOAD_IP", perMinute(20)),
+		OrgInvitationUser:      getEnvRule("RATE_LIMIT_ORG_INVITATION_USER", perMinute(5)),
+	}
+}
+
+func getEnvRule(key string, def ratelimit.Rule) ratelimit.Rule {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	rule, err := ratelimit.ParseRule(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return rule
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"user-jwt/pkg/ratelimit"
+	"user-jwt/pkg/screening"
+)
+
+// ScreeningConfig サインアップの不正利用対策の設定
+type ScreeningConfig struct {
+	VelocityIP    ratelimit.Rule // 同じIPからのサインアップ数の上限
+	PoWThreshold  ratelimit.Rule // 全体のサインアップ数がこれを超えるとProof of Workを要求する
+	PoWDifficulty int            // Proof of Workで求める先頭の0ビット数
+	PoWTTL        time.Duration  // Proof of Workのチャレンジの有効期限
+}
+
+// LoadScreeningConfig 環境変数からサインアップの不正利用対策の設定を読み込む
+func LoadScreeningConfig() ScreeningConfig {
+	return ScreeningConfig{
+		VelocityIP:    getEnvRule("SIGNUP_VELOCITY_IP", ratelimit.Rule{Limit: 10, Period: 24 * time.Hour}),
+		PoWThreshold:  getEnvRule("SIGNUP_POW_THRESHOLD", ratelimit.Rule{Limit: 30, Period: time.Minute}),
+		PoWDifficulty: getEnvInt("POW_DIFFICULTY", 20),
+		PoWTTL:        getEnvDuration("POW_CHALLENGE_TTL", 5*time.Minute),
+	}
+}
+
+// NewDisposableDomainList 同梱の使い捨てドメイン一覧に SIGNUP_DISPOSABLE_DOMAINS_FILE の一覧を加えて読み込む
+func NewDisposableDomainList() *screening.DomainList {
+	list, err := screening.NewDisposableDomainList(os.Getenv("SIGNUP_DISPOSABLE_DOMAINS_FILE"))
+	if err != nil {
+		log.Fatal("Failed to load disposable email domains:", err)
+	}
+	return list
+}
+
+// NewMXChecker SIGNUP_MX_STUB_FILE が設定されている場合のみMXレコードの確認に使うスタブを生成（未設定時はnil）
+func NewMXChecker() screening.MXChecker {
+	path := os.Getenv("SIGNUP_MX_STUB_FILE")
+	if path == "" {
+		return nil
+	}
+	checker, err := screening.NewStubMXChecker(path)
+	if err != nil {
+		log.Fatal("Failed to load MX record stub:", err)
+	}
+	return checker
+}
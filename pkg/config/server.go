@@ -0,0 +1,17 @@
+package config
+
+import "os"
+
+// ServerConfig HTTPサーバーの設定
+type ServerConfig struct {
+	// TrustedProxies X-Forwarded-For を信頼するリバースプロキシのIPまたはCIDR
+	// 未設定の場合はどのヘッダーも信頼せず、接続元のIPをクライアントIPとする
+	TrustedProxies []string
+}
+
+// LoadServerConfig 環境変数からHTTPサーバーの設定を読み込む（TRUSTED_PROXIES: カンマ区切り）
+func LoadServerConfig() ServerConfig {
+	return ServerConfig{
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+	}
+}
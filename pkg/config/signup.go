@@ -0,0 +1,70 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"user-jwt/pkg/utils"
+)
+
+// サインアップの受付方法
+const (
+	SignUpOpen     = "open"     // 誰でも登録できる
+	SignUpInvite   = "invite"   // 管理者が発行した招待でのみ登録できる
+	SignUpDomain   = "domain"   // 許可したドメインのメールアドレスでのみ登録できる
+	SignUpApproval = "approval" // 登録後、管理者の承認までサインインできない
+)
+
+// SignUpConfig サインアップの受付設定
+type SignUpConfig struct {
+	Mode           string
+	AllowedDomains []string      // SignUpDomain で受け付けるドメイン（正規化済み・サブドメインは含まない）
+	InvitationTTL  time.Duration // 招待の有効期限
+}
+
+// Open 誰でもアカウントを作成できるか
+func (c SignUpConfig) Open() bool {
+	return c.Mode == SignUpOpen
+}
+
+// DomainAllowed メールアドレスのドメインでサインアップできるか（SignUpDomain 以外では常に true）
+func (c SignUpConfig) DomainAllowed(email string) bool {
+	if c.Mode != SignUpDomain {
+		return true
+	}
+	normalized := utils.NormalizeEmail(email)
+	emailDomain := normalized[strings.LastIndex(normalized, "@")+1:]
+	for _, allowed := range c.AllowedDomains {
+		if emailDomain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadSignUpConfig 環境変数からサインアップの受付設定を読み込む
+func LoadSignUpConfig() SignUpConfig {
+	mode := getEnvString("SIGNUP_MODE", SignUpOpen)
+	switch mode {
+	case SignUpOpen, SignUpInvite, SignUpDomain, SignUpApproval:
+	default:
+		// 不明な値で誤って誰でも登録できる状態にしない
+		log.Printf("Unknown SIGNUP_MODE %q; falling back to %q.", mode, SignUpInvite)
+		mode = SignUpInvite
+	}
+
+	var domains []string
+	for _, domain := range strings.Split(os.Getenv("SIGNUP_ALLOWED_DOMAINS"), ",") {
+		if domain = utils.NormalizeDomain(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+
+	return SignUpConfig{
+		Mode:           mode,
+		AllowedDomains: domains,
+		InvitationTTL:  getEnvDuration("SIGNUP_INVITATION_TTL", 7*24*time.Hour),
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import "testing"
+
+func TestSignUpConfigDomainAllowed(t *testing.T) {
+	restricted := SignUpConfig{Mode: SignUpDomain, AllowedDomains: []string{"example.com", "xn--bcher-kva.example"}}
+	tests := []struct {
+		name  string
+		cfg   SignUpConfig
+		email string
+		want  bool
+	}{
+		{name: "allowed", cfg: restricted, email: "alice@example.com", want: true},
+		{name: "case insensitive", cfg: restricted, email: "Alice@EXAMPLE.com", want: true},
+		{name: "trailing dot", cfg: restricted, email: "alice@example.com.", want: true},
+		{name: "unicode domain", cfg: restricted, email: "alice@bücher.example", want: true},
+		{name: "other domain", cfg: restricted, email: "alice@example.org", want: false},
+		{name: "subdomain", cfg: restricted, email: "alice@mail.example.com", want: false},
+		{name: "suffix match", cfg: restricted, email: "alice@badexample.com", want: false},
+		{name: "allowed domain in local part", cfg: restricted, email: "example.com@evil.example", want: false},
+		{name: "no domain", cfg: restricted, email: "alice", want: false},
+		{name: "no allowed domains", cfg: SignUpConfig{Mode: SignUpDomain}, email: "alice@example.com", want: false},
+		{name: "open mode", cfg: SignUpConfig{Mode: SignUpOpen}, email: "alice@example.org", want: true},
+		{name: "invite mode", cfg: SignUpConfig{Mode: SignUpInvite}, email: "alice@example.org", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.DomainAllowed(tt.email); got != tt.want {
+				t.Fatalf("DomainAllowed(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSignUpConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		domains     string
+		wantMode    string
+		wantDomains []string
+	}{
+		{name: "default", wantMode: SignUpOpen},
+		{name: "domain", mode: "domain", domains: " Example.COM , ,Bücher.example ", wantMode: SignUpDomain, wantDomains: []string{"example.com", "xn--bcher-kva.example"}},
+		{name: "approval", mode: "approval", wantMode: SignUpApproval},
+		// 不明な値は招待制として扱う
+		{name: "unknown mode", mode: "opne", wantMode: SignUpInvite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SIGNUP_MODE", tt.mode)
+			t.Setenv("SIGNUP_ALLOWED_DOMAINS", tt.domains)
+
+			cfg := LoadSignUpConfig()
+			if cfg.Mode != tt.wantMode {
+				t.Fatalf("mode = %q, want %q", cfg.Mode, tt.wantMode)
+			}
+			if len(cfg.AllowedDomains) != len(tt.wantDomains) {
+				t.Fatalf("domains = %v, want %v", cfg.AllowedDomains, tt.wantDomains)
+			}
+			for i := range tt.wantDomains {
+				if cfg.AllowedDomains[i] != tt.wantDomains[i] {
+					t.Fatalf("domains = %v, want %v", cfg.AllowedDomains, tt.wantDomains)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package config
+
+import (
+	"log"
+
+	"user-jwt/pkg/sms"
+)
+
+// NewSMSSender SMSの送信手段を生成（送信事業者との連携は未対応のためログ出力のみ）
+func NewSMSSender() sms.Sender {
+	log.Println("No SMS provider is configured; SMS messages are written to the log.")
+	return sms.NewLogSender()
+}
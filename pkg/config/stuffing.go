@@ -0,0 +1,19 @@
+package config
+
+import (
+	"time"
+
+	"user-jwt/internal/domain"
+)
+
+// LoadStuffingThresholds クレデンシャルスタッフィング検知の初期しきい値
+// 実行時の変更は管理APIから行い、Redisに保存された値が優先される
+func LoadStuffingThresholds() domain.StuffingThresholds {
+	return domain.StuffingThresholds{
+		WindowSeconds: int(getEnvDuration("STUFFING_WINDOW", 10*time.Minute).Seconds()),
+		IP:            domain.StuffingRule{MinAttempts: 20, ChallengeRatio: 0.5, BlockRatio: 0.8},
+		Subnet:        domain.StuffingRule{MinAttempts: 50, ChallengeRatio: 0.5, BlockRatio: 0.8},
+		UserAgent:     domain.StuffingRule{MinAttempts: 100, ChallengeRatio: 0.7},
+		Global:        domain.StuffingRule{MinAttempts: 500, ChallengeRatio: 0.6},
+	}
+}
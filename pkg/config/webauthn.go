@@ -0,0 +1,64 @@
+package config
+
+import (
+	"crypto/x509"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"user-jwt/pkg/webauthn"
+)
+
+// WebAuthnConfig パスキーの設定
+type WebAuthnConfig struct {
+	webauthn.Config
+	PasskeyLogin bool // パスキーのみでのサインインを許可
+	SecondFactor bool // パスキーをサインインの第二要素として使う
+}
+
+// LoadWebAuthnConfig 環境変数からパスキーの設定を読み込む
+func LoadWebAuthnConfig() WebAuthnConfig {
+	return WebAuthnConfig{
+		Config: webauthn.Config{
+			RPID:                      getEnvString("WEBAUTHN_RP_ID", "localhost"),
+			RPName:                    getEnvString("WEBAUTHN_RP_NAME", "user-jwt"),
+			Origins:                   splitList(getEnvString("WEBAUTHN_ORIGINS", "http://localhost:8080")),
+			AttestationConveyance:     getEnvString("WEBAUTHN_ATTESTATION", "none"),
+			AllowedAttestationFormats: splitList(getEnvString("WEBAUTHN_ATTESTATION_FORMATS", "none,packed")),
+			AttestationRoots:          loadAttestationRoots(os.Getenv("WEBAUTHN_ATTESTATION_ROOTS_FILE")),
+			UserVerification:          getEnvString("WEBAUTHN_USER_VERIFICATION", webauthn.VerificationPreferred),
+			Timeout:                   getEnvDuration("WEBAUTHN_TIMEOUT", 5*time.Minute),
+		},
+		PasskeyLogin: os.Getenv("WEBAUTHN_PASSKEY_LOGIN") != "false",
+		SecondFactor: os.Getenv("WEBAUTHN_SECOND_FACTOR") != "false",
+	}
+}
+
+// カンマ区切りの文字列を分割（空要素は除く）
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// loadAttestationRoots 認証器メーカーのルート証明書（PEM）を読み込む
+// 未設定の場合は証明書チェーン付きのアテステーションを受け入れない
+func loadAttestationRoots(path string) *x509.CertPool {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal("Failed to read WEBAUTHN_ATTESTATION_ROOTS_FILE:", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		log.Fatal("WEBAUTHN_ATTESTATION_ROOTS_FILE contains no certificates")
+	}
+	return pool
+}
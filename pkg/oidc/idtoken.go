@@ -0,0 +1,106 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tenantPlaceholder マルチテナントのプロバイダー（Microsoft の common など）の issuer に含まれるテナントIDの置き換え箇所
+const tenantPlaceholder = "{tenantid}"
+
+// 受け付ける IDトークンの署名アルゴリズム
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Claims 検証済みの IDトークンから取り出したユーザー情報
+type Claims struct {
+	Issuer        string
+	Subject       string // プロバイダー内で不変のユーザー識別子
+	Email         string
+	EmailVerified bool // プロバイダーがメールアドレスの所有を確認済みか
+	Name          string
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string   `json:"nonce"`
+	AuthorizedParty string   `json:"azp"`
+	Email           string   `json:"email"`
+	EmailVerified   jsonBool `json:"email_verified"`
+	Name            string   `json:"name"`
+	TenantID        string   `json:"tid"`
+}
+
+// jsonBool 真偽値を文字列（"true"）で返すプロバイダーにも対応する
+type jsonBool bool
+
+func (b *jsonBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = jsonBool(v)
+	case string:
+		*b = jsonBool(v == "true")
+	}
+	return nil
+}
+
+// VerifyIDToken IDトークンの署名（JWKS）・issuer・audience・有効期限・nonce を検証する
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	var claims idTokenClaims
+	if _, err := parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	issuer := md.Issuer
+	if strings.Contains(issuer, tenantPlaceholder) {
+		if claims.TenantID == "" {
+			return Claims{}, fmt.Errorf("%w: missing tenant id", ErrInvalidIDToken)
+		}
+		issuer = strings.ReplaceAll(issuer, tenantPlaceholder, claims.TenantID)
+	}
+	if claims.Issuer != issuer {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	// 複数の audience を持つトークンは azp が自分宛てであることを求める
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != p.cfg.ClientID {
+		return Claims{}, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return Claims{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return Claims{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
@@ -0,0 +1,510 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "client-id"
+	testRedirectURL = "https://app.example/auth/oidc/test/callback"
+)
+
+// testOP テスト用の OpenID Provider（Discovery・JWKS・トークンエンドポイント）
+type testOP struct {
+	server *httptest.Server
+
+	mu         sync.Mutex
+	keys       map[string]*rsa.PrivateKey // JWKS に公開する鍵
+	jwksHits   int
+	codes      map[string]authorization // 発行した認可コード
+	issuer     string                   // discovery が返す issuer（空ならサーバーのURL）
+	nextClaims jwt.MapClaims            // 次に交換で返す IDトークンのクレーム
+	nextKid    string
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newTestOP(t *testing.T) *testOP {
+	t.Helper()
+	op := &testOP{keys: map[string]*rsa.PrivateKey{}, codes: map[string]authorization{}}
+	op.addKey(t, "key-1")
+	op.nextKid = "key-1"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		op.mu.Lock()
+		issuer := op.issuer
+		op.mu.Unlock()
+		if issuer == "" {
+			issuer = op.server.URL
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": op.server.URL + "/authorize",
+			"token_endpoint":         op.server.URL + "/token",
+			"jwks_uri":               op.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		op.mu.Lock()
+		defer op.mu.Unlock()
+		op.jwksHits++
+		keys := []map[string]string{}
+		for kid, key := range op.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+			return
+		}
+		op.mu.Lock()
+		auth, ok := op.codes[r.PostForm.Get("code")]
+		delete(op.codes, r.PostForm.Get("code"))
+		op.mu.Unlock()
+		// PKCE: code_verifier の S256 が認可リクエストの code_challenge と一致しなければ拒否する
+		if !ok || CodeChallenge(r.PostForm.Get("code_verifier")) != auth.challenge ||
+			r.PostForm.Get("client_id") != testClientID || r.PostForm.Get("redirect_uri") != testRedirectURL {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := op.claims(auth.nonce)
+		writeJSON(w, http.StatusOK, map[string]string{"id_token": op.sign(t, op.nextKid, claims)})
+	})
+	op.server = httptest.NewServer(mux)
+	t.Cleanup(op.server.Close)
+	return op
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (op *testOP) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op.mu.Lock()
+	op.keys[kid] = key
+	op.mu.Unlock()
+	return key
+}
+
+// authorize ブラウザでの認可を省略し、認可リクエストのパラメーターから認可コードを発行する
+func (op *testOP) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q", q.Get("code_challenge_method"))
+	}
+	code := "code-" + q.Get("state")
+	op.mu.Lock()
+	op.codes[code] = authorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	op.mu.Unlock()
+	return code
+}
+
+// claims 既定の有効な IDトークンのクレーム（nextClaims で上書きできる）
+func (op *testOP) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            op.server.URL,
+		"sub":            "subject-1",
+		"aud":            testClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	for k, v := range op.nextClaims {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func (op *testOP) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	op.mu.Lock()
+	key := op.keys[kid]
+	op.mu.Unlock()
+	if key == nil {
+		// JWKS に公開していない鍵で署名する
+		var err error
+		if key, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signWith(t, key, kid, claims)
+}
+
+func signWith(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func (op *testOP) jwksFetches() int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.jwksHits
+}
+
+func (op *testOP) provider() *Provider {
+	return NewProvider(Config{
+		Name:        "test",
+		Issuer:      op.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid", "email"},
+	}, op.server.Client())
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	op := newTestOP(t)
+	authURL, err := op.provider().AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Error("code_verifier must not be sent in the authorization request")
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 Appendix B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Fatalf("CodeChallenge = %q, want %q", got, want)
+	}
+}
+
+func TestExchangePKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		code     string
+		wantErr  error
+	}{
+		{name: "matching verifier", verifier: "verifier-1"},
+		{name: "verifier mismatch", verifier: "verifier-2", wantErr: ErrTokenExchange},
+		{name: "empty verifier", verifier: "", wantErr: ErrTokenExchange},
+		{name: "unknown code", verifier: "verifier-1", code: "unknown", wantErr: ErrTokenExchange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := newTestOP(t)
+			p := op.provider()
+			ctx := context.Background()
+			authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := op.authorize(t, authURL)
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			idToken, err := p.Exchange(ctx, code, tt.verifier)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := p.VerifyIDToken(ctx, idToken, "nonce-1"); err != nil {
+				t.Fatalf("VerifyIDToken: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		claims    jwt.MapClaims // 既定のクレームへの上書き（nil の値は削除）
+		nonce     string        // 検証時に渡す nonce
+		kid       string
+		sign      func(t *testing.T, claims jwt.MapClaims) string
+		wantErr   bool
+		wantEmail bool
+	}{
+		{name: "valid", nonce: "nonce-1", wantEmail: true},
+		{name: "email_verified as string", claims: jwt.MapClaims{"email_verified": "true"}, nonce: "nonce-1", wantEmail: true},
+		{name: "email not verified", claims: jwt.MapClaims{"email_verified": false}, nonce: "nonce-1"},
+		{name: "nonce mismatch", nonce: "nonce-2", wantErr: true},
+		{name: "nonce missing in token", claims: jwt.MapClaims{"nonce": nil}, nonce: "nonce-1", wantErr: true},
+		{name: "no expected nonce", claims: jwt.MapClaims{"nonce": ""}, nonce: "", wantErr: true},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example"}, nonce: "nonce-1", wantErr: true},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client"}, nonce: "nonce-1", wantErr: true},
+		{name: "multiple audiences without azp", claims: jwt.MapClaims{"aud": []string{testClientID, "other"}}, nonce: "nonce-1", wantErr: true},
+		{name: "multiple audiences with azp", claims: jwt.MapClaims{"aud": []string{testClientID, "other"}, "azp": testClientID}, nonce: "nonce-1", wantEmail: true},
+		{name: "azp for another client", claims: jwt.MapClaims{"azp": "other-client"}, nonce: "nonce-1", wantErr: true},
+		{name: "expired", claims: jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()}, nonce: "nonce-1", wantErr: true},
+		{name: "expired within leeway", claims: jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()}, nonce: "nonce-1", wantEmail: true},
+		{name: "missing exp", claims: jwt.MapClaims{"exp": nil}, nonce: "nonce-1", wantErr: true},
+		{name: "issued in the future", claims: jwt.MapClaims{"iat": now.Add(10 * time.Minute).Unix()}, nonce: "nonce-1", wantErr: true},
+		{name: "missing subject", claims: jwt.MapClaims{"sub": nil}, nonce: "nonce-1", wantErr: true},
+		{name: "signed by unpublished key", kid: "unpublished", nonce: "nonce-1", wantErr: true},
+		{
+			name:  "HMAC signature",
+			nonce: "nonce-1",
+			sign: func(t *testing.T, claims jwt.MapClaims) string {
+				raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			wantErr: true,
+		},
+		{
+			name:  "alg none",
+			nonce: "nonce-1",
+			sign: func(t *testing.T, claims jwt.MapClaims) string {
+				raw, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return raw
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := newTestOP(t)
+			op.nextClaims = tt.claims
+			claims := op.claims("nonce-1")
+
+			var raw string
+			switch {
+			case tt.sign != nil:
+				raw = tt.sign(t, claims)
+			case tt.kid != "":
+				raw = op.sign(t, tt.kid, claims)
+			default:
+				raw = op.sign(t, "key-1", claims)
+			}
+
+			got, err := op.provider().VerifyIDToken(context.Background(), raw, tt.nonce)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("err = %v, want ErrInvalidIDToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Subject != "subject-1" || got.Email != "alice@example.com" || got.EmailVerified != tt.wantEmail {
+				t.Fatalf("unexpected claims: %+v", got)
+			}
+		})
+	}
+}
+
+// 同じ nonce で発行された IDトークンを別のログインに使い回せない
+func TestVerifyIDTokenNonceReplay(t *testing.T) {
+	op := newTestOP(t)
+	p := op.provider()
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idToken, err := p.Exchange(ctx, op.authorize(t, authURL), "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(ctx, idToken, "nonce-1"); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	// 次のログインでは新しい nonce を発行するため、以前のトークンは一致しない
+	if _, err := p.VerifyIDToken(ctx, idToken, "nonce-2"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("replayed token: err = %v", err)
+	}
+	// 認可コードも1回しか交換できない
+	if _, err := p.Exchange(ctx, "code-state-1", "verifier-1"); !errors.Is(err, ErrTokenExchange) {
+		t.Fatalf("reused code: err = %v", err)
+	}
+}
+
+// 未知の kid は JWKS を取得し直して鍵のローテーションに追従する（再取得は最小間隔ごと）
+func TestKeyRotation(t *testing.T) {
+	op := newTestOP(t)
+	p := op.provider()
+	ctx := context.Background()
+
+	if _, err := p.VerifyIDToken(ctx, op.sign(t, "key-1", op.claims("n")), "n"); err != nil {
+		t.Fatalf("initial key: %v", err)
+	}
+	if op.jwksFetches() != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", op.jwksFetches())
+	}
+	// 取得済みの鍵はキャッシュを使う
+	if _, err := p.VerifyIDToken(ctx, op.sign(t, "key-1", op.claims("n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+	if op.jwksFetches() != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", op.jwksFetches())
+	}
+
+	// 新しい鍵に切り替わっても、最小間隔内は取得し直さない
+	op.addKey(t, "key-2")
+	rotated := op.sign(t, "key-2", op.claims("n"))
+	if _, err := p.VerifyIDToken(ctx, rotated, "n"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want ErrInvalidIDToken within refresh interval", err)
+	}
+	if op.jwksFetches() != 1 {
+		t.Fatalf("jwks fetched %d times, want 1", op.jwksFetches())
+	}
+
+	// 間隔を過ぎると未知の kid で取得し直す
+	p.mu.Lock()
+	p.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, rotated, "n"); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if op.jwksFetches() != 2 {
+		t.Fatalf("jwks fetched %d times, want 2", op.jwksFetches())
+	}
+
+	// 取り下げられた鍵は取得し直した後は使えない
+	op.mu.Lock()
+	retired := op.keys["key-1"]
+	delete(op.keys, "key-1")
+	op.mu.Unlock()
+	p.mu.Lock()
+	p.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.VerifyIDToken(ctx, op.sign(t, "key-3", op.claims("n")), "n"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("unknown kid: err = %v", err)
+	}
+	if op.jwksFetches() != 3 {
+		t.Fatalf("jwks fetched %d times, want 3", op.jwksFetches())
+	}
+	if _, err := p.VerifyIDToken(ctx, signWith(t, retired, "key-1", op.claims("n")), "n"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("retired key: err = %v", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	op := newTestOP(t)
+	op.issuer = "https://evil.example"
+	_, err := op.provider().AuthCodeURL(context.Background(), "s", "n", "v")
+	if !errors.Is(err, ErrDiscovery) {
+		t.Fatalf("err = %v, want ErrDiscovery", err)
+	}
+}
+
+// マルチテナントの issuer は tid で置き換えた値と一致することを求める
+func TestVerifyIDTokenTenantIssuer(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{name: "tenant issuer", claims: jwt.MapClaims{"tid": "tenant-1"}},
+		{name: "missing tid", claims: jwt.MapClaims{}, wantErr: true},
+		{name: "issuer for another tenant", claims: jwt.MapClaims{"tid": "tenant-2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := newTestOP(t)
+			op.issuer = op.server.URL + "/{tenantid}/v2.0"
+			claims := op.claims("n")
+			claims["iss"] = op.server.URL + "/tenant-1/v2.0"
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			_, err := op.provider().VerifyIDToken(context.Background(), op.sign(t, "key-1", claims), "n")
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseKeySetSkipsUnusableKeys(t *testing.T) {
+	set := jsonWebKeySet{Keys: []jsonWebKey{
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: "AQAB", E: "AQAB"},
+		{Kty: "oct", Kid: "symmetric"},
+		{Kty: "RSA", Kid: "bad-exponent", N: "AQAB", E: "AQ"},
+		{Kty: "EC", Kid: "off-curve", Crv: "P-256", X: "AQ", Y: "AQ"},
+		{Kty: "EC", Kid: "bad-curve", Crv: "secp256k1", X: "AQ", Y: "AQ"},
+	}}
+	if keys := parseKeySet(set); len(keys) != 0 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
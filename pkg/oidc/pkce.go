@@ -0,0 +1,12 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// CodeChallenge PKCE の code_verifier から S256 の code_challenge を求める
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
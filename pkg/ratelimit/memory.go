@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryLimiter struct {
+	mu      sync.Mutex
+	tats    map[string]time.Time
+	lastGC  time.Time
+	nowFunc func() time.Time
+}
+
+// NewMemoryLimiter プロセス内で完結するGCRAリミッター
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		tats:    map[string]time.Time{},
+		nowFunc: time.Now,
+	}
+}
+
+func (l *memoryLimiter) Allow(key string, rule Rule) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.nowFunc()
+	l.gc(now)
+
+	res, tat := gcra(now, l.tats[key], rule)
+	if res.Allowed {
+		l.tats[key] = tat
+	}
+	return res, nil
+}
+
+// gc 期限切れのキーを定期的に削除
+func (l *memoryLimiter) gc(now time.Time) {
+	if now.Sub(l.lastGC) < time.Minute {
+		return
+	}
+	for key, tat := range l.tats {
+		if tat.Before(now) {
+			delete(l.tats, key)
+		}
+	}
+	l.lastGC = now
+}
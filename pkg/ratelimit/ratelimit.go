@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule 期間あたりの許容リクエスト数
+type Rule struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRule "10/1m" 形式の文字列をRuleに変換
+func ParseRule(s string) (Rule, error) {
+	limitStr, periodStr, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rate limit rule: %q", s)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit: %q", limitStr)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit period: %q", periodStr)
+	}
+	// Redis上ではマイクロ秒単位で計算するため、それより短い間隔は表現できない
+	if period/time.Duration(limit) < time.Microsecond {
+		return Rule{}, fmt.Errorf("rate limit too high for period: %q", s)
+	}
+	return Rule{Limit: limit, Period: period}, nil
+}
+
+// emissionInterval GCRAにおける1リクエストあたりの間隔
+func (r Rule) emissionInterval() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// Result レート制限の判定結果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // 拒否時、次に許可されるまでの時間
+	ResetAfter time.Duration // 制限が完全に回復するまでの時間
+}
+
+// Limiter レート制限のインターフェース
+type Limiter interface {
+	Allow(key string, rule Rule) (Result, error)
+}
+
+// fallbackLimiter primaryが失敗した場合にsecondaryで判定する
+type fallbackLimiter struct {
+	primary   Limiter
+	secondary Limiter
+}
+
+// NewFallbackLimiter Redisが使えない場合にメモリ上のリミッターへ切り替えるリミッター
+func NewFallbackLimiter(primary, secondary Limiter) Limiter {
+	return &fallbackLimiter{primary: primary, secondary: secondary}
+}
+
+func (l *fallbackLimiter) Allow(key string, rule Rule) (Result, error) {
+	res, err := l.primary.Allow(key, rule)
+	if err == nil {
+		return res, nil
+	}
+	log.Printf("rate limiter unavailable, falling back to in-memory limiter: %v", err)
+	return l.secondary.Allow(key, rule)
+}
+
+// gcra 理論到着時刻(TAT)から判定結果を計算する
+func gcra(now, tat time.Time, rule Rule) (Result, time.Time) {
+	interval := rule.emissionInterval()
+	tolerance := rule.Period
+
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(interval)
+	allowAt := newTAT.Add(-tolerance)
+
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Limit:      rule.Limit,
+			Remaining:  0,
+			RetryAfter: allowAt.Sub(now),
+			ResetAfter: tat.Sub(now),
+		}, tat
+	}
+
+	remaining := int((tolerance - newTAT.Sub(now)) / interval)
+	return Result{
+		Allowed:    true,
+		Limit:      rule.Limit,
+		Remaining:  remaining,
+		ResetAfter: newTAT.Sub(now),
+	}, newTAT
+}
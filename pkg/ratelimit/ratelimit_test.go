@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "10/1m", want: Rule{Limit: 10, Period: time.Minute}},
+		{in: " 5 / 30s ", want: Rule{Limit: 5, Period: 30 * time.Second}},
+		{in: "10", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "ten/1m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/minute", wantErr: true},
+		// 1ミリ秒未満の間隔もマイクロ秒単位なら表現できる
+		{in: "2000/1s", want: Rule{Limit: 2000, Period: time.Second}},
+		{in: "2000000/1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRule(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// testClock テストから進める時計
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestMemoryLimiter() (*memoryLimiter, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := NewMemoryLimiter().(*memoryLimiter)
+	l.nowFunc = clock.Now
+	return l, clock
+}
+
+func TestMemoryLimiterGCRA(t *testing.T) {
+	// 3秒に3回 = 1秒に1回ずつ回復し、最大3回まで連続で許可する
+	rule := Rule{Limit: 3, Period: 3 * time.Second}
+	l, clock := newTestMemoryLimiter()
+
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{name: "first request", wantAllowed: true, wantRemaining: 2, wantReset: time.Second},
+		{name: "burst 2", wantAllowed: true, wantRemaining: 1, wantReset: 2 * time.Second},
+		{name: "burst 3", wantAllowed: true, wantRemaining: 0, wantReset: 3 * time.Second},
+		{name: "burst exhausted", wantAllowed: false, wantRetry: time.Second, wantReset: 3 * time.Second},
+		// 拒否されたリクエストは消費しない
+		{name: "still exhausted", advance: 500 * time.Millisecond, wantAllowed: false, wantRetry: 500 * time.Millisecond, wantReset: 2500 * time.Millisecond},
+		{name: "one token recovered", advance: 500 * time.Millisecond, wantAllowed: true, wantRemaining: 0, wantReset: 3 * time.Second},
+		{name: "fully recovered", advance: time.Minute, wantAllowed: true, wantRemaining: 2, wantReset: time.Second},
+	}
+	for _, s := range steps {
+		clock.now = clock.now.Add(s.advance)
+		res, err := l.Allow("ip:192.0.2.1", rule)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		want := Result{Allowed: s.wantAllowed, Limit: rule.Limit, Remaining: s.wantRemaining, RetryAfter: s.wantRetry, ResetAfter: s.wantReset}
+		if res != want {
+			t.Fatalf("%s: got %+v, want %+v", s.name, res, want)
+		}
+	}
+}
+
+func TestMemoryLimiterKeysAreIndependent(t *testing.T) {
+	rule := Rule{Limit: 1, Period: time.Minute}
+	l, _ := newTestMemoryLimiter()
+
+	if res, _ := l.Allow("a", rule); !res.Allowed {
+		t.Fatal("first request for a denied")
+	}
+	if res, _ := l.Allow("a", rule); res.Allowed {
+		t.Fatal("second request for a allowed")
+	}
+	if res, _ := l.Allow("b", rule); !res.Allowed {
+		t.Fatal("request for b denied by a's limit")
+	}
+}
+
+func TestMemoryLimiterGC(t *testing.T) {
+	rule := Rule{Limit: 10, Period: time.Second}
+	l, clock := newTestMemoryLimiter()
+
+	l.Allow("old", rule)
+	clock.now = clock.now.Add(2 * time.Minute)
+	l.Allow("new", rule)
+
+	if _, ok := l.tats["old"]; ok {
+		t.Fatal("expired key was not collected")
+	}
+	if _, ok := l.tats["new"]; !ok {
+		t.Fatal("active key was collected")
+	}
+}
+
+type stubLimiter struct {
+	res   Result
+	err   error
+	calls int
+}
+
+func (l *stubLimiter) Allow(key string, rule Rule) (Result, error) {
+	l.calls++
+	return l.res, l.err
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	rule := Rule{Limit: 1, Period: time.Minute}
+	tests := []struct {
+		name          string
+		primary       *stubLimiter
+		wantAllowed   bool
+		wantSecondary int
+	}{
+		{name: "primary available", primary: &stubLimiter{res: Result{Allowed: false}}, wantAllowed: false, wantSecondary: 0},
+		{name: "primary unavailable", primary: &stubLimiter{err: errors.New("connection refused")}, wantAllowed: true, wantSecondary: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secondary := &stubLimiter{res: Result{Allowed: true}}
+			res, err := NewFallbackLimiter(tt.primary, secondary).Allow("k", rule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if res.Allowed != tt.wantAllowed || secondary.calls != tt.wantSecondary {
+				t.Fatalf("allowed = %v, secondary calls = %d", res.Allowed, secondary.calls)
+			}
+		})
+	}
+}
+
+// Redis が落ちている間もメモリ上のリミッターで制限を続ける
+func TestFallbackLimiterStillLimits(t *testing.T) {
+	rule := Rule{Limit: 2, Period: time.Minute}
+	memory, _ := newTestMemoryLimiter()
+	l := NewFallbackLimiter(&stubLimiter{err: errors.New("redis: connection refused")}, memory)
+
+	for i, want := range []bool{true, true, false} {
+		res, err := l.Allow("ip:192.0.2.1", rule)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("request %d: allowed = %v, want %v", i, res.Allowed, want)
+		}
+	}
+}
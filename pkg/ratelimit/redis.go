@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GCRAをアトミックに実行するLuaスクリプト
+// ARGV: 1=emission interval(µs), 2=tolerance(µs)
+// 戻り値: {許可(1/0), 残り回数, retry_after(µs), reset_after(µs)}
+// "2000/1s" のような短い間隔でも0にならないようマイクロ秒で計算する
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+  tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+if now < allow_at then
+  return {0, 0, allow_at - now, tat - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+local remaining = math.floor((tolerance - (new_tat - now)) / interval)
+return {1, remaining, 0, new_tat - now}
+`)
+
+type redisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter Redis上で状態を共有するGCRAリミッター
+func NewRedisLimiter(client *redis.Client) Limiter {
+	return &redisLimiter{client: client}
+}
+
+func (l *redisLimiter) Allow(key string, rule Rule) (Result, error) {
+	ctx := context.Background()
+	values, err := gcraScript.Run(ctx, l.client, []string{"ratelimit:" + key},
+		rule.emissionInterval().Microseconds(), rule.Period.Microseconds()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      rule.Limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package screening
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+
+	"user-jwt/pkg/utils"
+)
+
+//go:embed disposable_domains.txt
+var defaultDisposableDomains string
+
+// DomainList ドメインの一覧（登録したドメインのサブドメインも一致とみなす）
+type DomainList struct {
+	domains map[string]struct{}
+}
+
+// NewDisposableDomainList 同梱の使い捨てドメイン一覧に、指定したファイルの一覧を加えて読み込む（path は空でもよい）
+func NewDisposableDomainList(path string) (*DomainList, error) {
+	list := &DomainList{domains: make(map[string]struct{})}
+	if err := list.read(strings.NewReader(defaultDisposableDomains)); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return list, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := list.read(f); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *DomainList) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if domain := utils.NormalizeDomain(line); domain != "" {
+			l.domains[domain] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+// Contains ドメインまたはその親ドメインが一覧にあるか
+func (l *DomainList) Contains(domain string) bool {
+	domain = utils.NormalizeDomain(domain)
+	for domain != "" {
+		if _, ok := l.domains[domain]; ok {
+			return true
+		}
+		i := strings.IndexByte(domain, '.')
+		if i < 0 {
+			break
+		}
+		domain = domain[i+1:]
+	}
+	return false
+}
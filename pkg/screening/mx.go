@@ -0,0 +1,52 @@
+package screening
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"user-jwt/pkg/utils"
+)
+
+// MXChecker メールを受け取れるドメインか（MXレコードの有無）を判定する
+type MXChecker interface {
+	HasMX(domain string) (bool, error)
+}
+
+type stubMXChecker struct {
+	records map[string][]string
+}
+
+// NewStubMXChecker DNSを引かずにファイルの内容でMXレコードを判定するMXChecker
+// ファイルは1行に「ドメイン MXホスト...」を書き、ホストのない行はMXレコードがないものとして扱う
+// 記載のないドメインはMXレコードがあるものとみなす（不明なドメインを拒否しない）
+func NewStubMXChecker(path string) (MXChecker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records := make(map[string][]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		records[utils.NormalizeDomain(fields[0])] = fields[1:]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &stubMXChecker{records: records}, nil
+}
+
+func (c *stubMXChecker) HasMX(domain string) (bool, error) {
+	hosts, ok := c.records[utils.NormalizeDomain(domain)]
+	return !ok || len(hosts) > 0, nil
+}
@@ -0,0 +1,20 @@
+package screening
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// CheckProofOfWork SHA-256(challenge + ":" + nonce) の先頭が difficulty ビット以上0であるか
+func CheckProofOfWork(challenge, nonce string, difficulty int) bool {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
@@ -0,0 +1,73 @@
+package screening
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"testing"
+)
+
+// leadingZeroBits SHA-256(challenge + ":" + nonce) の先頭の0ビット数
+func leadingZeroBits(challenge, nonce string) int {
+	sum := sha256.Sum256([]byte(challenge + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros
+}
+
+// solve 先頭が difficulty ビット以上0になる nonce を探す
+func solve(t *testing.T, challenge string, difficulty int) string {
+	t.Helper()
+	for i := 0; i < 1<<24; i++ {
+		nonce := strconv.Itoa(i)
+		if leadingZeroBits(challenge, nonce) >= difficulty {
+			return nonce
+		}
+	}
+	t.Fatalf("no nonce found for difficulty %d", difficulty)
+	return ""
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	const challenge = "c2lnbi11cC1jaGFsbGVuZ2U"
+	// バイトをまたぐ境界を確認するため、先頭が10ビット以上0になる nonce を使う
+	nonce := solve(t, challenge, 10)
+	zeros := leadingZeroBits(challenge, nonce)
+
+	tests := []struct {
+		name       string
+		challenge  string
+		nonce      string
+		difficulty int
+		want       bool
+	}{
+		{name: "no work required", challenge: challenge, nonce: "anything", difficulty: 0, want: true},
+		{name: "exact difficulty", challenge: challenge, nonce: nonce, difficulty: zeros, want: true},
+		{name: "lower difficulty", challenge: challenge, nonce: nonce, difficulty: 4, want: true},
+		{name: "one bit harder", challenge: challenge, nonce: nonce, difficulty: zeros + 1, want: false},
+		{name: "impossible difficulty", challenge: challenge, nonce: nonce, difficulty: 257, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckProofOfWork(tt.challenge, tt.nonce, tt.difficulty); got != tt.want {
+				t.Fatalf("CheckProofOfWork(difficulty %d) = %v, want %v", tt.difficulty, got, tt.want)
+			}
+		})
+	}
+}
+
+// 区切り文字を含めてハッシュするため、challenge と nonce の境界をずらした応答は通らない
+func TestCheckProofOfWorkSeparator(t *testing.T) {
+	nonce := solve(t, "abc", 8)
+	if leadingZeroBits("ab", "c:"+nonce) >= 8 {
+		t.Skip("shifted input happens to satisfy the difficulty")
+	}
+	if CheckProofOfWork("ab", "c:"+nonce, 8) {
+		t.Fatal("shifted challenge/nonce boundary accepted")
+	}
+}
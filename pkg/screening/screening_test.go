@@ -0,0 +1,73 @@
+package screening
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDisposableDomainList(t *testing.T) {
+	list, err := NewDisposableDomainList(writeFile(t, "# 追加分\nThrowaway.Example  # 社内で確認\n\nbücher-mail.example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "10minutemail.com", want: true}, // 同梱の一覧
+		{domain: "10MinuteMail.COM", want: true},
+		{domain: "mx.10minutemail.com", want: true}, // サブドメイン
+		{domain: "throwaway.example", want: true},   // 追加した一覧
+		{domain: "a.b.throwaway.example", want: true},
+		{domain: "Bücher-Mail.example", want: true},
+		{domain: "example.com", want: false},
+		{domain: "not10minutemail.com", want: false},
+		{domain: "10minutemail.com.example", want: false},
+		{domain: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := list.Contains(tt.domain); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestDisposableDomainListMissingFile(t *testing.T) {
+	if _, err := NewDisposableDomainList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestStubMXChecker(t *testing.T) {
+	checker, err := NewStubMXChecker(writeFile(t, "example.com mx1.example.com mx2.example.com\nNoMail.Example # MXなし\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{domain: "example.com", want: true},
+		{domain: "nomail.example", want: false},
+		{domain: "NOMAIL.example.", want: false},
+		{domain: "unknown.example", want: true}, // 記載のないドメインは拒否しない
+	}
+	for _, tt := range tests {
+		got, err := checker.HasMX(tt.domain)
+		if err != nil || got != tt.want {
+			t.Errorf("HasMX(%q) = (%v, %v), want %v", tt.domain, got, err, tt.want)
+		}
+	}
+}
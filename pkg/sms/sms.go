@@ -0,0 +1,20 @@
+package sms
+
+import "log"
+
+// Sender SMS送信のインターフェース
+type Sender interface {
+	Send(to, message string) error
+}
+
+type logSender struct{}
+
+// NewLogSender 送信せずにログへ出力するSender（開発用）
+func NewLogSender() Sender {
+	return &logSender{}
+}
+
+func (s *logSender) Send(to, message string) error {
+	log.Printf("[sms] to=%s\n%s", to, message)
+	return nil
+}
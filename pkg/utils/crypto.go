@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// 保存データ暗号化用のキー（AES-256-GCM）
+var encryptionKey []byte
+
+// ErrEncryptionKeyMissing 暗号化キーが設定されていない
+var ErrEncryptionKeyMissing = errors.New("encryption key is not configured")
+
+// SetEncryptionKey 保存データ暗号化用のキーを設定（32バイト）
+func SetEncryptionKey(key []byte) error {
+	if len(key) != 32 {
+		return errors.New("encryption key must be 32 bytes")
+	}
+	encryptionKey = key
+	return nil
+}
+
+// Encrypt 平文をAES-GCMで暗号化し、base64文字列で返す
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt Encrypt で暗号化した文字列を復号
+func Decrypt(ciphertext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	if encryptionKey == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// HashToken 高エントロピーなトークンやコードの保存用ハッシュ（SHA-256）
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RandomToken URLセーフなランダムトークンを生成
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
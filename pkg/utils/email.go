@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeEmail 重複判定・検索に使う正規化したメールアドレス
+// 前後の空白を除き、ローカル部はUnicode正規化（NFC）して小文字に、ドメインはIDNAでASCII（Punycode）表記にする
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return strings.ToLower(norm.NFC.String(email))
+	}
+
+	local := strings.ToLower(norm.NFC.String(email[:at]))
+	return local + "@" + NormalizeDomain(email[at+1:])
+}
+
+// NormalizeDomain ドメインを小文字のASCII（Punycode）表記にする
+func NormalizeDomain(domain string) string {
+	domain = strings.TrimSuffix(strings.ToLower(norm.NFC.String(strings.TrimSpace(domain))), ".")
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+	return domain
+}
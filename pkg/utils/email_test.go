@@ -0,0 +1,68 @@
+package utils
+
+import "testing"
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "already normalized", in: "alice@example.com", want: "alice@example.com"},
+		{name: "case and surrounding spaces", in: "  Alice@Example.COM\t", want: "alice@example.com"},
+		{name: "decomposed local part", in: "jose\u0301@example.com", want: "jos\u00e9@example.com"},
+		{name: "non-ASCII uppercase", in: "ÉLODIE@example.com", want: "élodie@example.com"},
+		{name: "internationalized domain", in: "user@Bücher.example", want: "user@xn--bcher-kva.example"},
+		{name: "punycode domain", in: "user@XN--BCHER-KVA.example", want: "user@xn--bcher-kva.example"},
+		{name: "fullwidth domain", in: "user@ＥＸＡＭＰＬＥ.com", want: "user@example.com"},
+		{name: "trailing dot", in: "user@example.com.", want: "user@example.com"},
+		{name: "quoted local part with @", in: `"a@b"@Example.com`, want: `"a@b"@example.com`},
+		{name: "invalid domain is only lowercased", in: "user@Exa_mple.com", want: "user@exa_mple.com"},
+		{name: "no @", in: " Alice ", want: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeEmail(tt.in); got != tt.want {
+				t.Fatalf("NormalizeEmail(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// 見た目が同じアドレスは同じ値に正規化される（重複登録の判定に使う）
+func TestNormalizeEmailEquivalence(t *testing.T) {
+	groups := [][]string{
+		{"jos\u00e9@bücher.example", "jose\u0301@bu\u0308cher.example", "JOSÉ@BÜCHER.EXAMPLE", "josé@xn--bcher-kva.example", " José@Bücher.example. "},
+		{"alice@example.com", "ALICE@EXAMPLE.COM", "alice@example.com."},
+	}
+	for _, group := range groups {
+		want := NormalizeEmail(group[0])
+		for _, email := range group[1:] {
+			if got := NormalizeEmail(email); got != want {
+				t.Errorf("NormalizeEmail(%q) = %q, want %q", email, got, want)
+			}
+		}
+	}
+	// ローカル部のドットや +タグ は別のアドレスとして扱う
+	if NormalizeEmail("a.lice@example.com") == NormalizeEmail("alice@example.com") ||
+		NormalizeEmail("alice+tag@example.com") == NormalizeEmail("alice@example.com") {
+		t.Fatal("distinct local parts normalized to the same address")
+	}
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Example.COM", want: "example.com"},
+		{in: " example.com. ", want: "example.com"},
+		{in: "Bücher.example", want: "xn--bcher-kva.example"},
+		{in: "例え.テスト", want: "xn--r8jz45g.xn--zckzah"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeDomain(tt.in); got != tt.want {
+			t.Errorf("NormalizeDomain(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
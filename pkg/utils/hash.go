@@ -1,20 +1,124 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ペッパー付きハッシュの接頭辞（例: "$p2$<bcrypt>"）
+const pepperPrefix = "$p"
+
+// ペッパーの鍵束（バージョン -> シークレット）
+var (
+	peppers        = map[int][]byte{}
+	currentPepperV = 0
+)
+
+// SetPeppers ペッパーの鍵束と現在のバージョンを設定
+// current が 0 の場合はペッパーを使わない（従来のbcryptのみ）
+func SetPeppers(keys map[int][]byte, current int) error {
+	if current != 0 {
+		if _, ok := keys[current]; !ok {
+			return fmt.Errorf("pepper version %d is not loaded", current)
+		}
+	}
+	peppers = keys
+	currentPepperV = current
+	return nil
+}
+
 // パスワードをハッシュ化
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
+	return hashWithPepper(password, currentPepperV)
 }
 
 // ハッシュ化されたパスワードを検証
 func CheckPasswordHash(password, hashedPassword string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	version, hash := splitPepperVersion(hashedPassword)
+	peppered, ok := applyPepper(password, version)
+	if !ok {
+		return false
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(peppered))
 	return err == nil
 }
+
+// 存在しないユーザーの照合に使うダミーハッシュ
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// CompareDummyHash 実在ユーザーと同じコストでダミーハッシュと照合する（常にfalse）
+// ユーザーが存在しない場合でも応答時間を揃えるために使う
+func CompareDummyHash(password string) bool {
+	dummyHashOnce.Do(func() {
+		hash, err := HashPassword("dummy-password-for-timing-equalization")
+		if err != nil {
+			hash, _ = hashWithPepper("dummy-password-for-timing-equalization", 0)
+		}
+		dummyHash = hash
+	})
+	CheckPasswordHash(password, dummyHash)
+	return false
+}
+
+// NeedsRehash 現在のペッパーバージョンで再ハッシュが必要か判定
+func NeedsRehash(hashedPassword string) bool {
+	version, _ := splitPepperVersion(hashedPassword)
+	return version != currentPepperV
+}
+
+func hashWithPepper(password string, version int) (string, error) {
+	peppered, ok := applyPepper(password, version)
+	if !ok {
+		return "", fmt.Errorf("pepper version %d is not loaded", version)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(peppered), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	if version == 0 {
+		return string(hashedPassword), nil
+	}
+	return pepperPrefix + strconv.Itoa(version) + string(hashedPassword), nil
+}
+
+// HMAC-SHA256でペッパーを適用（bcryptの72バイト制限に収まるようbase64化）
+func applyPepper(password string, version int) (string, bool) {
+	if version == 0 {
+		return password, true
+	}
+	key, ok := peppers[version]
+	if !ok {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), true
+}
+
+// 保存形式からペッパーバージョンとbcryptハッシュを取り出す
+// 接頭辞のない従来のハッシュはバージョン0として扱う
+func splitPepperVersion(hashedPassword string) (int, string) {
+	if !strings.HasPrefix(hashedPassword, pepperPrefix) {
+		return 0, hashedPassword
+	}
+	rest := hashedPassword[len(pepperPrefix):]
+	idx := strings.Index(rest, "$")
+	if idx <= 0 {
+		return 0, hashedPassword
+	}
+	version, err := strconv.Atoi(rest[:idx])
+	if err != nil {
+		return 0, hashedPassword
+	}
+	return version, rest[idx:]
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// setPeppers テスト中だけペッパーを設定し、終了時に元に戻す
+func setPeppers(t *testing.T, keys map[int][]byte, current int) {
+	t.Helper()
+	prevKeys, prevCurrent := peppers, currentPepperV
+	t.Cleanup(func() { peppers, currentPepperV = prevKeys, prevCurrent })
+	if err := SetPeppers(keys, current); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var testPeppers = map[int][]byte{
+	1: []byte("pepper-version-one-secret"),
+	2: []byte("pepper-version-two-secret"),
+}
+
+func TestPasswordHashPepperVersions(t *testing.T) {
+	tests := []struct {
+		name        string
+		hashVersion int // ハッシュ化した時点のペッパーバージョン
+		keys        map[int][]byte
+		current     int // 検証時点のペッパーバージョン
+		password    string
+		wantMatch   bool
+		wantRehash  bool
+	}{
+		{name: "no pepper", hashVersion: 0, keys: map[int][]byte{}, current: 0, password: "password123", wantMatch: true},
+		{name: "current pepper", hashVersion: 2, keys: testPeppers, current: 2, password: "password123", wantMatch: true},
+		{name: "older pepper still verifies", hashVersion: 1, keys: testPeppers, current: 2, password: "password123", wantMatch: true, wantRehash: true},
+		{name: "legacy hash after enabling pepper", hashVersion: 0, keys: testPeppers, current: 2, password: "password123", wantMatch: true, wantRehash: true},
+		{name: "wrong password", hashVersion: 2, keys: testPeppers, current: 2, password: "wrong-password", wantMatch: false},
+		{name: "retired pepper version", hashVersion: 1, keys: map[int][]byte{2: testPeppers[2]}, current: 2, password: "password123", wantMatch: false, wantRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPeppers(t, testPeppers, tt.hashVersion)
+			hash, err := HashPassword("password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.hashVersion != 0 && !strings.HasPrefix(hash, pepperPrefix) {
+				t.Fatalf("peppered hash has no version prefix: %q", hash)
+			}
+
+			setPeppers(t, tt.keys, tt.current)
+			if got := CheckPasswordHash(tt.password, hash); got != tt.wantMatch {
+				t.Fatalf("CheckPasswordHash = %v, want %v", got, tt.wantMatch)
+			}
+			if got := NeedsRehash(hash); got != tt.wantRehash {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.wantRehash)
+			}
+		})
+	}
+}
+
+// ペッパーを変えると同じパスワードでも照合できない（ハッシュだけ漏れても総当たりできない）
+func TestPasswordHashDependsOnPepper(t *testing.T) {
+	setPeppers(t, map[int][]byte{1: []byte("pepper-a-0123456789")}, 1)
+	hash, err := HashPassword("password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPeppers(t, map[int][]byte{1: []byte("pepper-b-0123456789")}, 1)
+	if CheckPasswordHash("password123", hash) {
+		t.Fatal("hash verified with a different pepper secret")
+	}
+}
+
+func TestSetPeppersRequiresCurrentVersion(t *testing.T) {
+	setPeppers(t, map[int][]byte{}, 0)
+	if err := SetPeppers(testPeppers, 3); err == nil {
+		t.Fatal("expected an error for a version that is not loaded")
+	}
+	if currentPepperV != 0 {
+		t.Fatalf("current version changed to %d", currentPepperV)
+	}
+}
+
+func TestSplitPepperVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      string
+		wantVersion int
+		wantHash    string
+	}{
+		{name: "legacy bcrypt", stored: "$2a$10$abc", wantVersion: 0, wantHash: "$2a$10$abc"},
+		{name: "version 1", stored: "$p1$2a$10$abc", wantVersion: 1, wantHash: "$2a$10$abc"},
+		{name: "version 12", stored: "$p12$2a$10$abc", wantVersion: 12, wantHash: "$2a$10$abc"},
+		{name: "missing version", stored: "$p$2a$10$abc", wantVersion: 0, wantHash: "$p$2a$10$abc"},
+		{name: "non-numeric version", stored: "$px$2a$10$abc", wantVersion: 0, wantHash: "$px$2a$10$abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, hash := splitPepperVersion(tt.stored)
+			if version != tt.wantVersion || hash != tt.wantHash {
+				t.Fatalf("got (%d, %q), want (%d, %q)", version, hash, tt.wantVersion, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestCompareDummyHash(t *testing.T) {
+	if CompareDummyHash("dummy-password-for-timing-equalization") {
+		t.Fatal("CompareDummyHash must always return false")
+	}
+}
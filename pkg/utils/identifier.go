@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ユーザー名に使える形式（英小文字で始まり、英小文字・数字と単独の . _ を含む3〜30文字）
+// 英数字以外の文字種は見た目の似た文字によるなりすましを防ぐため受け付けない
+var usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*([._][a-z0-9]+)*$`)
+
+// E.164形式の電話番号
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// 予約済みのユーザー名（サービスや運営者と誤認されるもの）
+var reservedUsernames = []string{
+	"admin", "administrator", "root", "system", "sysadmin", "superuser",
+	"support", "help", "helpdesk", "security", "abuse", "postmaster", "hostmaster", "webmaster",
+	"noreply", "mailer", "official", "staff", "moderator", "owner", "billing", "payments",
+	"api", "auth", "login", "signin", "signup", "register", "account", "accounts", "settings",
+	"user", "users", "me", "self", "null", "undefined", "anonymous", "guest", "test", "www",
+}
+
+var reservedUsernameKeys = func() map[string]struct{} {
+	keys := make(map[string]struct{}, len(reservedUsernames))
+	for _, name := range reservedUsernames {
+		keys[UsernameKey(name)] = struct{}{}
+	}
+	return keys
+}()
+
+// 見た目の紛らわしい文字を代表の文字に寄せる
+var confusableReplacer = strings.NewReplacer(
+	".", "", "_", "",
+	"rn", "m", "vv", "w",
+	"0", "o", "1", "l", "i", "l", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b",
+)
+
+// NormalizeUsername 前後の空白を除き小文字にする
+func NormalizeUsername(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidUsername ユーザー名の形式として正しいか（正規化済みの値を渡す）
+func ValidUsername(name string) bool {
+	return len(name) >= 3 && len(name) <= 30 && usernamePattern.MatchString(name)
+}
+
+// UsernameKey 紛らわしい表記を同一視した重複判定用のキー（"j0hn" と "john" は同じになる）
+func UsernameKey(name string) string {
+	return confusableReplacer.Replace(NormalizeUsername(name))
+}
+
+// ReservedUsername 予約済みのユーザー名、またはそれと紛らわしいユーザー名か
+func ReservedUsername(name string) bool {
+	_, reserved := reservedUsernameKeys[UsernameKey(name)]
+	return reserved
+}
+
+// NormalizePhone 表記用の空白・ハイフン・括弧を取り除く
+func NormalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+}
+
+// ValidPhone E.164形式の電話番号か（正規化済みの値を渡す）
+func ValidPhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
@@ -0,0 +1,91 @@
+package utils
+
+import "testing"
+
+func TestValidUsername(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "alice", want: true},
+		{name: "alice.smith", want: true},
+		{name: "alice_99", want: true},
+		{name: "abc", want: true},
+		{name: "ab"},
+		{name: "a234567890123456789012345678901"},
+		{name: "9alice"},
+		{name: "alice."},
+		{name: "alice..smith"},
+		{name: "alice._smith"},
+		{name: "alice-smith"},
+		{name: "Alice"},
+		{name: "аlice"}, // 先頭はキリル文字の а
+		{name: "ａｌｉｃｅ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidUsername(tt.name); got != tt.want {
+				t.Fatalf("ValidUsername(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// 紛らわしい表記は同じキーになり、重複登録を防げる
+func TestUsernameKey(t *testing.T) {
+	groups := [][]string{
+		{"john", "j0hn", "JOHN", "jo.hn", "jo_hn"},
+		{"william", "wi11iam", "wil.li.am"},
+		{"modern", "rnodern", "m0dern"},
+		{"wave", "vvave"},
+	}
+	for _, group := range groups {
+		want := UsernameKey(group[0])
+		for _, name := range group[1:] {
+			if got := UsernameKey(name); got != want {
+				t.Errorf("UsernameKey(%q) = %q, want %q (same as %q)", name, got, want, group[0])
+			}
+		}
+	}
+	if UsernameKey("alice") == UsernameKey("alicia") {
+		t.Fatal("different usernames share a key")
+	}
+}
+
+func TestReservedUsername(t *testing.T) {
+	for _, name := range []string{"admin", "Admin", "adm1n", "r00t", "sup.port", "supp0rt", "n0reply", "rnoderator"} {
+		if !ReservedUsername(name) {
+			t.Errorf("ReservedUsername(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"alice", "administrator1", "rooter", "supporter"} {
+		if ReservedUsername(name) {
+			t.Errorf("ReservedUsername(%q) = true", name)
+		}
+	}
+}
+
+func TestValidPhone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "+819012345678", want: true},
+		{in: "+81 90-1234-5678", want: true},
+		{in: "+1 (415) 555-0100", want: true},
+		{in: "+123456789012345", want: true},
+		{in: "+1234567890123456"},
+		{in: "09012345678"},
+		{in: "+0123456789"},
+		{in: "+1"},
+		{in: "+81-90-1234-567a"},
+		{in: "++819012345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := ValidPhone(NormalizePhone(tt.in)); got != tt.want {
+				t.Fatalf("ValidPhone(%q) = %v, want %v", NormalizePhone(tt.in), got, tt.want)
+			}
+		})
+	}
+}
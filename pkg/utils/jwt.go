@@ -1,33 +1,191 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTの署名キー
-var jwtKey = []byte("your_secret_key")
+// iat などの時刻をミリ秒まで含める（失効した直後に発行したトークンと区別できるように）
+func init() {
+	jwt.TimePrecision = time.Millisecond
+}
+
+// JWTの署名キー（起動時に SetJWTKey で設定する）
+var jwtKey []byte
+
+// ErrJWTKeyMissing 署名キーが設定されていない
+var ErrJWTKeyMissing = errors.New("jwt signing key is not configured")
+
+// SetJWTKey JWTの署名キー（HMAC-SHA256）を設定（32バイト以上）
+func SetJWTKey(key []byte) error {
+	if len(key) < 32 {
+		return errors.New("jwt signing key must be at least 32 bytes")
+	}
+	jwtKey = key
+	return nil
+}
+
+// 用途限定トークンの種類
+const (
+	PurposeMFA         = "mfa"          // MFA検証待ちのチャレンジトークン
+	PurposeVerifyEmail = "verify_email" // メールアドレス確認リンク
+	PurposeExport      = "data_export"  // データエクスポートのダウンロードリンク
+	PurposeOrgInvite   = "org_invite"   // 組織への招待リンク
+)
+
+// 認証方式（RFC 8176 の amr 値）
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRHardwareKey = "hwk"
+	AMRMFA         = "mfa"
+	AMRFederated   = "fed" // 外部の OpenID Provider での認証（RFC 8176 にはない独自の値）
+)
+
+// 認証コンテキストクラス（acr）
+const (
+	ACRSingleFactor = "aal1"
+	ACRMultiFactor  = "aal2"
+)
+
+// AuthContext トークン発行時の認証情報
+type AuthContext struct {
+	Time time.Time // 認証した時刻
+	AMR  []string
+	ACR  string
+}
+
+// NewAuthContext 現在時刻で認証コンテキストを生成（acr は amr から決定）
+func NewAuthContext(amr ...string) AuthContext {
+	return AuthContext{Time: time.Now(), AMR: amr, ACR: ACRForAMR(amr)}
+}
+
+// ACRForAMR 多要素認証を経ていれば aal2、それ以外は aal1
+func ACRForAMR(amr []string) string {
+	for _, m := range amr {
+		if m == AMRMFA {
+			return ACRMultiFactor
+		}
+	}
+	return ACRSingleFactor
+}
+
+// ACRLevel acr の強さを比較用の数値にする
+func ACRLevel(acr string) int {
+	switch acr {
+	case ACRMultiFactor:
+		return 2
+	case ACRSingleFactor:
+		return 1
+	}
+	return 0
+}
+
+// AccessTokenTTL アクセストークンの有効期限
+const AccessTokenTTL = 24 * time.Hour
+
+// TokenSubject トークンに含めるユーザー情報
+type TokenSubject struct {
+	UserID        string // 公開ID
+	Email         string
+	EmailVerified bool
+	Role          string
+	Orgs          map[string]string // 組織の公開ID → ロール
+}
 
 // カスタムクレーム
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID        string            `json:"user_id"` // 公開ID（内部の連番IDは含めない）
+	Email         string            `json:"email"`
+	EmailVerified bool              `json:"email_verified"`
+	Role          string            `json:"role"`
+	Orgs          map[string]string `json:"orgs,omitempty"`    // 所属する組織のロール（所属が変わると失効させる）
+	Purpose       string            `json:"purpose,omitempty"` // 用途限定トークンの場合のみ設定
+	AuthTime      *jwt.NumericDate  `json:"auth_time,omitempty"`
+	AMR           []string          `json:"amr,omitempty"`
+	ACR           string            `json:"acr,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // JWTトークンを生成
-func GenerateJWT(userID uint, email string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // 24時間の有効期限
+func GenerateJWT(subject TokenSubject, auth AuthContext) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenTTL)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:        subject.UserID,
+		Email:         subject.Email,
+		EmailVerified: subject.EmailVerified,
+		Role:          subject.Role,
+		Orgs:          subject.Orgs,
+		AuthTime:      jwt.NewNumericDate(auth.Time),
+		AMR:           auth.AMR,
+		ACR:           auth.ACR,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	return signClaims(claims)
+}
+
+// JWTトークンを検証
+func VerifyJWT(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// 用途限定トークンはアクセストークンとして使えない
+	if claims.Purpose != "" {
+		return nil, errors.New("token is not an access token")
+	}
+	return claims, nil
+}
+
+// Context クレームから認証コンテキストを取り出す
+func (c *Claims) Context() AuthContext {
+	auth := AuthContext{AMR: c.AMR, ACR: c.ACR}
+	if c.AuthTime != nil {
+		auth.Time = c.AuthTime.Time
+	}
+	return auth
+}
+
+// IssuedBefore 指定した時刻より前に発行されたか（iat のないトークンは発行時刻がわからないため true）
+func (c *Claims) IssuedBefore(t time.Time) bool {
+	return c.IssuedAt == nil || c.IssuedAt.Time.Before(t)
+}
+
+// GeneratePurposeJWT 用途を限定した短命トークンを生成
+// claims には用途に応じて UserID・Email・AMR・ID などを設定して渡す
+func GeneratePurposeJWT(purpose string, ttl time.Duration, claims Claims) (string, error) {
+	claims.Purpose = purpose
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        claims.ID, // 対象リソースのIDを指定する場合
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	return signClaims(&claims)
+}
+
+// VerifyPurposeJWT 用途限定トークンを検証
+func VerifyPurposeJWT(tokenString, purpose string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != purpose {
+		return nil, errors.New("token purpose mismatch")
+	}
+	return claims, nil
+}
+
+func signClaims(claims *Claims) (string, error) {
+	if jwtKey == nil {
+		return "", ErrJWTKeyMissing
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString(jwtKey)
 	if err != nil {
@@ -37,13 +195,15 @@ func GenerateJWT(userID uint, email string) (string, error) {
 	return tokenString, nil
 }
 
-// JWTトークンを検証
-func VerifyJWT(tokenString string) (*Claims, error) {
+func parseClaims(tokenString string) (*Claims, error) {
+	if jwtKey == nil {
+		return nil, ErrJWTKeyMissing
+	}
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil || !token.Valid {
 		return nil, err
 	}
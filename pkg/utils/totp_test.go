@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 付録B のテスト用シークレット（ASCII "12345678901234567890"）
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// 付録B の8桁の値の下6桁
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode([]byte("12345678901234567890"), tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+	key := []byte("12345678901234567890")
+	codeAt := func(offset int64) string { return totpCode(key, current+offset) }
+
+	tests := []struct {
+		name      string
+		secret    string
+		code      string
+		afterStep int64
+		wantStep  int64
+		wantOK    bool
+	}{
+		{name: "current step", secret: rfcSecret, code: codeAt(0), wantStep: current, wantOK: true},
+		{name: "previous step within skew", secret: rfcSecret, code: codeAt(-1), wantStep: current - 1, wantOK: true},
+		{name: "next step within skew", secret: rfcSecret, code: codeAt(1), wantStep: current + 1, wantOK: true},
+		{name: "two steps old", secret: rfcSecret, code: codeAt(-2)},
+		{name: "two steps ahead", secret: rfcSecret, code: codeAt(2)},
+		{name: "lowercase secret", secret: strings.ToLower(rfcSecret), code: codeAt(0), wantStep: current, wantOK: true},
+		{name: "wrong code", secret: rfcSecret, code: "000000"},
+		{name: "too short", secret: rfcSecret, code: codeAt(0)[:5]},
+		{name: "too long", secret: rfcSecret, code: codeAt(0) + "0"},
+		{name: "invalid secret", secret: "not base32!", code: codeAt(0)},
+
+		// 使用済みステップ以前のコードは再利用として拒否する
+		{name: "replay of used step", secret: rfcSecret, code: codeAt(0), afterStep: current},
+		{name: "older than used step", secret: rfcSecret, code: codeAt(-1), afterStep: current},
+		{name: "newer than used step", secret: rfcSecret, code: codeAt(1), afterStep: current, wantStep: current + 1, wantOK: true},
+		{name: "after previous step", secret: rfcSecret, code: codeAt(0), afterStep: current - 1, wantStep: current, wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, now, tt.afterStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("ValidateTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// 一致したステップを次回の afterStep に渡すと、同じコードは2回目から通らない
+func TestValidateTOTPSingleUse(t *testing.T) {
+	now := time.Now()
+	code := totpCode([]byte("12345678901234567890"), now.Unix()/totpPeriod)
+
+	step, ok := ValidateTOTP(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+	if _, ok := ValidateTOTP(rfcSecret, code, now.Add(10*time.Second), step); ok {
+		t.Fatal("code accepted twice")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes (err %v)", secret, len(key), err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("User JWT", "alice@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/User JWT:alice@example.com" {
+		t.Fatalf("unexpected URI: %s", u)
+	}
+	q := u.Query()
+	if q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("issuer") != "User JWT" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Fatalf("unexpected parameters: %v", q)
+	}
+}
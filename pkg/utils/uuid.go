@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"regexp"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// NewUUIDv7 時刻順に並ぶ推測困難なID（RFC 9562 UUIDv7）を生成
+// 先頭48ビットがミリ秒単位のUNIX時刻、残りの74ビットが乱数
+func NewUUIDv7() (string, error) {
+	return NewUUIDv7At(time.Now())
+}
+
+// NewUUIDv7At 指定時刻のUUIDv7を生成（既存データへの付与で作成順を保つために使う）
+func NewUUIDv7At(t time.Time) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+
+	ms := uint64(t.UnixMilli())
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(b[:6], ts[2:])
+
+	b[6] = (b[6] & 0x0f) | 0x70 // version 7
+	b[8] = (b[8] & 0x3f) | 0x80 // variant 10
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:]), nil
+}
+
+// IsUUID 小文字表記のUUIDか判定
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
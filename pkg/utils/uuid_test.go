@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	seen := map[string]bool{}
+	for range 1000 {
+		id, err := NewUUIDv7()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsUUID(id) {
+			t.Fatalf("%q is not a lowercase UUID", id)
+		}
+		if id[14] != '7' || !isVariant10(id[19]) {
+			t.Fatalf("%q: version %c, variant %c", id, id[14], id[19])
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func isVariant10(c byte) bool {
+	return c == '8' || c == '9' || c == 'a' || c == 'b'
+}
+
+// 既存ユーザーへの付与では作成日時を使い、文字列の順序が作成順と一致する
+func TestNewUUIDv7AtOrdering(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	prev := ""
+	for i := range 100 {
+		id, err := NewUUIDv7At(base.Add(time.Duration(i) * time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev {
+			t.Fatalf("%q is not after %q", id, prev)
+		}
+		prev = id
+	}
+
+	// 先頭48ビットはミリ秒単位のUNIX時刻
+	id, _ := NewUUIDv7At(base)
+	if got, want := id[:8]+id[9:13], "018cc820d888"; got != want {
+		t.Fatalf("timestamp = %s, want %s", got, want)
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	tests := map[string]bool{
+		"0190a6f2-7b3c-7d4e-8f00-000000000001":   true,
+		"0190A6F2-7B3C-7D4E-8F00-000000000001":   false, // 大文字は正規化されていない
+		"0190a6f27b3c7d4e8f00000000000001":       false,
+		"{0190a6f2-7b3c-7d4e-8f00-000000000001}": false,
+		"42":                                     false, // 旧来の連番ID
+		"":                                       false,
+	}
+	for s, want := range tests {
+		if got := IsUUID(s); got != want {
+			t.Errorf("IsUUID(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
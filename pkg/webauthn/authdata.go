@@ -0,0 +1,79 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// authenticatorData のフラグ
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+var errAuthData = errors.New("malformed authenticator data")
+
+// AuthenticatorData 認証器が署名するデータ
+type AuthenticatorData struct {
+	RPIDHash            []byte
+	Flags               byte
+	SignCount           uint32
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte // COSE_Key（CBOR）
+}
+
+// ParseAuthenticatorData authenticatorData のバイト列を解析する
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errAuthData
+	}
+
+	ad := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if ad.Flags&FlagAttestedCredentialData != 0 {
+		if len(rest) < 18 {
+			return nil, errAuthData
+		}
+		ad.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return nil, errAuthData
+		}
+		ad.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errAuthData
+		}
+		ad.CredentialPublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if ad.Flags&FlagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errAuthData
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, errAuthData
+	}
+	return ad, nil
+}
+
+// HasFlag 指定したフラグが立っているか
+func (ad *AuthenticatorData) HasFlag(flag byte) bool {
+	return ad.Flags&flag != 0
+}
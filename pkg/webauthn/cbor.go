@@ -0,0 +1,141 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// WebAuthnで使われるCBOR（CTAP2正規形）の最小限のデコーダー
+// 不定長のエンコードと半精度浮動小数点はサポートしない
+
+var errCBOR = errors.New("malformed cbor")
+
+// 入れ子の最大深さ
+const cborMaxDepth = 16
+
+// decodeCBOR 先頭の1データ項目をデコードし、残りのバイト列を返す
+// 整数は int64、バイト列は []byte、文字列は string、配列は []interface{}、
+// マップは map[interface{}]interface{} になる
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if len(data) == 0 || depth > cborMaxDepth {
+		return nil, nil, errCBOR
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	if major == 7 {
+		return decodeCBORSimple(info, data)
+	}
+
+	arg, data, err := readCBORArg(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0: // 符号なし整数
+		if arg > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return int64(arg), data, nil
+	case 1: // 負の整数
+		if arg > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(arg), data, nil
+	case 2, 3: // バイト列・文字列
+		if uint64(len(data)) < arg {
+			return nil, nil, errCBOR
+		}
+		b := append([]byte(nil), data[:arg]...)
+		if major == 3 {
+			return string(b), data[arg:], nil
+		}
+		return b, data[arg:], nil
+	case 4: // 配列
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		arr := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var v interface{}
+			v, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, data, nil
+	case 5: // マップ
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var k, v interface{}
+			k, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errCBOR
+			}
+			v, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k] = v
+		}
+		return m, data, nil
+	case 6: // タグ（中身のみ返す）
+		return decodeCBORItem(data, depth+1)
+	}
+	return nil, nil, errCBOR
+}
+
+func readCBORArg(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, errCBOR
+}
+
+func decodeCBORSimple(info byte, data []byte) (interface{}, []byte, error) {
+	switch info {
+	case 20:
+		return false, data, nil
+	case 21:
+		return true, data, nil
+	case 22, 23:
+		return nil, data, nil
+	case 26:
+		if len(data) < 4 {
+			return nil, nil, errCBOR
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), data[4:], nil
+	case 27:
+		if len(data) < 8 {
+			return nil, nil, errCBOR
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), data[8:], nil
+	}
+	return nil, nil, errCBOR
+}
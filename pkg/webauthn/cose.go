@@ -0,0 +1,119 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSEアルゴリズム識別子
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// COSEキータイプ
+const (
+	coseKtyOKP int64 = 1
+	coseKtyEC2 int64 = 2
+	coseKtyRSA int64 = 3
+)
+
+var errUnsupportedKey = errors.New("unsupported public key")
+
+// PublicKey COSE形式から取り出した公開鍵
+type PublicKey struct {
+	Alg int64
+	Key crypto.PublicKey
+}
+
+// ParseCOSEKey COSE_Key（CBOR）を解析する
+func ParseCOSEKey(data []byte) (*PublicKey, error) {
+	v, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errUnsupportedKey
+	}
+	return parseCOSEMap(m)
+}
+
+func parseCOSEMap(m map[interface{}]interface{}) (*PublicKey, error) {
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errUnsupportedKey
+		}
+		return &PublicKey{Alg: alg, Key: pub}, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errUnsupportedKey
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		return &PublicKey{Alg: alg, Key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return &PublicKey{Alg: alg, Key: ed25519.PublicKey(x)}, nil
+	}
+	return nil, errUnsupportedKey
+}
+
+// Verify 署名を検証
+func (k *PublicKey) Verify(data, sig []byte) bool {
+	return verifySignature(k.Alg, k.Key, data, sig)
+}
+
+func verifySignature(alg int64, key crypto.PublicKey, data, sig []byte) bool {
+	switch alg {
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, data, sig)
+	}
+	return false
+}
@@ -0,0 +1,468 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 検証エラー
+var (
+	ErrInvalidClientData   = errors.New("invalid client data")
+	ErrInvalidAuthData     = errors.New("invalid authenticator data")
+	ErrInvalidAttestation  = errors.New("attestation not accepted")
+	ErrInvalidSignature    = errors.New("invalid signature")
+	ErrUserNotVerified     = errors.New("user verification required")
+	ErrSignCountRegression = errors.New("signature counter did not increase")
+)
+
+// ユーザー検証の要求レベル
+const (
+	VerificationRequired    = "required"
+	VerificationPreferred   = "preferred"
+	VerificationDiscouraged = "discouraged"
+)
+
+// Config Relying Party の設定
+type Config struct {
+	RPID                      string
+	RPName                    string
+	Origins                   []string
+	AttestationConveyance     string   // none / indirect / direct
+	AllowedAttestationFormats []string // 受け入れるアテステーション形式（none / packed）
+	// AttestationRoots packed の x5c（認証器メーカーの証明書チェーン）を検証するルート証明書
+	// 未設定の場合は x5c 付きのアテステーションを信頼できないため拒否する（セルフアテステーションは受け入れる）
+	AttestationRoots *x509.CertPool
+	UserVerification string
+	Timeout          time.Duration
+}
+
+// Credential 登録された公開鍵クレデンシャル
+type Credential struct {
+	ID                []byte
+	PublicKey         []byte // COSE_Key
+	SignCount         uint32
+	AAGUID            []byte
+	AttestationFormat string
+	Transports        []string
+	UserVerified      bool
+}
+
+// CredentialDescriptor allowCredentials / excludeCredentials の要素
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor クレデンシャルIDからディスクリプタを生成
+func NewCredentialDescriptor(id []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: "public-key", ID: Encode(id), Transports: transports}
+}
+
+// User 登録時に認証器へ渡すユーザー情報
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type userEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type pubKeyCredParam struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions navigator.credentials.create() に渡すオプション
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     rpEntity               `json:"rp"`
+	User                   userEntity             `json:"user"`
+	PubKeyCredParams       []pubKeyCredParam      `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions navigator.credentials.get() に渡すオプション
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse 登録時にクライアントから返る PublicKeyCredential（base64url）
+type AttestationResponse struct {
+	ID       string `json:"id" validate:"required"`
+	Type     string `json:"type" validate:"required"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON" validate:"required"`
+		AttestationObject string   `json:"attestationObject" validate:"required"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse 認証時にクライアントから返る PublicKeyCredential（base64url）
+type AssertionResponse struct {
+	ID       string `json:"id" validate:"required"`
+	Type     string `json:"type" validate:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" validate:"required"`
+		AuthenticatorData string `json:"authenticatorData" validate:"required"`
+		Signature         string `json:"signature" validate:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge ランダムなチャレンジを生成
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Encode base64url（パディングなし）でエンコード
+func Encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode base64url（パディングの有無を問わない）をデコード
+func Decode(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// NewCreationOptions 登録セレモニーのオプションを生成
+func (c *Config) NewCreationOptions(challenge []byte, user User, exclude []CredentialDescriptor, residentKey string) CreationOptions {
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return CreationOptions{
+		Challenge: Encode(challenge),
+		RP:        rpEntity{ID: c.RPID, Name: c.RPName},
+		User:      userEntity{ID: Encode(user.ID), Name: user.Name, DisplayName: user.DisplayName},
+		PubKeyCredParams: []pubKeyCredParam{
+			{Type: "public-key", Alg: AlgES256},
+			{Type: "public-key", Alg: AlgEdDSA},
+			{Type: "public-key", Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:      residentKey,
+			UserVerification: c.UserVerification,
+		},
+		Attestation: c.AttestationConveyance,
+	}
+}
+
+// NewRequestOptions 認証セレモニーのオプションを生成
+func (c *Config) NewRequestOptions(challenge []byte, allow []CredentialDescriptor, userVerification string) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        Encode(challenge),
+		Timeout:          c.Timeout.Milliseconds(),
+		RPID:             c.RPID,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}
+}
+
+// VerifyRegistration 登録セレモニーの応答を検証し、クレデンシャルを返す
+func (c *Config) VerifyRegistration(challenge []byte, resp AttestationResponse, requireUV bool) (*Credential, error) {
+	clientDataJSON, err := Decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, ErrInvalidClientData
+	}
+	if err := c.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := Decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidAttestation
+	}
+	v, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, ErrInvalidAttestation
+	}
+	attObj, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+	format, _ := attObj["fmt"].(string)
+	rawAuthData, _ := attObj["authData"].([]byte)
+	attStmt, _ := attObj["attStmt"].(map[interface{}]interface{})
+
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, ErrInvalidAuthData
+	}
+	if err := c.verifyAuthData(authData, requireUV); err != nil {
+		return nil, err
+	}
+	if !authData.HasFlag(FlagAttestedCredentialData) || len(authData.CredentialID) == 0 {
+		return nil, ErrInvalidAuthData
+	}
+
+	pub, err := ParseCOSEKey(authData.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := c.verifyAttestation(format, attStmt, rawAuthData, authData.AAGUID, clientDataHash[:], pub); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:                authData.CredentialID,
+		PublicKey:         authData.CredentialPublicKey,
+		SignCount:         authData.SignCount,
+		AAGUID:            authData.AAGUID,
+		AttestationFormat: format,
+		Transports:        resp.Response.Transports,
+		UserVerified:      authData.HasFlag(FlagUserVerified),
+	}, nil
+}
+
+// VerifyAssertion 認証セレモニーの応答を検証し、新しい署名カウンターを返す
+func (c *Config) VerifyAssertion(challenge []byte, resp AssertionResponse, publicKey []byte, storedCount uint32, requireUV bool) (uint32, bool, error) {
+	clientDataJSON, err := Decode(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, false, ErrInvalidClientData
+	}
+	if err := c.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, false, err
+	}
+
+	rawAuthData, err := Decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, false, ErrInvalidAuthData
+	}
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, false, ErrInvalidAuthData
+	}
+	if err := c.verifyAuthData(authData, requireUV); err != nil {
+		return 0, false, err
+	}
+
+	sig, err := Decode(resp.Response.Signature)
+	if err != nil {
+		return 0, false, ErrInvalidSignature
+	}
+	pub, err := ParseCOSEKey(publicKey)
+	if err != nil {
+		return 0, false, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if !pub.Verify(append(append([]byte(nil), rawAuthData...), clientDataHash[:]...), sig) {
+		return 0, false, ErrInvalidSignature
+	}
+
+	// カウンターが増えていない場合は認証器が複製された可能性がある
+	if (authData.SignCount != 0 || storedCount != 0) && authData.SignCount <= storedCount {
+		return 0, false, ErrSignCountRegression
+	}
+
+	return authData.SignCount, authData.HasFlag(FlagUserVerified), nil
+}
+
+func (c *Config) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return ErrInvalidClientData
+	}
+	if cd.Type != ceremony {
+		return ErrInvalidClientData
+	}
+	got, err := Decode(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrInvalidClientData
+	}
+	for _, origin := range c.Origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+	return ErrInvalidClientData
+}
+
+func (c *Config) verifyAuthData(ad *AuthenticatorData, requireUV bool) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if subtle.ConstantTimeCompare(ad.RPIDHash, rpIDHash[:]) != 1 {
+		return ErrInvalidAuthData
+	}
+	if !ad.HasFlag(FlagUserPresent) {
+		return ErrInvalidAuthData
+	}
+	if requireUV && !ad.HasFlag(FlagUserVerified) {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// verifyAttestation アテステーションポリシーに従って attStmt を検証する
+func (c *Config) verifyAttestation(format string, attStmt map[interface{}]interface{}, authData, aaguid, clientDataHash []byte, credKey *PublicKey) error {
+	if !c.formatAllowed(format) {
+		return ErrInvalidAttestation
+	}
+
+	switch format {
+	case "none":
+		if len(attStmt) != 0 {
+			return ErrInvalidAttestation
+		}
+		return nil
+
+	case "packed":
+		alg, _ := attStmt["alg"].(int64)
+		sig, _ := attStmt["sig"].([]byte)
+		signed := append(append([]byte(nil), authData...), clientDataHash...)
+
+		x5c, hasX5C := attStmt["x5c"].([]interface{})
+		if !hasX5C {
+			// セルフアテステーション: クレデンシャル自身の鍵で署名されている
+			if alg != credKey.Alg || !credKey.Verify(signed, sig) {
+				return ErrInvalidAttestation
+			}
+			return nil
+		}
+
+		certs, err := parseX5C(x5c)
+		if err != nil {
+			return ErrInvalidAttestation
+		}
+		if !verifySignature(alg, certs[0].PublicKey, signed, sig) {
+			return ErrInvalidAttestation
+		}
+		return c.verifyAttestationCert(certs, aaguid)
+	}
+	return ErrInvalidAttestation
+}
+
+// oidFIDOGenCEAAGUID 証明書に含まれる認証器の AAGUID 拡張（id-fido-gen-ce-aaguid）
+var oidFIDOGenCEAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+func parseX5C(x5c []interface{}) ([]*x509.Certificate, error) {
+	if len(x5c) == 0 {
+		return nil, ErrInvalidAttestation
+	}
+	certs := make([]*x509.Certificate, 0, len(x5c))
+	for _, v := range x5c {
+		der, ok := v.([]byte)
+		if !ok {
+			return nil, ErrInvalidAttestation
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// verifyAttestationCert packed のアテステーション証明書の要件（WebAuthn §8.2.1）とルートまでのチェーンを検証する
+func (c *Config) verifyAttestationCert(certs []*x509.Certificate, aaguid []byte) error {
+	if c.AttestationRoots == nil {
+		return ErrInvalidAttestation
+	}
+
+	leaf := certs[0]
+	if leaf.Version != 3 || !leaf.BasicConstraintsValid || leaf.IsCA {
+		return ErrInvalidAttestation
+	}
+	subject := leaf.Subject
+	if len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" {
+		return ErrInvalidAttestation
+	}
+	if !containsString(subject.OrganizationalUnit, "Authenticator Attestation") {
+		return ErrInvalidAttestation
+	}
+
+	// AAGUID 拡張がある場合は認証器データの AAGUID と一致しなければならない
+	for _, ext := range leaf.Extensions {
+		if !ext.Id.Equal(oidFIDOGenCEAAGUID) {
+			continue
+		}
+		var certAAGUID []byte
+		if ext.Critical {
+			return ErrInvalidAttestation
+		}
+		if rest, err := asn1.Unmarshal(ext.Value, &certAAGUID); err != nil || len(rest) != 0 {
+			return ErrInvalidAttestation
+		}
+		if subtle.ConstantTimeCompare(certAAGUID, aaguid) != 1 {
+			return ErrInvalidAttestation
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         c.AttestationRoots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return ErrInvalidAttestation
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) formatAllowed(format string) bool {
+	for _, f := range c.AllowedAttestationFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
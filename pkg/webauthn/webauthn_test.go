@@ -0,0 +1,553 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+var testAAGUID = []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+
+// テスト用の最小限の CBOR エンコーダー（マップはキーの順序を保つため cborMap を使う）
+type cborPair struct {
+	key, value interface{}
+}
+
+type cborMap []cborPair
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		b := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		return b
+	default:
+		b := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+}
+
+func cborEncode(v interface{}) []byte {
+	switch v := v.(type) {
+	case int:
+		return cborEncode(int64(v))
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, cborEncode(item)...)
+		}
+		return out
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, cborEncode(pair.key)...)
+			out = append(out, cborEncode(pair.value)...)
+		}
+		return out
+	}
+	panic("unsupported cbor value")
+}
+
+// softAuthenticator ES256 の鍵を持つソフトウェア認証器
+type softAuthenticator struct {
+	key    *ecdsa.PrivateKey
+	credID []byte
+	aaguid []byte
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credID: []byte("credential-id-0001"), aaguid: testAAGUID}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	return cborEncode(cborMap{
+		{int64(1), coseKtyEC2},
+		{int64(3), AlgES256},
+		{int64(-1), int64(1)},
+		{int64(-2), a.key.X.FillBytes(make([]byte, 32))},
+		{int64(-3), a.key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+func (a *softAuthenticator) authData(rpID string, flags byte, count uint32, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	if attested {
+		flags |= FlagAttestedCredentialData
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, count)
+	if attested {
+		data = append(data, a.aaguid...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credID)))
+		data = append(data, a.credID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, authData, clientDataJSON []byte) []byte {
+	t.Helper()
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func clientDataJSON(t *testing.T, ceremony string, challenge []byte, origin string) []byte {
+	t.Helper()
+	raw, err := json.Marshal(clientData{Type: ceremony, Challenge: Encode(challenge), Origin: origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func attestationResponse(clientDataJSON, attestationObject []byte) AttestationResponse {
+	var resp AttestationResponse
+	resp.ID = "credential"
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = Encode(clientDataJSON)
+	resp.Response.AttestationObject = Encode(attestationObject)
+	return resp
+}
+
+func testConfig() *Config {
+	return &Config{
+		RPID:                      testRPID,
+		RPName:                    "Example",
+		Origins:                   []string{testOrigin},
+		AllowedAttestationFormats: []string{"none", "packed"},
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	challenge := []byte("registration-challenge-0123456789")
+
+	tests := []struct {
+		name      string
+		ceremony  string
+		challenge []byte
+		origin    string
+		rpID      string
+		flags     byte
+		format    string // none / packed（セルフアテステーション）
+		attStmt   cborMap
+		badSig    bool
+		requireUV bool
+		formats   []string
+		wantErr   error
+	}{
+		{name: "none", format: "none"},
+		{name: "packed self attestation", format: "packed"},
+		{name: "user verified", format: "none", flags: FlagUserPresent | FlagUserVerified, requireUV: true},
+		{name: "wrong origin", format: "none", origin: "https://evil.example", wantErr: ErrInvalidClientData},
+		{name: "wrong ceremony", format: "none", ceremony: "webauthn.get", wantErr: ErrInvalidClientData},
+		{name: "wrong challenge", format: "none", challenge: []byte("other"), wantErr: ErrInvalidClientData},
+		{name: "wrong rp id hash", format: "none", rpID: "evil.example", wantErr: ErrInvalidAuthData},
+		{name: "user not present", format: "none", flags: FlagUserVerified, wantErr: ErrInvalidAuthData},
+		{name: "user verification required", format: "none", requireUV: true, wantErr: ErrUserNotVerified},
+		{name: "format not allowed", format: "packed", formats: []string{"none"}, wantErr: ErrInvalidAttestation},
+		{name: "none with statement", format: "none", attStmt: cborMap{{"alg", AlgES256}}, wantErr: ErrInvalidAttestation},
+		{name: "bad self attestation signature", format: "packed", badSig: true, wantErr: ErrInvalidAttestation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newSoftAuthenticator(t)
+			cfg := testConfig()
+			if tt.formats != nil {
+				cfg.AllowedAttestationFormats = tt.formats
+			}
+			ceremony, sentChallenge, origin, rpID, flags := "webauthn.create", challenge, testOrigin, testRPID, FlagUserPresent
+			if tt.ceremony != "" {
+				ceremony = tt.ceremony
+			}
+			if tt.challenge != nil {
+				sentChallenge = tt.challenge
+			}
+			if tt.origin != "" {
+				origin = tt.origin
+			}
+			if tt.rpID != "" {
+				rpID = tt.rpID
+			}
+			if tt.flags != 0 {
+				flags = tt.flags
+			}
+
+			cd := clientDataJSON(t, ceremony, sentChallenge, origin)
+			authData := auth.authData(rpID, flags, 0, true)
+			attStmt := tt.attStmt
+			if attStmt == nil {
+				attStmt = cborMap{}
+			}
+			if tt.format == "packed" && tt.attStmt == nil {
+				sig := sign(t, auth.key, authData, cd)
+				if tt.badSig {
+					sig = sign(t, auth.key, authData, []byte("other client data"))
+				}
+				attStmt = cborMap{{"alg", AlgES256}, {"sig", sig}}
+			}
+			attObj := cborEncode(cborMap{{"fmt", tt.format}, {"attStmt", attStmt}, {"authData", authData}})
+
+			cred, err := cfg.VerifyRegistration(challenge, attestationResponse(cd, attObj), tt.requireUV)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(cred.ID) != string(auth.credID) || string(cred.AAGUID) != string(auth.aaguid) || cred.AttestationFormat != tt.format {
+				t.Fatalf("unexpected credential: %+v", cred)
+			}
+			if cred.UserVerified != (flags&FlagUserVerified != 0) {
+				t.Fatalf("UserVerified = %v", cred.UserVerified)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	challenge := []byte("assertion-challenge-0123456789")
+
+	tests := []struct {
+		name        string
+		ceremony    string
+		origin      string
+		rpID        string
+		flags       byte
+		count       uint32
+		storedCount uint32
+		requireUV   bool
+		otherKey    bool
+		wantCount   uint32
+		wantUV      bool
+		wantErr     error
+	}{
+		{name: "counter increases", count: 6, storedCount: 5, wantCount: 6},
+		{name: "counter unsupported", count: 0, storedCount: 0, wantCount: 0},
+		{name: "user verified", flags: FlagUserPresent | FlagUserVerified, count: 1, requireUV: true, wantCount: 1, wantUV: true},
+		{name: "counter not increased", count: 5, storedCount: 5, wantErr: ErrSignCountRegression},
+		{name: "counter regressed", count: 3, storedCount: 5, wantErr: ErrSignCountRegression},
+		{name: "counter reset to zero", count: 0, storedCount: 5, wantErr: ErrSignCountRegression},
+		{name: "wrong origin", origin: "https://evil.example", count: 1, wantErr: ErrInvalidClientData},
+		{name: "wrong ceremony", ceremony: "webauthn.create", count: 1, wantErr: ErrInvalidClientData},
+		{name: "wrong rp id hash", rpID: "evil.example", count: 1, wantErr: ErrInvalidAuthData},
+		{name: "user not present", flags: FlagUserVerified, count: 1, wantErr: ErrInvalidAuthData},
+		{name: "user verification required", count: 1, requireUV: true, wantErr: ErrUserNotVerified},
+		{name: "signed by another key", count: 1, otherKey: true, wantErr: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newSoftAuthenticator(t)
+			ceremony, origin, rpID, flags := "webauthn.get", testOrigin, testRPID, FlagUserPresent
+			if tt.ceremony != "" {
+				ceremony = tt.ceremony
+			}
+			if tt.origin != "" {
+				origin = tt.origin
+			}
+			if tt.rpID != "" {
+				rpID = tt.rpID
+			}
+			if tt.flags != 0 {
+				flags = tt.flags
+			}
+
+			cd := clientDataJSON(t, ceremony, challenge, origin)
+			authData := auth.authData(rpID, flags, tt.count, false)
+			signer := auth.key
+			if tt.otherKey {
+				signer = newSoftAuthenticator(t).key
+			}
+
+			var resp AssertionResponse
+			resp.ID = Encode(auth.credID)
+			resp.Type = "public-key"
+			resp.Response.ClientDataJSON = Encode(cd)
+			resp.Response.AuthenticatorData = Encode(authData)
+			resp.Response.Signature = Encode(sign(t, signer, authData, cd))
+
+			count, uv, err := testConfig().VerifyAssertion(challenge, resp, auth.coseKey(), tt.storedCount, tt.requireUV)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != tt.wantCount || uv != tt.wantUV {
+				t.Fatalf("got count=%d uv=%v, want count=%d uv=%v", count, uv, tt.wantCount, tt.wantUV)
+			}
+		})
+	}
+}
+
+// attestationCA テスト用の認証器メーカーのルート証明書
+type attestationCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newAttestationCA(t *testing.T) *attestationCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Root", Organization: []string{"Test Vendor"}, Country: []string{"JP"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &attestationCA{cert: cert, key: key}
+}
+
+// issue アテステーション証明書を発行する（modify でテンプレートを変更できる）
+func (ca *attestationCA) issue(t *testing.T, key *ecdsa.PrivateKey, modify func(*x509.Certificate)) []byte {
+	t.Helper()
+	aaguidExt, err := asn1.Marshal(testAAGUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName:         "Test Authenticator",
+			Organization:       []string{"Test Vendor"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			Country:            []string{"JP"},
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: oidFIDOGenCEAAGUID, Value: aaguidExt}},
+	}
+	if modify != nil {
+		modify(template)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestVerifyRegistrationX5C(t *testing.T) {
+	challenge := []byte("registration-challenge-0123456789")
+	ca := newAttestationCA(t)
+
+	tests := []struct {
+		name      string
+		modify    func(*x509.Certificate)
+		roots     func() *x509.CertPool
+		otherRoot bool
+		wantErr   error
+	}{
+		{name: "trusted chain"},
+		{name: "no configured roots", roots: func() *x509.CertPool { return nil }, wantErr: ErrInvalidAttestation},
+		{name: "untrusted root", otherRoot: true, wantErr: ErrInvalidAttestation},
+		{name: "missing OU", modify: func(c *x509.Certificate) { c.Subject.OrganizationalUnit = nil }, wantErr: ErrInvalidAttestation},
+		{name: "missing country", modify: func(c *x509.Certificate) { c.Subject.Country = nil }, wantErr: ErrInvalidAttestation},
+		{name: "leaf is CA", modify: func(c *x509.Certificate) { c.IsCA = true }, wantErr: ErrInvalidAttestation},
+		{name: "no basic constraints", modify: func(c *x509.Certificate) { c.BasicConstraintsValid = false }, wantErr: ErrInvalidAttestation},
+		{
+			name: "aaguid mismatch",
+			modify: func(c *x509.Certificate) {
+				other, _ := asn1.Marshal(make([]byte, 16))
+				c.ExtraExtensions = []pkix.Extension{{Id: oidFIDOGenCEAAGUID, Value: other}}
+			},
+			wantErr: ErrInvalidAttestation,
+		},
+		{
+			name: "critical aaguid extension",
+			modify: func(c *x509.Certificate) {
+				c.ExtraExtensions[0].Critical = true
+			},
+			wantErr: ErrInvalidAttestation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newSoftAuthenticator(t)
+			attKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			issuer := ca
+			if tt.otherRoot {
+				issuer = newAttestationCA(t)
+			}
+			leaf := issuer.issue(t, attKey, tt.modify)
+
+			cfg := testConfig()
+			cfg.AttestationRoots = x509.NewCertPool()
+			cfg.AttestationRoots.AddCert(ca.cert)
+			if tt.roots != nil {
+				cfg.AttestationRoots = tt.roots()
+			}
+
+			cd := clientDataJSON(t, "webauthn.create", challenge, testOrigin)
+			authData := auth.authData(testRPID, FlagUserPresent, 0, true)
+			attStmt := cborMap{
+				{"alg", AlgES256},
+				{"sig", sign(t, attKey, authData, cd)},
+				{"x5c", []interface{}{leaf}},
+			}
+			attObj := cborEncode(cborMap{{"fmt", "packed"}, {"attStmt", attStmt}, {"authData", authData}})
+
+			_, err = cfg.VerifyRegistration(challenge, attestationResponse(cd, attObj), false)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "truncated uint16 argument", data: []byte{0x19, 0x01}},
+		{name: "truncated uint64 argument", data: []byte{0x1b, 0, 0, 0}},
+		{name: "byte string longer than input", data: []byte{0x45, 0x01, 0x02}},
+		{name: "huge byte string length", data: []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "huge array length", data: []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "huge map length", data: []byte{0xbb, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}},
+		{name: "array missing items", data: []byte{0x83, 0x01, 0x02}},
+		{name: "map missing value", data: []byte{0xa1, 0x01}},
+		{name: "map with byte string key", data: []byte{0xa1, 0x41, 0x00, 0x01}},
+		{name: "uint above int64", data: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "indefinite length", data: []byte{0x5f, 0x41, 0x00, 0xff}},
+		{name: "reserved additional info", data: []byte{0x1c}},
+		{name: "truncated float", data: []byte{0xfb, 0x00, 0x00}},
+		{name: "unsupported simple value", data: []byte{0xf8, 0x20}},
+		{name: "nesting too deep", data: append(bytesRepeat(0x81, cborMaxDepth+2), 0x01)},
+		{name: "tag without content", data: []byte{0xc0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func bytesRepeat(b byte, n int) []byte {
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = b
+	}
+	return out
+}
+
+// 途中で切れた入力はどの位置で切れてもパニックせずエラーになる
+func TestTruncatedInputs(t *testing.T) {
+	challenge := []byte("registration-challenge-0123456789")
+	auth := newSoftAuthenticator(t)
+	cd := clientDataJSON(t, "webauthn.create", challenge, testOrigin)
+	authData := auth.authData(testRPID, FlagUserPresent, 0, true)
+	attObj := cborEncode(cborMap{
+		{"fmt", "packed"},
+		{"attStmt", cborMap{{"alg", AlgES256}, {"sig", sign(t, auth.key, authData, cd)}}},
+		{"authData", authData},
+	})
+	cfg := testConfig()
+
+	if _, err := cfg.VerifyRegistration(challenge, attestationResponse(cd, attObj), false); err != nil {
+		t.Fatalf("full attestation object rejected: %v", err)
+	}
+
+	for i := 0; i < len(attObj); i++ {
+		if _, _, err := decodeCBOR(attObj[:i]); err == nil {
+			t.Fatalf("decodeCBOR accepted %d of %d bytes", i, len(attObj))
+		}
+		if _, err := cfg.VerifyRegistration(challenge, attestationResponse(cd, attObj[:i]), false); err == nil {
+			t.Fatalf("VerifyRegistration accepted %d of %d bytes", i, len(attObj))
+		}
+	}
+	for i := 0; i < len(authData); i++ {
+		if _, err := ParseAuthenticatorData(authData[:i]); err == nil {
+			t.Fatalf("ParseAuthenticatorData accepted %d of %d bytes", i, len(authData))
+		}
+	}
+	coseKey := auth.coseKey()
+	for i := 0; i < len(coseKey); i++ {
+		if _, err := ParseCOSEKey(coseKey[:i]); err == nil {
+			t.Fatalf("ParseCOSEKey accepted %d of %d bytes", i, len(coseKey))
+		}
+	}
+}
+
+// 認証器データの後ろに余分なバイトがある場合は拒否する
+func TestParseAuthenticatorDataTrailingBytes(t *testing.T) {
+	auth := newSoftAuthenticator(t)
+	data := append(auth.authData(testRPID, FlagUserPresent, 0, true), 0x00)
+	if _, err := ParseAuthenticatorData(data); err == nil {
+		t.Fatal("expected an error")
+	}
+}